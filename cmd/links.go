@@ -0,0 +1,237 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"os"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/server"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/spf13/cobra"
+	"gorm.io/gen"
+)
+
+func init() {
+	var (
+		dryRun    bool
+		batchSize int
+
+		exportUser   string
+		exportFormat string
+		exportSearch string
+		exportSort   string
+		exportOutput string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "links",
+		Short: "Operations related to shared links.\nThe mysql address must be specified in the configuration file or environment variable, see more: `keepshare config`",
+	}
+
+	rehashCmd := &cobra.Command{
+		Use:     "rehash",
+		Short:   "Recompute hashes of original links in keepshare_shared_link, keepshare_blacklist and tables of hosts, such as pikpak_file.\nRun it after upgrading or changing link_strip_params, so that existing links keep resolving.",
+		Example: "keepshare links rehash --dry-run",
+		Run: func(_ *cobra.Command, _ []string) {
+			rehashLinks(dryRun, batchSize)
+		},
+	}
+
+	rehashCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only count the rows to be updated, without updating them.")
+	rehashCmd.Flags().IntVar(&batchSize, "batch-size", 500, "The number of rows to read in a batch.")
+
+	exportCmd := &cobra.Command{
+		Use:     "export",
+		Short:   "Export shared links of a user matching the search DSL, in the format csv or jsonl.",
+		Example: `keepshare links export --user someone@example.com --format jsonl --search 'created_at > -7d' -o links.jsonl`,
+		Run: func(_ *cobra.Command, _ []string) {
+			exportLinks(exportUser, exportFormat, exportSearch, exportSort, exportOutput)
+		},
+	}
+
+	exportCmd.Flags().StringVar(&exportUser, "user", "", "The id, email or channel of the user.")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "csv", "The format of the output, csv or jsonl.")
+	exportCmd.Flags().StringVar(&exportSearch, "search", "", "The search DSL of shared links, all shared links are exported if it is empty.")
+	exportCmd.Flags().StringVar(&exportSort, "sort", "", "The order of shared links, e.g. -size, created_at:asc, they are in the order of creation descending by default.")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "-", "The file to write, - for stdout.")
+	_ = exportCmd.MarkFlagRequired("user")
+
+	cmd.AddCommand(rehashCmd, exportCmd)
+	rootCmd.AddCommand(cmd)
+}
+
+func exportLinks(user, format, search, sort, output string) {
+	// the output may be stdout.
+	errLog := log.New(os.Stderr, "", 0)
+	if err := config.Load(); err != nil {
+		errLog.Fatal("load config err:", err)
+	}
+
+	w := os.Stdout
+	if output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			errLog.Fatal("create output file err:", err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	n, err := server.ExportSharedLinks(context.Background(), config.MySQL(), bw, user, format, search, sort)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		errLog.Fatalf("export shared links err after %d rows: %v", n, err)
+	}
+	errLog.Printf("exported %d shared links", n)
+}
+
+// rehashStats counts rows of a table.
+type rehashStats struct {
+	scanned, updated, conflicted int64
+}
+
+func rehashLinks(dryRun bool, batchSize int) {
+	if err := config.Load(); err != nil {
+		stdLog.Fatal("load config err:", err)
+	}
+
+	ctx := context.Background()
+	q := query.Use(config.MySQL())
+
+	s, hostRows := rehashSharedLinks(ctx, q, dryRun, batchSize)
+	stdLog.Printf("keepshare_shared_link: scanned %d, updated %d, conflicted %d", s.scanned, s.updated, s.conflicted)
+	for host, n := range hostRows {
+		stdLog.Printf("%s: updated %d", host, n)
+	}
+
+	s = rehashBlacklist(ctx, q, dryRun, batchSize)
+	stdLog.Printf("keepshare_blacklist: scanned %d, updated %d, conflicted %d", s.scanned, s.updated, s.conflicted)
+
+	if dryRun {
+		stdLog.Println("DRY RUN, nothing is updated")
+	}
+}
+
+// rehashSharedLinks simplifies original links and recomputes hashes of original links and host shared links.
+// Rows conflicting with existing rows of the new hashes are kept as they are.
+// The hashes of original links changed are also updated in tables of the hosts, it returns the rows updated of each host.
+func rehashSharedLinks(ctx context.Context, q *query.Query, dryRun bool, batchSize int) (s rehashStats, hostRows map[string]int64) {
+	hostRows = map[string]int64{}
+	t := q.SharedLink
+
+	var last int64
+	for {
+		rows, err := t.WithContext(ctx).Where(t.AutoID.Gt(last)).Order(t.AutoID).Limit(batchSize).Find()
+		if err != nil {
+			stdLog.Fatal("query shared links err:", err)
+		}
+		if len(rows) == 0 {
+			return s, hostRows
+		}
+		last = rows[len(rows)-1].AutoID
+
+		for _, row := range rows {
+			s.scanned++
+			simple := lk.Simplify(row.OriginalLink)
+			hash := lk.Hash(simple)
+			hostHash := row.HostSharedLinkHash
+			if row.HostSharedLink != "" {
+				hostHash = lk.Hash(row.HostSharedLink)
+			}
+			if simple == row.OriginalLink && hash == row.OriginalLinkHash && hostHash == row.HostSharedLinkHash {
+				continue
+			}
+
+			if dryRun {
+				s.updated++
+				continue
+			}
+
+			_, err := t.WithContext(ctx).Where(t.AutoID.Eq(row.AutoID)).UpdateSimple(
+				t.OriginalLink.Value(simple),
+				t.OriginalLinkHash.Value(hash),
+				t.HostSharedLinkHash.Value(hostHash),
+			)
+			if gormutil.IsDuplicateError(err) {
+				stdLog.Printf("CONFLICT shared link %d: `%s` exists with hash %s", row.AutoID, simple, hash)
+				s.conflicted++
+				continue
+			}
+			if err != nil {
+				stdLog.Fatalf("update shared link %d err: %v", row.AutoID, err)
+			}
+			s.updated++
+
+			if hash == row.OriginalLinkHash {
+				continue
+			}
+			host := hosts.Get(row.Host)
+			if host == nil {
+				continue
+			}
+			n, err := host.RehashLinks(ctx, config.MySQL(), row.UserID, map[string]string{row.OriginalLinkHash: hash})
+			if err != nil {
+				stdLog.Fatalf("rehash links of host %s for shared link %d err: %v", row.Host, row.AutoID, err)
+			}
+			hostRows[host.Name()] += n
+		}
+	}
+}
+
+// rehashBlacklist recomputes hashes of original links, rows conflicting with existing rows are deleted.
+func rehashBlacklist(ctx context.Context, q *query.Query, dryRun bool, batchSize int) (s rehashStats) {
+	t := q.Blacklist
+
+	var lastUser, lastHash string
+	for {
+		rows, err := t.WithContext(ctx).
+			Where(t.WithContext(ctx).Where(t.UserID.Gt(lastUser)).Or(t.UserID.Eq(lastUser), t.OriginalLinkHash.Gt(lastHash))).
+			Order(t.UserID, t.OriginalLinkHash).
+			Limit(batchSize).
+			Find()
+		if err != nil {
+			stdLog.Fatal("query blacklist err:", err)
+		}
+		if len(rows) == 0 {
+			return s
+		}
+		lastUser, lastHash = rows[len(rows)-1].UserID, rows[len(rows)-1].OriginalLinkHash
+
+		for _, row := range rows {
+			s.scanned++
+			hash := lk.Hash(row.OriginalLink)
+			if hash == row.OriginalLinkHash {
+				continue
+			}
+
+			if dryRun {
+				s.updated++
+				continue
+			}
+
+			where := []gen.Condition{t.UserID.Eq(row.UserID), t.OriginalLinkHash.Eq(row.OriginalLinkHash)}
+			_, err := t.WithContext(ctx).Where(where...).UpdateSimple(t.OriginalLinkHash.Value(hash))
+			if gormutil.IsDuplicateError(err) {
+				s.conflicted++
+				_, err = t.WithContext(ctx).Where(where...).Delete()
+			} else if err == nil {
+				s.updated++
+			}
+			if err != nil {
+				stdLog.Fatalf("update blacklist %s of user %s err: %v", row.OriginalLinkHash, row.UserID, err)
+			}
+		}
+	}
+}
@@ -6,7 +6,10 @@ package cmd
 
 import (
 	"fmt"
+	"io/fs"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/KeepShareOrg/keepshare/config"
@@ -14,6 +17,7 @@ import (
 	"github.com/KeepShareOrg/keepshare/server/rawsql"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 )
 
 func init() {
@@ -35,6 +39,18 @@ func init() {
 
 	createCmd.Flags().BoolVar(&dropEmpty, "drop-empty", false, "When creating a table, if the table is empty, drop the table and recreate it.")
 
+	migrateCmd := &cobra.Command{
+		Use:     "migrate",
+		Short:   "Apply the pending migrations of existing tables, such as new columns and indexes.\nRun it after upgrading, `tables create` also runs it after creating tables.",
+		Example: "keepshare tables migrate",
+		Run: func(_ *cobra.Command, _ []string) {
+			if err := config.Load(); err != nil {
+				stdLog.Fatal("load config err:", err)
+			}
+			migrateTables(config.MySQL(), nil)
+		},
+	}
+
 	dumpCmd := &cobra.Command{
 		Use:     "dump",
 		Short:   "Dump the CREATE TABLE statements for tables",
@@ -42,7 +58,7 @@ func init() {
 		Run:     dumpTables,
 	}
 
-	cmd.AddCommand(createCmd, dumpCmd)
+	cmd.AddCommand(createCmd, migrateCmd, dumpCmd)
 	rootCmd.AddCommand(cmd)
 }
 
@@ -57,6 +73,8 @@ func createTables(_ *cobra.Command, args []string, dropEmpty bool) {
 		stdLog.Fatal("get current tables err:", err)
 	}
 
+	// tables created by the statements have all columns and indexes, their migrations are not needed.
+	created := map[string]bool{}
 	for t, s := range loadStatements() {
 		if len(args) > 0 && !lo.Contains(args, t) {
 			continue
@@ -93,7 +111,92 @@ func createTables(_ *cobra.Command, args []string, dropEmpty bool) {
 		} else {
 			stdLog.Printf("CREATE TABLE `%s`", t)
 		}
+		created[t] = true
+	}
+
+	migrateTables(db, created)
+}
+
+// migrateTables applies the migrations in rawsql.Migrations which are not recorded in keepshare_schema_migration.
+// The migrations of tables in created are recorded only, and those of missing tables are skipped.
+func migrateTables(db *gorm.DB, created map[string]bool) {
+	var current []string
+	if err := db.Raw("SHOW TABLES").Scan(&current).Error; err != nil {
+		stdLog.Fatal("get current tables err:", err)
+	}
+	if !lo.Contains(current, migrationTable) {
+		if err := db.Exec(loadStatements()[migrationTable]).Error; err != nil {
+			stdLog.Fatalf("CREATE TABLE `%s` err: %v", migrationTable, err)
+		}
+	}
+
+	var applied []string
+	if err := db.Raw("SELECT `version` FROM `" + migrationTable + "`").Scan(&applied).Error; err != nil {
+		stdLog.Fatal("get applied migrations err:", err)
+	}
+
+	for _, m := range loadMigrations() {
+		if lo.Contains(applied, m.version) {
+			continue
+		}
+		if !created[m.table] {
+			if !lo.Contains(current, m.table) {
+				stdLog.Printf("SKIP migration `%s` of missing table `%s`", m.version, m.table)
+				continue
+			}
+			for _, stmt := range m.statements {
+				if err := db.Exec(stmt).Error; err != nil {
+					stdLog.Fatalf("APPLY migration `%s` err: %v, statement: %s", m.version, err, stmt)
+				}
+			}
+			stdLog.Printf("APPLY migration `%s`", m.version)
+		}
+		err := db.Exec("INSERT INTO `"+migrationTable+"` (`version`) VALUES (?)", m.version).Error
+		if err != nil {
+			stdLog.Fatalf("record migration `%s` err: %v", m.version, err)
+		}
+	}
+}
+
+const migrationTable = "keepshare_schema_migration"
+
+type migration struct {
+	version    string
+	table      string
+	statements []string
+}
+
+// loadMigrations returns the migrations in the order of versions.
+func loadMigrations() []*migration {
+	files, err := fs.Glob(rawsql.Migrations, "migrations/*.sql")
+	if err != nil {
+		stdLog.Fatal("read rawsql.Migrations err:", err)
+	}
+	sort.Strings(files)
+
+	alter := regexp.MustCompile("(?i)^ALTER\\s+TABLE\\s+`?([_A-Za-z0-9]+)`?")
+	migrations := make([]*migration, 0, len(files))
+	for _, f := range files {
+		b, err := fs.ReadFile(rawsql.Migrations, f)
+		if err != nil {
+			stdLog.Fatal("read rawsql.Migrations err:", err)
+		}
+
+		m := &migration{version: strings.TrimSuffix(path.Base(f), ".sql")}
+		for _, s := range strings.Split(string(b), ";") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			match := alter.FindStringSubmatch(s)
+			if match == nil || (m.table != "" && m.table != match[1]) {
+				stdLog.Fatalf("invalid migration `%s`, it must have ALTER TABLE statements of a table", m.version)
+			}
+			m.table = match[1]
+			m.statements = append(m.statements, s)
+		}
+		migrations = append(migrations, m)
 	}
+	return migrations
 }
 
 func dumpTables(_ *cobra.Command, args []string) {
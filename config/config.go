@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
 	"github.com/KeepShareOrg/keepshare/pkg/log"
 	"github.com/spf13/viper"
 )
@@ -16,9 +18,29 @@ import (
 // define main configs.
 var (
 	DefaultHost = func() string { return viper.GetString("host_default") }
-	RootDomain  = func() string { return viper.GetString("root_domain") }
-	ListenHTTP  = func() string { return viper.GetString("listen_http") }
-	ListenHTTPS = func() string { return viper.GetString("listen_https") }
+	HostPolicy  = func() string { return viper.GetString("host_policy") }
+
+	HostMiddlewares    = func() string { return viper.GetString("host_middlewares") }
+	HostRetryAttempts  = func() int { return viper.GetInt("host_retry_attempts") }
+	HostRetryBackoff   = func() time.Duration { return viper.GetDuration("host_retry_backoff") }
+	HostStatusCacheTTL = func() time.Duration { return viper.GetDuration("host_status_cache_ttl") }
+	LinkStripParams    = func() string { return viper.GetString("link_strip_params") }
+	LinkInfoProviders  = func() string { return viper.GetString("link_info_providers") }
+	LinkInfoCacheTTL   = func() time.Duration { return viper.GetDuration("link_info_cache_ttl") }
+	WhatsLinkEndpoint  = func() string { return viper.GetString("whatslink_endpoint") }
+
+	RateLimitChannel   = func() string { return viper.GetString("rate_limit_channel") }
+	RateLimitIP        = func() string { return viper.GetString("rate_limit_ip") }
+	RateLimitChannelIP = func() string { return viper.GetString("rate_limit_channel_ip") }
+	RateLimitHostTasks = func() string { return viper.GetString("rate_limit_host_tasks") }
+	RateLimitImport    = func() string { return viper.GetString("rate_limit_import") }
+	RootDomain         = func() string { return viper.GetString("root_domain") }
+	ListenHTTP         = func() string { return viper.GetString("listen_http") }
+	ListenHTTPS        = func() string { return viper.GetString("listen_https") }
+	TrustedProxies     = func() string { return viper.GetString("trusted_proxies") }
+
+	ModerationReloadInterval = func() time.Duration { return viper.GetDuration("moderation_reload_interval") }
+	SharedLinkEventsTimeout  = func() time.Duration { return viper.GetDuration("shared_link_events_timeout") }
 
 	LogLevel        = func() string { return viper.GetString("log_level") }
 	LogFormat       = func() string { return viper.GetString("log_format") }
@@ -40,8 +62,29 @@ var (
 var configs = map[string]properties{
 	"root_domain":  {"localhost", "Domain for this project, including web pages or keep sharing links"},
 	"host_default": {"pikpak", "When no host is specified, this host is used by default"},
+	"host_policy":  {"", "Ordered hosts separated by comma to create auto sharing links, e.g. `pikpak,webdav`, the next host is used when the previous one fails. Default to host_default"},
 	"listen_http":  {":8080", "HTTP server listen address"},
-	"listen_https": {"", "HTTPS server listen address"},
+
+	"host_middlewares":      {"logging,metrics,status_cache,retry", "Ordered middlewares separated by comma to wrap all hosts, the first one is the outermost. Options: logging, metrics, status_cache, retry"},
+	"host_retry_attempts":   {3, "The maximum attempts to call idempotent methods of hosts on transient errors"},
+	"host_retry_backoff":    {"200ms", "The backoff before the first retry of host methods, it doubles after each retry"},
+	"host_status_cache_ttl": {"1m", "The duration to cache OK statuses of host shared links"},
+	"link_info_providers":   {"local,whatslink", "Ordered providers separated by comma to get names and sizes of original links, the latter ones fill the fields unknown by the former ones. Options: local, whatslink"},
+	"link_info_cache_ttl":   {"24h", "The duration to cache link info of remote providers, such as whatslink"},
+	"whatslink_endpoint":    {"https://whatslink.info/api/v1/link", "The api endpoint of whatslink.info"},
+	"link_strip_params":     {"utm_*,fbclid,gclid", "Query parameters separated by comma to remove from HTTP(S) and FTP links before hashing, a parameter ends with `*` matches the prefix. Run `keepshare links rehash` after changing it"},
+	"listen_https":          {"", "HTTPS server listen address"},
+	"trusted_proxies":       {"", "IPs or CIDRs of reverse proxies separated by comma, whose X-Forwarded-For and X-Real-IP headers are trusted for client IPs. Empty means client IPs are the remote addresses of connections"},
+
+	"rate_limit_channel":    {"600/1m", "Default limit of new host tasks created by visits to auto sharing links of a channel, in the format `<burst>/<period>`, empty means unlimited. Visits to existing shared links are not limited. Users can override it"},
+	"rate_limit_ip":         {"60/1m", "Limit of new host tasks created by visits to auto sharing links of all channels from a client IP, users can not override it"},
+	"rate_limit_channel_ip": {"30/1m", "Default limit of new host tasks created by visits to auto sharing links of a channel from a client IP, users can override it"},
+	"rate_limit_host_tasks": {"300/1m", "Global limit of new host tasks created by visits to auto sharing links"},
+	"rate_limit_import":     {"600/1m", "Limit of links of an import job created on hosts, empty means as fast as the queue runs"},
+
+	"admin_users":                {"", "IDs of users separated by comma who can manage moderation rules"},
+	"moderation_reload_interval": {"30s", "The interval to reload moderation rules from the database, changes made by the admin API take effect immediately on the same instance"},
+	"shared_link_events_timeout": {"10m", "The maximum duration of streams of shared link events, clients reconnect after that"},
 
 	"log_level":         {"info", "Options: panic, fatal, error, warn, info, debug, trace"},
 	"log_format":        {"json", "Options: json, text"},
@@ -91,6 +134,8 @@ func Load() error {
 		MaxBackups:  LogBackups(),
 	})
 
+	lk.SetStripParams(strings.Split(LinkStripParams(), ",")...)
+
 	if err := initMysql(); err != nil {
 		return err
 	}
@@ -0,0 +1,169 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package chaos provides a host middleware which injects faults for resilience testing.
+// It adds latency and errors to host methods, keeps shared links stuck in CREATED and drops FileComplete events,
+// so that the task runner and status polling can be verified to converge eventually.
+// It must not be enabled in production.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+)
+
+// ErrInjected is returned by methods of hosts when an error is injected, it is transient so it can be retried.
+var ErrInjected = fmt.Errorf("chaos: injected fault: %w", hosts.ErrTransient)
+
+// Fault to inject into a host method.
+type Fault struct {
+	// Latency is added before calling the method.
+	Latency time.Duration `mapstructure:"latency"`
+	// Jitter is the maximum random latency added to Latency.
+	Jitter time.Duration `mapstructure:"jitter"`
+	// ErrorRate is the probability in [0, 1] to return ErrInjected instead of calling the method.
+	ErrorRate float64 `mapstructure:"error_rate"`
+}
+
+// Config of fault injection.
+type Config struct {
+	// Enabled reports whether faults are injected.
+	Enabled bool `mapstructure:"enabled"`
+	// Hosts are the names of hosts to inject faults into, empty means all hosts.
+	Hosts []string `mapstructure:"hosts"`
+	// Methods are the faults of host methods, the key is the case-insensitive method name such as `GetStatuses`,
+	// or `*` for methods which are not configured.
+	Methods map[string]Fault `mapstructure:"methods"`
+	// StuckRate is the probability in [0, 1] that GetStatuses and CreateFromLinks report an OK shared link as CREATED.
+	StuckRate float64 `mapstructure:"stuck_rate"`
+	// DropFileCompleteRate is the probability in [0, 1] to drop a FileComplete event of the hosts.
+	DropFileCompleteRate float64 `mapstructure:"drop_file_complete_rate"`
+}
+
+// Middleware returns a middleware which injects faults into hosts by the config,
+// FileComplete events published to the event bus are dropped by the config too.
+func Middleware(c *Config, events *hosts.EventBus) hosts.Middleware {
+	methods := make(map[string]Fault, len(c.Methods))
+	for k, v := range c.Methods {
+		methods[strings.ToLower(k)] = v
+	}
+	ch := &chaos{config: c, methods: methods, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+	if events != nil && c.DropFileCompleteRate > 0 {
+		events.Filter(func(ctx context.Context, e hosts.Event) bool {
+			ev, ok := e.(hosts.FileCompleteEvent)
+			if !ok || !ch.enabled(ev.Host) || !ch.hit(c.DropFileCompleteRate) {
+				return true
+			}
+			log.WithContext(ctx).WithFields(log.Fields{
+				hosts.LogFieldHost: ev.Host,
+				"hash":             ev.OriginalLinkHash,
+			}).Warn("chaos: drop file complete event")
+			return false
+		})
+	}
+
+	return func(name string, next hosts.Host) hosts.Host {
+		if !ch.enabled(name) {
+			return next
+		}
+		return &stuck{Host: hosts.Intercept(ch.intercept)(name, next), ch: ch}
+	}
+}
+
+type chaos struct {
+	config  *Config
+	methods map[string]Fault // key: lower case method name
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func (ch *chaos) enabled(host string) bool {
+	return len(ch.config.Hosts) == 0 || slices.ContainsFunc(ch.config.Hosts, func(h string) bool { return strings.EqualFold(h, host) })
+}
+
+// hit returns true with the probability.
+func (ch *chaos) hit(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.rand.Float64() < probability
+}
+
+func (ch *chaos) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return time.Duration(ch.rand.Int63n(int64(max)))
+}
+
+func (ch *chaos) intercept(ctx context.Context, call *hosts.Call, invoke hosts.Invoker) error {
+	f, ok := ch.methods[strings.ToLower(call.Method)]
+	if !ok {
+		f = ch.methods["*"]
+	}
+
+	if latency := f.Latency + ch.jitter(f.Jitter); latency > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(latency):
+		}
+	}
+
+	if ch.hit(f.ErrorRate) {
+		log.WithContext(ctx).WithFields(log.Fields{
+			hosts.LogFieldHost: call.Host,
+			"method":           call.Method,
+		}).Warn("chaos: inject error")
+		return ErrInjected
+	}
+	return invoke(ctx)
+}
+
+// stuck reports OK shared links as CREATED by StuckRate.
+type stuck struct {
+	hosts.Host
+	ch *chaos
+}
+
+func (h *stuck) Unwrap() hosts.Host {
+	return h.Host
+}
+
+func (h *stuck) CreateFromLinks(ctx context.Context, userID string, originalLinks []string, createBy string, ip string) (map[string]*share.Share, error) {
+	sharedLinks, err := h.Host.CreateFromLinks(ctx, userID, originalLinks, createBy, ip)
+	for link, sh := range sharedLinks {
+		if sh != nil && sh.State == share.StatusOK && h.ch.hit(h.ch.config.StuckRate) {
+			c := *sh
+			c.State = share.StatusCreated
+			sharedLinks[link] = &c
+		}
+	}
+	return sharedLinks, err
+}
+
+func (h *stuck) GetStatuses(ctx context.Context, userID string, hostSharedLinks []string) (map[string]share.State, error) {
+	statuses, err := h.Host.GetStatuses(ctx, userID, hostSharedLinks)
+	for link, state := range statuses {
+		if state == share.StatusOK && h.ch.hit(h.ch.config.StuckRate) {
+			statuses[link] = share.StatusCreated
+		}
+	}
+	return statuses, err
+}
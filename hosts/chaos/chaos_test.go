@@ -0,0 +1,96 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package chaos
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(`
+chaos:
+  enabled: true
+  hosts: pikpak,webdav
+  methods:
+    GetStatuses:
+      latency: 100ms
+      error_rate: 0.5
+  stuck_rate: 0.1
+`)))
+
+	var c Config
+	require.NoError(t, v.UnmarshalKey("chaos", &c))
+	assert.True(t, c.Enabled)
+	assert.Equal(t, []string{"pikpak", "webdav"}, c.Hosts)
+	assert.Equal(t, Fault{Latency: 100 * time.Millisecond, ErrorRate: 0.5}, c.Methods["getstatuses"])
+	assert.Equal(t, 0.1, c.StuckRate)
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	fake := hoststest.NewFake()
+	fake.CompleteAfter = -1
+	link := "https://example.com/a.mp4"
+
+	var completed int
+	hosts.Subscribe(fake.Events, func(context.Context, hosts.FileCompleteEvent) error {
+		completed++
+		return nil
+	})
+
+	c := &Config{
+		Hosts: []string{"Fake"},
+		Methods: map[string]Fault{
+			"CreateFromLinks": {Latency: 10 * time.Millisecond},
+			"*":               {ErrorRate: 1},
+		},
+		StuckRate:            1,
+		DropFileCompleteRate: 1,
+	}
+	m := Middleware(c, fake.Events)
+	assert.Same(t, fake, m("other", fake), "hosts which are not configured are not wrapped")
+
+	h := m("fake", fake)
+	_, ok := hosts.As[*hoststest.Fake](h)
+	assert.True(t, ok)
+
+	start := time.Now()
+	_, err := h.CreateFromLinks(ctx, "user", []string{link}, share.AutoShare, "")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+	fake.Complete("user", link)
+	assert.Zero(t, completed, "file complete events are dropped")
+	sharedLinks, err := h.CreateFromLinks(ctx, "user", []string{link}, share.AutoShare, "")
+	require.NoError(t, err)
+	assert.Equal(t, share.StatusCreated, sharedLinks[link].State, "OK links are stuck in CREATED")
+
+	_, err = h.GetStatuses(ctx, "user", []string{sharedLinks[link].HostSharedLink})
+	assert.ErrorIs(t, err, ErrInjected)
+	assert.True(t, hosts.IsTransient(err))
+
+	c.StuckRate, c.DropFileCompleteRate = 0, 0
+	sharedLinks, err = h.CreateFromLinks(ctx, "user", []string{link}, share.AutoShare, "")
+	require.NoError(t, err)
+	assert.Equal(t, share.StatusOK, sharedLinks[link].State)
+
+	link = "https://example.com/b.mp4"
+	_, err = h.CreateFromLinks(ctx, "user", []string{link}, share.AutoShare, "")
+	require.NoError(t, err)
+	fake.Complete("user", link)
+	assert.Equal(t, 1, completed)
+}
@@ -0,0 +1,264 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package hosts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/queue"
+	"github.com/hibiken/asynq"
+)
+
+// EventQueue is the queue of the tasks of queue-backed subscribers, servers must process it.
+const EventQueue = "host_event"
+
+// EventType is the type of event.
+type EventType string
+
+// Enum all event types.
+const (
+	FileComplete          EventType = "file_complete"
+	FileProgress          EventType = "file_progress"
+	FileError             EventType = "file_error"
+	ShareCreated          EventType = "share_created"
+	ShareDeleted          EventType = "share_deleted"
+	WorkerExhausted       EventType = "worker_exhausted"
+	MasterPasswordInvalid EventType = "master_password_invalid"
+)
+
+// Event is the payload of an event published by hosts.
+type Event interface {
+	Type() EventType
+}
+
+// FileCompleteEvent is published when a file has been downloaded by the host.
+type FileCompleteEvent struct {
+	Host string `json:"host"`
+	// UserID is the owner of the file, it is the keepshare user id, except the worker user id for PikPak.
+	UserID           string `json:"user_id"`
+	OriginalLinkHash string `json:"original_link_hash"`
+}
+
+// FileProgressEvent is published when the download progress of a file is updated by the host.
+type FileProgressEvent struct {
+	Host string `json:"host"`
+	// UserID is the owner of the file, it is the keepshare user id, except the worker user id for PikPak.
+	UserID           string `json:"user_id"`
+	OriginalLinkHash string `json:"original_link_hash"`
+	// Progress is the percentage in [0, 100].
+	Progress int `json:"progress"`
+}
+
+// FileErrorEvent is published when the host failed to download a file.
+type FileErrorEvent struct {
+	Host string `json:"host"`
+	// UserID is the owner of the file, it is the keepshare user id, except the worker user id for PikPak.
+	UserID           string `json:"user_id"`
+	OriginalLinkHash string `json:"original_link_hash"`
+	Error            string `json:"error"`
+}
+
+// ShareCreatedEvent is published when a host shared link is created for the keepshare user.
+type ShareCreatedEvent struct {
+	Host           string `json:"host"`
+	UserID         string `json:"user_id"`
+	OriginalLink   string `json:"original_link"`
+	HostSharedLink string `json:"host_shared_link"`
+}
+
+// ShareDeletedEvent is published when shared links of the keepshare user are deleted from the host.
+type ShareDeletedEvent struct {
+	Host          string   `json:"host"`
+	UserID        string   `json:"user_id"`
+	OriginalLinks []string `json:"original_links"`
+}
+
+// WorkerExhaustedEvent is published when there are no worker accounts with enough capacity for the master account.
+type WorkerExhaustedEvent struct {
+	Host         string `json:"host"`
+	MasterUserID string `json:"master_user_id"`
+	Size         int64  `json:"size"`
+	Error        string `json:"error"`
+}
+
+// MasterPasswordInvalidEvent is published when the host can not login to the master account of the keepshare user.
+type MasterPasswordInvalidEvent struct {
+	Host         string `json:"host"`
+	UserID       string `json:"user_id"`
+	MasterUserID string `json:"master_user_id"`
+}
+
+// Type implements Event.
+func (FileCompleteEvent) Type() EventType { return FileComplete }
+
+// Type implements Event.
+func (FileProgressEvent) Type() EventType { return FileProgress }
+
+// Type implements Event.
+func (FileErrorEvent) Type() EventType { return FileError }
+
+// Type implements Event.
+func (ShareCreatedEvent) Type() EventType { return ShareCreated }
+
+// Type implements Event.
+func (ShareDeletedEvent) Type() EventType { return ShareDeleted }
+
+// Type implements Event.
+func (WorkerExhaustedEvent) Type() EventType { return WorkerExhausted }
+
+// Type implements Event.
+func (MasterPasswordInvalidEvent) Type() EventType { return MasterPasswordInvalid }
+
+// EventBus delivers events of hosts to subscribers, it is safe for concurrent use.
+// Publishing to a nil *EventBus does nothing.
+type EventBus struct {
+	queue *queue.Client
+
+	mu      sync.RWMutex
+	nextID  int
+	subs    map[EventType][]*subscriber
+	filters []*filter
+}
+
+type filter struct {
+	id int
+	fn func(ctx context.Context, e Event) bool
+}
+
+type subscriber struct {
+	id   int
+	name string // the name of queue-backed subscribers.
+	fn   func(ctx context.Context, e Event) error
+}
+
+// NewEventBus returns an event bus, the queue is required by queue-backed subscribers only.
+func NewEventBus(q *queue.Client) *EventBus {
+	return &EventBus{queue: q, subs: map[EventType][]*subscriber{}}
+}
+
+func (b *EventBus) add(typ EventType, name string, fn func(ctx context.Context, e Event) error) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.subs[typ] = append(b.subs[typ], &subscriber{id: id, name: name, fn: fn})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.subs[typ] = slices.DeleteFunc(b.subs[typ], func(s *subscriber) bool { return s.id == id })
+	}
+}
+
+// Filter adds a function which is called by Publish before delivering events,
+// the event is dropped if it returns false. It returns a function to remove the filter.
+func (b *EventBus) Filter(fn func(ctx context.Context, e Event) bool) (remove func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.filters = append(b.filters, &filter{id: id, fn: fn})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.filters = slices.DeleteFunc(b.filters, func(f *filter) bool { return f.id == id })
+	}
+}
+
+// Subscribe adds a handler of the event E, which is called synchronously by Publish.
+// Handlers are called in the order of subscription, it returns a function to unsubscribe.
+func Subscribe[E Event](b *EventBus, fn func(ctx context.Context, e E) error) (unsubscribe func()) {
+	var zero E
+	return b.add(zero.Type(), "", func(ctx context.Context, e Event) error {
+		v, ok := e.(E)
+		if !ok {
+			return fmt.Errorf("unexpected event %T", e)
+		}
+		return fn(ctx, v)
+	})
+}
+
+// SubscribeQueue adds a handler of the event E, which is called asynchronously by the queue.
+// Each event is handled once by one of the replicas and retried if the handler returns an error.
+// The name identifies the subscriber across replicas, all replicas must subscribe with the same name.
+func SubscribeQueue[E Event](b *EventBus, name string, fn func(ctx context.Context, e E) error) (unsubscribe func(), err error) {
+	if b.queue == nil {
+		return nil, errors.New("the event bus has no queue")
+	}
+
+	var zero E
+	typ := zero.Type()
+	taskType := fmt.Sprintf("host_event:%s:%s", typ, name)
+
+	var unsubscribed atomic.Bool
+	err = b.queue.RegisterHandler(taskType, asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if unsubscribed.Load() {
+			return nil
+		}
+		var e E
+		if err := json.Unmarshal(t.Payload(), &e); err != nil {
+			log.WithContext(ctx).WithField("task_type", taskType).Errorf("unmarshal event err: %v", err)
+			return nil // never succeed, do not retry.
+		}
+		return fn(ctx, e)
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("register handler of %s err: %w", taskType, err)
+	}
+
+	unsub := b.add(typ, name, func(ctx context.Context, e Event) error {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = b.queue.Enqueue(taskType, payload, asynq.Queue(EventQueue))
+		return err
+	})
+	return func() {
+		unsubscribed.Store(true)
+		unsub()
+	}, nil
+}
+
+// Publish delivers the event to subscribers in the order of subscription unless it is dropped by filters,
+// synchronous handlers are called in the current goroutine and queue-backed subscribers get a task each.
+// All subscribers are called even if some of them fail, and the errors are joined.
+func (b *EventBus) Publish(ctx context.Context, e Event) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.RLock()
+	subs := slices.Clone(b.subs[e.Type()])
+	filters := slices.Clone(b.filters)
+	b.mu.RUnlock()
+
+	for _, f := range filters {
+		if !f.fn(ctx, e) {
+			return nil
+		}
+	}
+
+	var errs []error
+	for _, s := range subs {
+		if err := s.fn(ctx, e); err != nil {
+			if s.name != "" {
+				err = fmt.Errorf("enqueue event for %s err: %w", s.name, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package hosts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBus(t *testing.T) {
+	ctx := context.Background()
+	b := NewEventBus(nil)
+
+	var calls []string
+	Subscribe(b, func(_ context.Context, e FileCompleteEvent) error {
+		calls = append(calls, "first:"+e.OriginalLinkHash)
+		return errors.New("first failed")
+	})
+	unsubscribe := Subscribe(b, func(_ context.Context, e FileCompleteEvent) error {
+		calls = append(calls, "second:"+e.OriginalLinkHash)
+		return nil
+	})
+	Subscribe(b, func(_ context.Context, e ShareDeletedEvent) error {
+		calls = append(calls, "deleted:"+e.UserID)
+		return nil
+	})
+
+	err := b.Publish(ctx, FileCompleteEvent{Host: "test", UserID: "user", OriginalLinkHash: "hash"})
+	assert.EqualError(t, err, "first failed")
+	assert.Equal(t, []string{"first:hash", "second:hash"}, calls)
+
+	calls = nil
+	unsubscribe()
+	assert.Error(t, b.Publish(ctx, FileCompleteEvent{OriginalLinkHash: "hash"}))
+	assert.NoError(t, b.Publish(ctx, ShareDeletedEvent{UserID: "user"}))
+	assert.NoError(t, b.Publish(ctx, FileErrorEvent{}))
+	assert.Equal(t, []string{"first:hash", "deleted:user"}, calls)
+
+	calls = nil
+	remove := b.Filter(func(_ context.Context, e Event) bool { return e.Type() != ShareDeleted })
+	assert.NoError(t, b.Publish(ctx, ShareDeletedEvent{UserID: "user"}))
+	assert.Empty(t, calls)
+	remove()
+	assert.NoError(t, b.Publish(ctx, ShareDeletedEvent{UserID: "user"}))
+	assert.Equal(t, []string{"deleted:user"}, calls)
+
+	_, err = SubscribeQueue(b, "test", func(context.Context, FileCompleteEvent) error { return nil })
+	assert.Error(t, err)
+
+	var nilBus *EventBus
+	assert.NoError(t, nilBus.Publish(ctx, FileCompleteEvent{}))
+}
@@ -9,8 +9,10 @@ import (
 	"errors"
 	"io/fs"
 	"regexp"
+	"slices"
 	"strings"
 
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
 	"github.com/KeepShareOrg/keepshare/pkg/mail"
 	"github.com/KeepShareOrg/keepshare/pkg/queue"
 	"github.com/KeepShareOrg/keepshare/pkg/share"
@@ -19,6 +21,8 @@ import (
 )
 
 // Host is an interface of file hosting provider.
+// A host may also implement http.Handler to serve its own pages, requests to `/hosts/<name>/*` are routed to it.
+// Hosts publish events such as FileComplete to Dependencies.Events.
 type Host interface {
 	// CreateShare creates a shared link for a file.
 	CreateShare(ctx context.Context, master string, worker string, fileID string) (sharedLink string, err error)
@@ -38,9 +42,6 @@ type Host interface {
 	// HostInfo returns basic information of the host.
 	HostInfo(ctx context.Context, userID string, options map[string]any) (resp map[string]any, err error)
 
-	// AddEventListener add an event listener to the host.
-	AddEventListener(event EventType, callback ListenerCallback)
-
 	// ChangeMasterAccountPassword changes the master account password of the host.
 	ChangeMasterAccountPassword(ctx context.Context, userID, newPassword string, SavePassword bool) (string, error)
 
@@ -55,17 +56,48 @@ type Host interface {
 
 	// DonateRedeemCode donates the redeem code of the host.
 	DonateRedeemCode(ctx context.Context, nickname, userID string, redeemCodes []string) error
+
+	// Capabilities returns what the host supports.
+	Capabilities() Capabilities
 }
 
-// EventType is the type of event.
-type EventType string
+// Capabilities describes the features supported by a host.
+type Capabilities struct {
+	// LinkSchemes are the lower case schemes of original links that the host can create shared links from, e.g. `magnet`.
+	LinkSchemes []string `json:"link_schemes"`
 
-const (
-	// FileComplete is the event type of file complete.
-	FileComplete EventType = "file_complete"
-)
+	// MaxFileSize is the maximum size of a single file in bytes, 0 means unlimited or unknown.
+	MaxFileSize int64 `json:"max_file_size"`
+
+	// PasswordShares reports whether the host shared links can be protected by password.
+	PasswordShares bool `json:"password_shares"`
+
+	// Statistics reports whether GetStatistics returns the real visits of host shared links.
+	Statistics bool `json:"statistics"`
+
+	// Revenue reports whether the host pays commissions to the keepshare user.
+	Revenue bool `json:"revenue"`
+
+	// PasswordManagement reports whether the master account password can be changed and confirmed.
+	PasswordManagement bool `json:"password_management"`
+
+	// RedeemCodes reports whether redeem codes can be donated to the host.
+	RedeemCodes bool `json:"redeem_codes"`
 
-type ListenerCallback func(userID, originalLinkHash string)
+	// ReleaseOnlyForPremium reports whether the storage release can be limited to premium worker accounts,
+	// the host must provide Properties.PremiumLinks.
+	ReleaseOnlyForPremium bool `json:"release_only_for_premium"`
+
+	// RedirectParams are the names of query parameters understood by the host shared pages,
+	// such as `act` to play automatically on PikPak.
+	RedirectParams []string `json:"redirect_params"`
+}
+
+// SupportsLink returns whether the scheme of the link is supported.
+func (c Capabilities) SupportsLink(link string) bool {
+	scheme, _, found := strings.Cut(link, ":")
+	return found && slices.Contains(c.LinkSchemes, strings.ToLower(scheme))
+}
 
 // Properties of a host.
 type Properties struct {
@@ -80,6 +112,19 @@ type Properties struct {
 	// The name of tables must be prefixed with the host provider's name.
 	// If the host do not need mysql database, this property can be empty.
 	CreateTableStatements []string
+
+	// Middlewares wrap this host only, inside the middlewares of Use.
+	Middlewares []Middleware
+
+	// RehashLinks updates the hashes of original links stored by the host for the keepshare user,
+	// the keys of hashes are the old hashes and the values are the new ones.
+	// It is called without starting the host, after the algorithm of link.Hash changes.
+	// If the host do not store hashes of original links, this property can be empty.
+	RehashLinks func(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes map[string]string) (rows int64, err error)
+
+	// PremiumLinks returns the hashes of original links stored by premium accounts for the keepshare user,
+	// among the given hashes. It is required if the host supports Capabilities.ReleaseOnlyForPremium.
+	PremiumLinks func(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes []string) (premium []string, err error)
 }
 
 // Dependencies of hosts.
@@ -88,6 +133,10 @@ type Dependencies struct {
 	Redis  *redis.Client
 	Mailer mail.Mailer
 	Queue  *queue.Client
+	Events *EventBus
+
+	// LinkInfo provides names and sizes of original links, it may be nil.
+	LinkInfo lk.LinkInfoProvider
 }
 
 var hosts = map[string]*HostWithProperties{}
@@ -106,6 +155,9 @@ var (
 	ErrInvalidName           = errors.New("name does not match this regular expression: " + namePattern)
 )
 
+// ErrNotSupported is returned when the host does not support the operation.
+var ErrNotSupported = errors.New("operation not supported by the host")
+
 // Register a new host.
 func Register(p *Properties) error {
 	if match, _ := regexp.MatchString(namePattern, p.Name); !match {
@@ -121,11 +173,17 @@ func Register(p *Properties) error {
 	return nil
 }
 
-// Start all hosts.
+// Unregister removes the host, it is used to clean up hosts registered by tests.
+func Unregister(name string) {
+	delete(hosts, strings.ToLower(name))
+}
+
+// Start all hosts, each host is wrapped by the middlewares of its properties and Use.
 func Start(d *Dependencies) {
-	for _, v := range hosts {
+	for name, v := range hosts {
 		if v.Host == nil {
-			v.Host = v.p.New(d)
+			h := wrap(name, v.p.New(d), v.p.Middlewares)
+			v.Host = wrap(name, h, middlewares)
 		}
 	}
 }
@@ -154,6 +212,40 @@ func (h *HostWithProperties) CreateTableStatements() []string {
 	return h.p.CreateTableStatements
 }
 
+// RehashLinks updates the hashes of original links stored by the host, see Properties.RehashLinks.
+func (h *HostWithProperties) RehashLinks(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes map[string]string) (rows int64, err error) {
+	if h.p.RehashLinks == nil || len(hashes) == 0 {
+		return 0, nil
+	}
+	return h.p.RehashLinks(ctx, db, keepshareUserID, hashes)
+}
+
+// PremiumLinks returns the hashes of original links stored by premium accounts, see Properties.PremiumLinks.
+func (h *HostWithProperties) PremiumLinks(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes []string) ([]string, error) {
+	if h.p.PremiumLinks == nil {
+		return nil, ErrNotSupported
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	return h.p.PremiumLinks(ctx, db, keepshareUserID, hashes)
+}
+
+type sizeHintsKey struct{}
+
+// WithSizeHints returns a context carrying the known total sizes of original links, such as sizes parsed from torrent files,
+// so that hosts can skip querying the sizes before creating files. The key is the original link.
+func WithSizeHints(ctx context.Context, hints map[string]int64) context.Context {
+	return context.WithValue(ctx, sizeHintsKey{}, hints)
+}
+
+// SizeHint returns the size of the original link carried by the context.
+func SizeHint(ctx context.Context, link string) (size int64, ok bool) {
+	hints, _ := ctx.Value(sizeHintsKey{}).(map[string]int64)
+	size, ok = hints[link]
+	return size, ok && size > 0
+}
+
 // ReadSQLFileFromFS walk the directory and read *.sql files from FS such as embed.FS.
 func ReadSQLFileFromFS(fsys fs.FS) ([]string, error) {
 	var data []string
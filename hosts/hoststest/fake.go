@@ -0,0 +1,273 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package hoststest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/stretchr/testify/require"
+)
+
+// Fake is an in-memory host, it is safe for concurrent use.
+// Created files complete after CompleteAfter, or when Complete is called if CompleteAfter is negative.
+type Fake struct {
+	// Name is the host name of published events.
+	Name string
+	// Events receives the events of the fake host.
+	Events *hosts.EventBus
+	// CompleteAfter is the delay before a created file completes, negative means never complete automatically.
+	CompleteAfter time.Duration
+	// States forces the final state of original links, such as SENSITIVE or ERROR.
+	States map[string]share.State
+	// CreateErr is returned by CreateFromLinks if it is not nil.
+	CreateErr error
+	// Caps is returned by Capabilities.
+	Caps hosts.Capabilities
+
+	mu      sync.Mutex
+	files   map[string]*fakeFile // key: userID/originalLinkHash
+	created int
+}
+
+type fakeFile struct {
+	userID     string
+	link       string
+	state      share.State
+	sharedLink string
+	createdAt  time.Time
+	visitor    int32
+}
+
+// NewFake returns a fake host whose files complete immediately.
+func NewFake() *Fake {
+	return &Fake{
+		Name:   "fake",
+		Events: hosts.NewEventBus(nil),
+		States: map[string]share.State{},
+		Caps:   hosts.Capabilities{LinkSchemes: []string{"magnet", "http", "https"}, Statistics: true},
+		files:  map[string]*fakeFile{},
+	}
+}
+
+// FakeProperties returns the properties to register the fake host with the name,
+// the fake host publishes events to Dependencies.Events if it is not nil.
+func FakeProperties(name string, f *Fake) *hosts.Properties {
+	f.Name = name
+	return &hosts.Properties{
+		Name: name,
+		New: func(d *hosts.Dependencies) hosts.Host {
+			if d != nil && d.Events != nil {
+				f.Events = d.Events
+			}
+			return f
+		},
+	}
+}
+
+// Register registers and starts the fake host with the name, it is unregistered when the test ends.
+func Register(t testing.TB, name string, f *Fake) *hosts.HostWithProperties {
+	t.Helper()
+	require.NoError(t, hosts.Register(FakeProperties(name, f)))
+	t.Cleanup(func() { hosts.Unregister(name) })
+	hosts.Start(&hosts.Dependencies{})
+	return hosts.Get(name)
+}
+
+func fakeKey(userID, link string) string {
+	return userID + "/" + lk.Hash(link)
+}
+
+// Created returns the number of files created, which is the number of downloads started on a real host.
+func (f *Fake) Created() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.created
+}
+
+// Complete completes a created file and publishes the FileComplete event.
+func (f *Fake) Complete(userID, link string) {
+	f.mu.Lock()
+	file := f.files[fakeKey(userID, link)]
+	if file == nil || file.state != share.StatusCreated {
+		f.mu.Unlock()
+		return
+	}
+	file.state = share.StatusOK
+	if st, ok := f.States[link]; ok {
+		file.state = st
+	}
+	if file.state == share.StatusOK {
+		file.sharedLink = fmt.Sprintf("https://fake.host/s/%s", fakeKey(userID, link))
+	}
+	state, sharedLink := file.state, file.sharedLink
+	f.mu.Unlock()
+
+	ctx := context.Background()
+	if state == share.StatusError {
+		_ = f.Events.Publish(ctx, hosts.FileErrorEvent{Host: f.Name, UserID: userID, OriginalLinkHash: lk.Hash(link), Error: "fake error"})
+		return
+	}
+	_ = f.Events.Publish(ctx, hosts.FileCompleteEvent{Host: f.Name, UserID: userID, OriginalLinkHash: lk.Hash(link)})
+	if sharedLink != "" {
+		_ = f.Events.Publish(ctx, hosts.ShareCreatedEvent{Host: f.Name, UserID: userID, OriginalLink: link, HostSharedLink: sharedLink})
+	}
+}
+
+// CreateShare returns the shared link of the file, the fileID is `userID/originalLinkHash`.
+func (f *Fake) CreateShare(_ context.Context, _ string, _ string, fileID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file := f.files[fileID]
+	if file == nil || file.sharedLink == "" {
+		return "", fmt.Errorf("file_not_found: %s", fileID)
+	}
+	return file.sharedLink, nil
+}
+
+// CreateFromLinks create shared links based on the input original links.
+func (f *Fake) CreateFromLinks(_ context.Context, userID string, originalLinks []string, createBy string, _ string) (map[string]*share.Share, error) {
+	if f.CreateErr != nil {
+		return nil, f.CreateErr
+	}
+
+	var toComplete []string
+	f.mu.Lock()
+	sharedLinks := make(map[string]*share.Share, len(originalLinks))
+	for _, link := range originalLinks {
+		key := fakeKey(userID, link)
+		file := f.files[key]
+		if file != nil && file.state == share.StatusError {
+			delete(f.files, key) // create again next time.
+		}
+		if file == nil {
+			file = &fakeFile{userID: userID, link: link, state: share.StatusCreated, createdAt: time.Now()}
+			f.files[key] = file
+			f.created++
+			if f.CompleteAfter >= 0 {
+				toComplete = append(toComplete, link)
+			}
+		}
+		sharedLinks[link] = &share.Share{
+			State:          file.state,
+			Title:          link,
+			HostSharedLink: file.sharedLink,
+			OriginalLink:   link,
+			CreatedBy:      createBy,
+			CreatedAt:      file.createdAt,
+			Statistics:     share.Statistics{Visitor: file.visitor},
+		}
+	}
+	f.mu.Unlock()
+
+	for _, link := range toComplete {
+		link := link
+		time.AfterFunc(f.CompleteAfter, func() { f.Complete(userID, link) })
+	}
+	return sharedLinks, nil
+}
+
+func (f *Fake) findBySharedLink(userID, sharedLink string) *fakeFile {
+	for _, file := range f.files {
+		if file.sharedLink == sharedLink && (userID == "" || file.userID == userID) {
+			return file
+		}
+	}
+	return nil
+}
+
+// GetStatuses return the statuses of each host shared link.
+func (f *Fake) GetStatuses(_ context.Context, userID string, hostSharedLinks []string) (map[string]share.State, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	statuses := make(map[string]share.State, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		statuses[link] = share.StatusNotFound
+		if file := f.findBySharedLink(userID, link); file != nil {
+			statuses[link] = file.state
+		}
+	}
+	return statuses, nil
+}
+
+// GetStatistics return the statistics of each host shared link.
+func (f *Fake) GetStatistics(_ context.Context, userID string, hostSharedLinks []string) (map[string]share.Statistics, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	details := make(map[string]share.Statistics, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		if file := f.findBySharedLink(userID, link); file != nil {
+			details[link] = share.Statistics{Visitor: file.visitor}
+		}
+	}
+	return details, nil
+}
+
+// Delete delete shared links by original links.
+func (f *Fake) Delete(ctx context.Context, userID string, originalLinks []string) error {
+	f.mu.Lock()
+	var deleted []string
+	for _, link := range originalLinks {
+		if _, ok := f.files[fakeKey(userID, link)]; ok {
+			delete(f.files, fakeKey(userID, link))
+			deleted = append(deleted, link)
+		}
+	}
+	f.mu.Unlock()
+
+	if len(deleted) == 0 {
+		return nil
+	}
+	return f.Events.Publish(ctx, hosts.ShareDeletedEvent{Host: f.Name, UserID: userID, OriginalLinks: deleted})
+}
+
+// HostInfo returns the number of files of the user.
+func (f *Fake) HostInfo(_ context.Context, userID string, _ map[string]any) (map[string]any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, file := range f.files {
+		if file.userID == userID {
+			n++
+		}
+	}
+	return map[string]any{"files": n}, nil
+}
+
+// ChangeMasterAccountPassword is not supported.
+func (f *Fake) ChangeMasterAccountPassword(context.Context, string, string, bool) (string, error) {
+	return "", hosts.ErrNotSupported
+}
+
+// ConfirmMasterAccountPassword is not supported.
+func (f *Fake) ConfirmMasterAccountPassword(context.Context, string, string, bool) error {
+	return hosts.ErrNotSupported
+}
+
+// GetMasterAccountLoginStatus is always valid.
+func (f *Fake) GetMasterAccountLoginStatus(context.Context, string) (string, error) {
+	return "valid", nil
+}
+
+// AssignMasterAccount does nothing.
+func (f *Fake) AssignMasterAccount(context.Context, string) error {
+	return nil
+}
+
+// DonateRedeemCode is not supported.
+func (f *Fake) DonateRedeemCode(context.Context, string, string, []string) error {
+	return hosts.ErrNotSupported
+}
+
+// Capabilities returns Caps.
+func (f *Fake) Capabilities() hosts.Capabilities {
+	return f.Caps
+}
@@ -0,0 +1,292 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package hoststest provides a conformance test suite for hosts.Host implementations,
+// and an in-memory fake host for server tests.
+//
+// A host provider runs the suite in its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		hoststest.Run(t, properties, &hoststest.Options{
+//			Dependencies: hoststest.MySQLDependencies(t, properties),
+//			Links:        []string{origin.URL + "/a.txt"},
+//		})
+//	}
+package hoststest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/download"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Options of the conformance test suite.
+type Options struct {
+	// Dependencies are passed to Properties.New.
+	Dependencies *hosts.Dependencies
+	// Links are the original links which the host can complete, e.g. served by an httptest server.
+	Links []string
+	// Timeout is the max time to wait for the links to complete, default 30s.
+	Timeout time.Duration
+	// Concurrency is the number of goroutines in the concurrent test, default 8.
+	Concurrency int
+}
+
+// Run runs the standard battery of tests against the host created by the properties.
+func Run(t *testing.T, p *hosts.Properties, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 8
+	}
+
+	t.Run("Tables", func(t *testing.T) { testTables(t, p) })
+
+	require.NotEmpty(t, opts.Links, "the links to create are required")
+	if opts.Dependencies == nil {
+		opts.Dependencies = &hosts.Dependencies{}
+	}
+	if opts.Dependencies.Events == nil {
+		opts.Dependencies.Events = hosts.NewEventBus(nil)
+	}
+
+	s := &suite{opts: opts, host: p.New(opts.Dependencies), completed: map[string]int{}}
+	unsubscribe := hosts.Subscribe(opts.Dependencies.Events, func(_ context.Context, e hosts.FileCompleteEvent) error {
+		if strings.EqualFold(e.Host, p.Name) {
+			s.onComplete(e.OriginalLinkHash)
+		}
+		return nil
+	})
+	defer unsubscribe()
+
+	t.Run("Capabilities", s.testCapabilities)
+	t.Run("CreateFromLinks", s.testCreateFromLinks)
+	t.Run("Delete", s.testDelete)
+	t.Run("Concurrent", s.testConcurrent)
+}
+
+var tableNameRegexp = regexp.MustCompile("(?i)CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?`?([_A-Za-z0-9]+)`?")
+
+// testTables checks that the names of tables are prefixed with the host name.
+func testTables(t *testing.T, p *hosts.Properties) {
+	for _, m := range tableNameRegexp.FindAllStringSubmatch(strings.Join(p.CreateTableStatements, "\n"), -1) {
+		table := strings.ToLower(m[1])
+		assert.True(t, strings.HasPrefix(table, strings.ToLower(p.Name)+"_"), "table `%s` is not prefixed with the host name `%s`", table, p.Name)
+	}
+}
+
+type suite struct {
+	opts *Options
+	host hosts.Host
+
+	mu        sync.Mutex
+	completed map[string]int // key: originalLinkHash
+}
+
+func (s *suite) onComplete(originalLinkHash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completed[originalLinkHash]++
+}
+
+func (s *suite) completedTimes(link string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.completed[lk.Hash(link)]
+}
+
+// newUserID returns a unique user id for each test, so that tests don't affect each other.
+func newUserID() string {
+	return fmt.Sprintf("t%015d", time.Now().UnixNano()%1e15)
+}
+
+// testCapabilities checks that the links of the suite are supported by the host.
+func (s *suite) testCapabilities(t *testing.T) {
+	caps := s.host.Capabilities()
+	assert.NotEmpty(t, caps.LinkSchemes, "link schemes are required")
+	for _, scheme := range caps.LinkSchemes {
+		assert.Equal(t, strings.ToLower(scheme), scheme, "link schemes must be lower case")
+	}
+	for _, link := range s.opts.Links {
+		assert.True(t, caps.SupportsLink(link), "link %s is not supported", link)
+	}
+}
+
+// waitOK polls CreateFromLinks until all links are OK.
+func (s *suite) waitOK(t *testing.T, userID string, links []string) map[string]*share.Share {
+	ctx := context.Background()
+	deadline := time.Now().Add(s.opts.Timeout)
+	for {
+		shares, err := s.host.CreateFromLinks(ctx, userID, links, share.AutoShare, "")
+		require.NoError(t, err)
+
+		done := true
+		for _, link := range links {
+			sh := shares[link]
+			require.NotNil(t, sh, "no share of link %s", link)
+			require.NotEqual(t, share.StatusError, sh.State, "link: %s, error: %s", link, sh.Error)
+			if sh.State != share.StatusOK {
+				done = false
+			}
+		}
+		if done {
+			return shares
+		}
+		require.True(t, time.Now().Before(deadline), "timeout waiting for links to complete")
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// waitCompleted waits until the FileComplete event of each link is published.
+func (s *suite) waitCompleted(t *testing.T, links []string) {
+	deadline := time.Now().Add(s.opts.Timeout)
+	for _, link := range links {
+		for s.completedTimes(link) == 0 {
+			require.True(t, time.Now().Before(deadline), "timeout waiting for FileComplete of %s", link)
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// testCreateFromLinks checks that CreateFromLinks is idempotent per (userID, link),
+// and the FileComplete event is published exactly once for each link.
+func (s *suite) testCreateFromLinks(t *testing.T) {
+	ctx := context.Background()
+	userID := newUserID()
+	links := s.opts.Links
+	defer s.host.Delete(ctx, userID, links)
+
+	first, err := s.host.CreateFromLinks(ctx, userID, links, share.AutoShare, "")
+	require.NoError(t, err)
+	second, err := s.host.CreateFromLinks(ctx, userID, links, share.AutoShare, "")
+	require.NoError(t, err)
+	for _, link := range links {
+		require.Contains(t, first, link)
+		require.Contains(t, second, link)
+		assert.Equal(t, link, second[link].OriginalLink)
+		assert.Equal(t, first[link].CreatedAt.Unix(), second[link].CreatedAt.Unix(), "created again: %s", link)
+	}
+
+	shares := s.waitOK(t, userID, links)
+	s.waitCompleted(t, links)
+
+	again, err := s.host.CreateFromLinks(ctx, userID, links, share.AutoShare, "")
+	require.NoError(t, err)
+	for _, link := range links {
+		assert.Equal(t, share.StatusOK, again[link].State, link)
+		assert.Equal(t, shares[link].HostSharedLink, again[link].HostSharedLink, link)
+		assert.NotEmpty(t, again[link].HostSharedLink, link)
+		assert.Equal(t, 1, s.completedTimes(link), "FileComplete published times of %s", link)
+	}
+}
+
+// testDelete checks that Delete removes what GetStatuses reports.
+func (s *suite) testDelete(t *testing.T) {
+	ctx := context.Background()
+	userID := newUserID()
+	links := s.opts.Links
+
+	shares := s.waitOK(t, userID, links)
+	hostLinks := make([]string, 0, len(links))
+	for _, sh := range shares {
+		hostLinks = append(hostLinks, sh.HostSharedLink)
+	}
+
+	statuses, err := s.host.GetStatuses(ctx, userID, hostLinks)
+	require.NoError(t, err)
+	for _, link := range hostLinks {
+		assert.Equal(t, share.StatusOK, statuses[link], link)
+	}
+
+	require.NoError(t, s.host.Delete(ctx, userID, links))
+
+	statuses, err = s.host.GetStatuses(ctx, userID, hostLinks)
+	require.NoError(t, err)
+	for _, link := range hostLinks {
+		assert.Contains(t, []share.State{share.StatusNotFound, share.StatusDeleted}, statuses[link], link)
+	}
+}
+
+// testConcurrent checks that concurrent calls are safe and create each link once.
+func (s *suite) testConcurrent(t *testing.T) {
+	ctx := context.Background()
+	userID := newUserID()
+	links := s.opts.Links
+	defer s.host.Delete(ctx, userID, links)
+
+	before := make(map[string]int, len(links))
+	for _, link := range links {
+		before[link] = s.completedTimes(link)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, s.opts.Concurrency*2)
+	for i := 0; i < s.opts.Concurrency; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := s.host.CreateFromLinks(ctx, userID, links, share.AutoShare, "")
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := s.host.GetStatuses(ctx, userID, links)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	s.waitOK(t, userID, links)
+
+	// wait a moment for the events of duplicate downloads if any.
+	time.Sleep(200 * time.Millisecond)
+	for _, link := range links {
+		assert.LessOrEqual(t, s.completedTimes(link)-before[link], 1, "created more than once: %s", link)
+	}
+}
+
+// AllowPrivateNetworks allows hosts to download from test servers on loopback addresses until the test ends.
+func AllowPrivateNetworks(t testing.TB) {
+	download.AllowPrivateNetworks = true
+	t.Cleanup(func() { download.AllowPrivateNetworks = false })
+}
+
+// MySQLDependencies returns dependencies connected to the mysql of env KS_DB_MYSQL,
+// and creates the tables of the host. The test is skipped if the env is empty.
+func MySQLDependencies(t *testing.T, p *hosts.Properties) *hosts.Dependencies {
+	dsn := os.Getenv("KS_DB_MYSQL")
+	if dsn == "" {
+		t.Skip("KS_DB_MYSQL is empty")
+	}
+
+	db, err := gorm.Open(mysql.Open(dsn))
+	require.NoError(t, err)
+
+	for _, stmt := range p.CreateTableStatements {
+		require.NoError(t, db.Exec(stmt).Error)
+	}
+	return &hosts.Dependencies{Mysql: db}
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package hoststest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	Run(t, FakeProperties("fake", NewFake()), &Options{
+		Links:   []string{"https://example.com/a.txt", "magnet:?xt=urn:btih:c9e15763f722f23e98a29decdfae341b98d53056"},
+		Timeout: 5 * time.Second,
+	})
+}
+
+func TestFakeStates(t *testing.T) {
+	ctx := context.Background()
+	f := NewFake()
+	f.CompleteAfter = -1
+	f.States["https://example.com/sensitive"] = share.StatusSensitive
+
+	links := []string{"https://example.com/sensitive", "https://example.com/ok"}
+	shares, err := f.CreateFromLinks(ctx, "user", links, share.AutoShare, "")
+	assert.NoError(t, err)
+	assert.Equal(t, share.StatusCreated, shares[links[0]].State)
+
+	var completed []string
+	hosts.Subscribe(f.Events, func(_ context.Context, e hosts.FileCompleteEvent) error {
+		completed = append(completed, e.OriginalLinkHash)
+		return nil
+	})
+	for _, link := range links {
+		f.Complete("user", link)
+	}
+	assert.Len(t, completed, 2)
+
+	shares, err = f.CreateFromLinks(ctx, "user", links, share.AutoShare, "")
+	assert.NoError(t, err)
+	assert.Equal(t, share.StatusSensitive, shares[links[0]].State)
+	assert.Equal(t, share.StatusOK, shares[links[1]].State)
+	assert.Equal(t, 2, f.Created())
+}
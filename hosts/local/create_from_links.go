@@ -0,0 +1,76 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+)
+
+// CreateShare mints a signed download link of a file, the fileID is the auto_id of local_file.
+func (l *Local) CreateShare(ctx context.Context, _ string, _ string, fileID string) (sharedLink string, err error) {
+	id, err := strconv.ParseInt(fileID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid file id: %s", fileID)
+	}
+
+	t := &l.q.File
+	f, err := t.WithContext(ctx).Where(t.AutoID.Eq(id), t.Status.Eq(mirror.StatusOK)).Take()
+	if err != nil {
+		return "", fmt.Errorf("query file err: %w", err)
+	}
+	return l.sharedLink(f.AutoID, f.Name), nil
+}
+
+// CreateFromLinks create shared links based on the input original links.
+// Files are downloaded in background, the state of new links is CREATED until the download is done.
+func (l *Local) CreateFromLinks(ctx context.Context, userID string, originalLinks []string, createBy string, _ string) (sharedLinks map[string]*share.Share, err error) {
+	return l.mirror.CreateFromLinks(ctx, userID, originalLinks, createBy)
+}
+
+// storage saves the downloaded files to local disk.
+type storage struct {
+	*Local
+}
+
+// Save implements mirror.Storage, the content is written to a temporary file first, so that there are no partial files.
+func (s storage) Save(_ context.Context, f *mirror.File, fileName string, r io.Reader, _ int64) (string, string, error) {
+	rel := path.Join(f.KeepshareUserID, f.OriginalLinkHash, fileName)
+	dst := s.abs(rel)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", "", err
+	}
+
+	tmp := dst + ".downloading"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return "", "", err
+	}
+	_, err = io.Copy(out, r)
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", "", fmt.Errorf("save file err: %w", err)
+	}
+	return rel, s.sharedLink(f.AutoID, fileName), nil
+}
+
+// Remove implements mirror.Storage.
+func (s storage) Remove(_ context.Context, rel string) error {
+	return os.RemoveAll(filepath.Dir(s.abs(rel)))
+}
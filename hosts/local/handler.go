@@ -0,0 +1,78 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"crypto/hmac"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+)
+
+// ServeHTTP serves the shared files: GET /files/<id>/<name>?s=<signature>.
+// The server routes requests of `/hosts/local/*` to it with the prefix stripped.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, "/files/")
+	idStr, fileName, _ := strings.Cut(rest, "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if !ok || err != nil || fileName == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !hmac.Equal([]byte(r.URL.Query().Get("s")), []byte(l.sign(id, fileName))) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	ctx := r.Context()
+	t := &l.q.File
+	f, err := t.WithContext(ctx).Where(t.AutoID.Eq(id), t.Status.Eq(mirror.StatusOK)).Take()
+	if err != nil || f.Name != fileName {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(l.abs(f.Path))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// count the first request of a download only.
+	if r.Method == http.MethodGet && r.Header.Get("Range") == "" {
+		go func() {
+			_, err := t.WithContext(context.Background()).Where(t.AutoID.Eq(id)).UpdateSimple(
+				t.Visitor.Add(1),
+				t.LastVisitedAt.Value(time.Now()),
+			)
+			if err != nil {
+				log.WithError(err).WithField("auto_id", id).Error("update visitor err")
+			}
+		}()
+	}
+
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	http.ServeContent(w, r, f.Name, stat.ModTime(), file)
+}
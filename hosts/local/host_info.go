@@ -0,0 +1,178 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/local/model"
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/samber/lo"
+)
+
+// findBySharedLinks returns the files of host shared links, mapped by host shared link.
+func (l *Local) findBySharedLinks(ctx context.Context, userID string, hostSharedLinks []string) (map[string]*model.File, error) {
+	hashToLink := make(map[string]string, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		hashToLink[lk.Hash(link)] = link
+	}
+
+	t := &l.q.File
+	stmt := t.WithContext(ctx).Where(t.SharedLinkHash.In(lo.Keys(hashToLink)...))
+	if userID != "" {
+		stmt = stmt.Where(t.KeepshareUserID.Eq(userID))
+	}
+	files, err := stmt.Find()
+	if err != nil && !gormutil.IsNotFoundError(err) {
+		return nil, fmt.Errorf("query files err: %w", err)
+	}
+
+	m := make(map[string]*model.File, len(files))
+	for _, f := range files {
+		m[hashToLink[f.SharedLinkHash]] = f
+	}
+	return m, nil
+}
+
+// GetStatuses return the statuses of each host shared link.
+// A completed file is DELETED if it has been removed from the disk.
+func (l *Local) GetStatuses(ctx context.Context, userID string, hostSharedLinks []string) (statuses map[string]share.State, err error) {
+	files, err := l.findBySharedLinks(ctx, userID, hostSharedLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses = make(map[string]share.State, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		f := files[link]
+		if f == nil {
+			statuses[link] = share.StatusNotFound
+			continue
+		}
+		st := mirror.State(f.Status)
+		if st == share.StatusOK {
+			if _, err := os.Stat(l.abs(f.Path)); os.IsNotExist(err) {
+				st = share.StatusDeleted
+			}
+		}
+		statuses[link] = st
+	}
+	return statuses, nil
+}
+
+// GetStatistics return the statistics of each host shared link, the visitor is the number of downloads.
+func (l *Local) GetStatistics(ctx context.Context, userID string, hostSharedLinks []string) (details map[string]share.Statistics, err error) {
+	files, err := l.findBySharedLinks(ctx, userID, hostSharedLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	details = make(map[string]share.Statistics, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		if f := files[link]; f != nil {
+			details[link] = share.Statistics{Visitor: f.Visitor}
+		}
+	}
+	return details, nil
+}
+
+// Delete delete shared links by original links.
+func (l *Local) Delete(ctx context.Context, userID string, originalLinks []string) error {
+	if len(originalLinks) == 0 {
+		return nil
+	}
+
+	hashes := make([]string, 0, len(originalLinks))
+	for _, link := range originalLinks {
+		hashes = append(hashes, lk.Hash(link))
+	}
+
+	t := &l.q.File
+	files, err := t.WithContext(ctx).Where(t.KeepshareUserID.Eq(userID), t.OriginalLinkHash.In(hashes...)).Find()
+	if err != nil && !gormutil.IsNotFoundError(err) {
+		return fmt.Errorf("query files err: %w", err)
+	}
+
+	for _, f := range files {
+		if f.Path != "" {
+			if err := os.RemoveAll(filepath.Dir(l.abs(f.Path))); err != nil {
+				return fmt.Errorf("remove file err: %w", err)
+			}
+		}
+		if _, err := t.WithContext(ctx).Where(t.AutoID.Eq(f.AutoID)).Delete(); err != nil {
+			return fmt.Errorf("delete file err: %w", err)
+		}
+	}
+
+	if len(files) > 0 {
+		links := lo.Map(files, func(f *model.File, _ int) string { return f.OriginalLink })
+		l.publish(ctx, hosts.ShareDeletedEvent{Host: name, UserID: userID, OriginalLinks: links})
+	}
+	return nil
+}
+
+// HostInfo returns basic information of the host.
+func (l *Local) HostInfo(ctx context.Context, userID string, _ map[string]any) (resp map[string]any, err error) {
+	var res struct {
+		Files   int64
+		Size    int64
+		Visitor int64
+	}
+	t := &l.q.File
+	err = t.WithContext(ctx).
+		Select(t.AutoID.Count().As("files"), t.Size.Sum().As("size"), t.Visitor.Sum().As("visitor")).
+		Where(t.KeepshareUserID.Eq(userID), t.Status.Eq(mirror.StatusOK)).
+		Scan(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"files":   res.Files,
+		"size":    res.Size,
+		"visitor": res.Visitor,
+	}, nil
+}
+
+// ChangeMasterAccountPassword is not supported, there is no master account.
+func (l *Local) ChangeMasterAccountPassword(context.Context, string, string, bool) (string, error) {
+	return "", hosts.ErrNotSupported
+}
+
+// ConfirmMasterAccountPassword is not supported.
+func (l *Local) ConfirmMasterAccountPassword(context.Context, string, string, bool) error {
+	return hosts.ErrNotSupported
+}
+
+// GetMasterAccountLoginStatus is always valid.
+func (l *Local) GetMasterAccountLoginStatus(context.Context, string) (string, error) {
+	return "valid", nil
+}
+
+// AssignMasterAccount does nothing.
+func (l *Local) AssignMasterAccount(context.Context, string) error {
+	return nil
+}
+
+// DonateRedeemCode is not supported.
+func (l *Local) DonateRedeemCode(context.Context, string, string, []string) error {
+	return hosts.ErrNotSupported
+}
+
+// Capabilities returns what the local host supports.
+func (l *Local) Capabilities() hosts.Capabilities {
+	return hosts.Capabilities{
+		LinkSchemes: []string{"http", "https", "ftp"},
+		MaxFileSize: l.maxFileSize,
+		Statistics:  true,
+	}
+}
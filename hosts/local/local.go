@@ -0,0 +1,160 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/local/model"
+	"github.com/KeepShareOrg/keepshare/hosts/local/query"
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/util"
+	"github.com/spf13/viper"
+)
+
+// name of this host, it is also the path prefix of the share pages: `/hosts/local/`.
+const name = "local"
+
+// Local stores the originals on local disk and serves the files by itself,
+// it needs no third-party service and is suitable for offline deployments.
+type Local struct {
+	*hosts.Dependencies
+
+	q      *query.Query
+	mirror *mirror.Mirror
+
+	dir         string
+	publicURL   string
+	secret      []byte
+	maxFileSize int64
+}
+
+//go:embed  rawsql/*.sql
+var sqlFS embed.FS
+
+func init() {
+	sql, err := hosts.ReadSQLFileFromFS(sqlFS)
+	if err != nil {
+		panic(fmt.Errorf("read sql files err: %w", err))
+	}
+
+	hosts.Register(&hosts.Properties{Name: name, New: New, CreateTableStatements: sql, RehashLinks: mirror.RehashLinks(model.TableNameFile)})
+}
+
+// New create a Local host.
+func New(d *hosts.Dependencies) hosts.Host {
+	viper.SetDefault("local.dir", "./data/local")
+	viper.SetDefault("local.max_concurrent_downloads", 4)
+	viper.SetDefault("local.download_timeout", 6*time.Hour)
+
+	l := &Local{
+		Dependencies: d,
+		q:            query.Use(d.Mysql),
+		publicURL:    strings.TrimRight(util.FirstNotEmpty(viper.GetString("local.public_url"), "https://"+config.RootDomain()), "/"),
+		maxFileSize:  viper.GetInt64("local.max_file_size"),
+	}
+	l.mirror = mirror.New(mirror.Options{
+		Host:                   name,
+		Table:                  model.TableNameFile,
+		DB:                     d.Mysql,
+		Events:                 d.Events,
+		Storage:                storage{l},
+		MaxFileSize:            l.maxFileSize,
+		DownloadTimeout:        viper.GetDuration("local.download_timeout"),
+		MaxConcurrentDownloads: viper.GetInt("local.max_concurrent_downloads"),
+	})
+
+	dir, err := filepath.Abs(viper.GetString("local.dir"))
+	if err != nil {
+		log.WithError(err).Error("get absolute path of local.dir err")
+		dir = viper.GetString("local.dir")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.WithError(err).Errorf("create local.dir %s err", dir)
+	}
+	l.dir = dir
+
+	if secret := viper.GetString("local.secret"); secret != "" {
+		l.secret = []byte(secret)
+	} else if l.secret, err = loadSecret(dir); err != nil {
+		// the links signed by the secret are invalid after restarting.
+		log.WithError(err).Error("load the secret of local.dir err, set local.secret in the config")
+		l.secret = randomSecret()
+	}
+
+	return l
+}
+
+// secretFile is the file of the secret generated if local.secret is not configured, it is not served by the host.
+const secretFile = ".secret"
+
+// loadSecret returns the secret saved in the dir, a random one is generated and saved if it does not exist.
+func loadSecret(dir string) ([]byte, error) {
+	p := filepath.Join(dir, secretFile)
+	if b, err := os.ReadFile(p); err == nil && len(b) > 0 {
+		return b, nil
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// the file is created exclusively, so that concurrent instances sharing the dir use the same secret.
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return os.ReadFile(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	secret := randomSecret()
+	if _, err := f.Write(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func randomSecret() []byte {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return []byte(hex.EncodeToString(b))
+}
+
+// abs returns the absolute path of a relative path stored in the table.
+func (l *Local) abs(rel string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(rel))
+}
+
+func (l *Local) sign(id int64, fileName string) string {
+	h := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(h, "%d/%s", id, fileName)
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// sharedLink returns the signed download link of a file.
+func (l *Local) sharedLink(id int64, fileName string) string {
+	u := &url.URL{Path: fmt.Sprintf("/hosts/%s/files/%d/%s", name, id, fileName)}
+	return fmt.Sprintf("%s%s?s=%s", l.publicURL, u.EscapedPath(), l.sign(id, fileName))
+}
+
+// publish publishes the event of the host, errors are logged only.
+func (l *Local) publish(ctx context.Context, e hosts.Event) {
+	if err := l.Events.Publish(ctx, e); err != nil {
+		log.WithContext(ctx).WithField("event", e).Errorf("publish event err: %v", err)
+	}
+}
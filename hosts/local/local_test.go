@@ -0,0 +1,92 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransfer(t *testing.T) {
+	hoststest.AllowPrivateNetworks(t)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="a b.txt"`)
+		_, _ = w.Write([]byte("some content"))
+	}))
+	defer origin.Close()
+
+	l := &Local{dir: t.TempDir(), publicURL: "https://example.com", secret: []byte("secret")}
+	f := &mirror.File{AutoID: 1, KeepshareUserID: "user", OriginalLinkHash: "hash", OriginalLink: origin.URL + "/download"}
+
+	update, err := mirror.New(mirror.Options{Storage: storage{l}}).Transfer(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Equal(t, "user/hash/a b.txt", update.Path)
+	assert.Equal(t, int64(len("some content")), update.Size)
+
+	b, err := os.ReadFile(l.abs(update.Path))
+	assert.NoError(t, err)
+	assert.Equal(t, "some content", string(b))
+
+	u, err := url.Parse(update.SharedLink)
+	assert.NoError(t, err)
+	assert.Equal(t, "/hosts/local/files/1/a b.txt", u.Path)
+	assert.Equal(t, l.sign(1, "a b.txt"), u.Query().Get("s"))
+}
+
+func TestServeHTTPInvalidSignature(t *testing.T) {
+	l := &Local{secret: []byte("secret")}
+
+	for path, code := range map[string]int{
+		"/files/1/a.txt?s=invalid": http.StatusForbidden,
+		"/files/x/a.txt":           http.StatusNotFound,
+		"/other":                   http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		l.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, code, w.Code, path)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	hoststest.AllowPrivateNetworks(t)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer origin.Close()
+
+	sql, err := hosts.ReadSQLFileFromFS(sqlFS)
+	assert.NoError(t, err)
+	p := &hosts.Properties{Name: name, New: New, CreateTableStatements: sql}
+
+	viper.Set("local.dir", t.TempDir())
+	hoststest.Run(t, p, &hoststest.Options{
+		Dependencies: hoststest.MySQLDependencies(t, p),
+		Links:        []string{origin.URL + "/a.txt", origin.URL + "/b.txt"},
+	})
+}
+
+func TestLoadSecret(t *testing.T) {
+	dir := t.TempDir()
+	secret, err := loadSecret(dir)
+	assert.NoError(t, err)
+	assert.Len(t, secret, 64)
+	assert.NotEqual(t, secret, randomSecret())
+
+	again, err := loadSecret(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, secret, again, "the saved secret is reused")
+}
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS `local_file`
+(
+	`auto_id`            bigint       NOT NULL AUTO_INCREMENT,
+	`keepshare_user_id`  varchar(16)  NOT NULL,
+	`original_link_hash` char(40)     NOT NULL,
+	`original_link`      text         NOT NULL,
+	`status`             varchar(32)  NOT NULL,
+	`path`               varchar(512) NOT NULL DEFAULT '' COMMENT "relative to the local.dir",
+	`name`               varchar(256) NOT NULL DEFAULT '',
+	`size`               bigint       NOT NULL DEFAULT 0,
+	`visitor`            int          NOT NULL DEFAULT 0,
+	`shared_link_hash`   char(40)     NOT NULL DEFAULT '',
+	`shared_link`        text         NOT NULL,
+	`created_at`         datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated_at`         datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`last_visited_at`    datetime     NOT NULL DEFAULT '2000-01-01 00:00:00',
+	`error`              text,
+	PRIMARY KEY (`auto_id`),
+	UNIQUE KEY (`keepshare_user_id`, `original_link_hash`),
+	KEY (`shared_link_hash`),
+	KEY (`status`, `updated_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
@@ -0,0 +1,171 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package hosts
+
+import (
+	"context"
+
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+)
+
+// Middleware wraps a host to add cross-cutting behaviors such as caching, retries, metrics and logging.
+// The returned host should implement `Unwrap() Host` to return next,
+// so that the optional interfaces of the wrapped host such as http.Handler can be found by As.
+type Middleware func(name string, next Host) Host
+
+var middlewares []Middleware
+
+// Use appends middlewares to wrap all hosts, it must be called before Start.
+// The first middleware is the outermost one, and it wraps the middlewares of Properties.
+func Use(m ...Middleware) {
+	middlewares = append(middlewares, m...)
+}
+
+// wrap the host with middlewares, the first middleware is the outermost one.
+func wrap(name string, h Host, m []Middleware) Host {
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](name, h)
+	}
+	return h
+}
+
+// Unwrap returns the host wrapped by a middleware, or nil if the host is not wrapped.
+func Unwrap(h Host) Host {
+	u, ok := h.(interface{ Unwrap() Host })
+	if !ok {
+		return nil
+	}
+	return u.Unwrap()
+}
+
+// As finds the first host in the chain of middlewares that implements T, e.g. http.Handler.
+func As[T any](h Host) (T, bool) {
+	for h != nil {
+		if t, ok := h.(T); ok {
+			return t, true
+		}
+		h = Unwrap(h)
+	}
+	var zero T
+	return zero, false
+}
+
+// Call is a method call of a host.
+type Call struct {
+	Host   string
+	Method string
+}
+
+// Invoker invokes the method of the next host, the results are returned by the intercepted method.
+type Invoker func(ctx context.Context) error
+
+// Interceptor intercepts all methods of a host except Capabilities.
+// It must call invoke to call the next host, it may call invoke more than once to retry.
+type Interceptor func(ctx context.Context, call *Call, invoke Invoker) error
+
+// Intercept returns a middleware that passes all method calls through the interceptor.
+func Intercept(i Interceptor) Middleware {
+	return func(name string, next Host) Host {
+		return &intercepted{name: name, next: next, intercept: i}
+	}
+}
+
+type intercepted struct {
+	name      string
+	next      Host
+	intercept Interceptor
+}
+
+func (h *intercepted) Unwrap() Host {
+	return h.next
+}
+
+func (h *intercepted) call(ctx context.Context, method string, invoke Invoker) error {
+	return h.intercept(ctx, &Call{Host: h.name, Method: method}, invoke)
+}
+
+func (h *intercepted) CreateShare(ctx context.Context, master string, worker string, fileID string) (sharedLink string, err error) {
+	err = h.call(ctx, "CreateShare", func(ctx context.Context) (err error) {
+		sharedLink, err = h.next.CreateShare(ctx, master, worker, fileID)
+		return err
+	})
+	return sharedLink, err
+}
+
+func (h *intercepted) CreateFromLinks(ctx context.Context, userID string, originalLinks []string, createBy string, ip string) (sharedLinks map[string]*share.Share, err error) {
+	err = h.call(ctx, "CreateFromLinks", func(ctx context.Context) (err error) {
+		sharedLinks, err = h.next.CreateFromLinks(ctx, userID, originalLinks, createBy, ip)
+		return err
+	})
+	return sharedLinks, err
+}
+
+func (h *intercepted) GetStatuses(ctx context.Context, userID string, hostSharedLinks []string) (statuses map[string]share.State, err error) {
+	err = h.call(ctx, "GetStatuses", func(ctx context.Context) (err error) {
+		statuses, err = h.next.GetStatuses(ctx, userID, hostSharedLinks)
+		return err
+	})
+	return statuses, err
+}
+
+func (h *intercepted) GetStatistics(ctx context.Context, userID string, hostSharedLinks []string) (details map[string]share.Statistics, err error) {
+	err = h.call(ctx, "GetStatistics", func(ctx context.Context) (err error) {
+		details, err = h.next.GetStatistics(ctx, userID, hostSharedLinks)
+		return err
+	})
+	return details, err
+}
+
+func (h *intercepted) Delete(ctx context.Context, userID string, originalLinks []string) error {
+	return h.call(ctx, "Delete", func(ctx context.Context) error {
+		return h.next.Delete(ctx, userID, originalLinks)
+	})
+}
+
+func (h *intercepted) HostInfo(ctx context.Context, userID string, options map[string]any) (resp map[string]any, err error) {
+	err = h.call(ctx, "HostInfo", func(ctx context.Context) (err error) {
+		resp, err = h.next.HostInfo(ctx, userID, options)
+		return err
+	})
+	return resp, err
+}
+
+func (h *intercepted) ChangeMasterAccountPassword(ctx context.Context, userID, newPassword string, savePassword bool) (password string, err error) {
+	err = h.call(ctx, "ChangeMasterAccountPassword", func(ctx context.Context) (err error) {
+		password, err = h.next.ChangeMasterAccountPassword(ctx, userID, newPassword, savePassword)
+		return err
+	})
+	return password, err
+}
+
+func (h *intercepted) ConfirmMasterAccountPassword(ctx context.Context, keepShareUserID, password string, savePassword bool) error {
+	return h.call(ctx, "ConfirmMasterAccountPassword", func(ctx context.Context) error {
+		return h.next.ConfirmMasterAccountPassword(ctx, keepShareUserID, password, savePassword)
+	})
+}
+
+func (h *intercepted) GetMasterAccountLoginStatus(ctx context.Context, keepShareUserID string) (status string, err error) {
+	err = h.call(ctx, "GetMasterAccountLoginStatus", func(ctx context.Context) (err error) {
+		status, err = h.next.GetMasterAccountLoginStatus(ctx, keepShareUserID)
+		return err
+	})
+	return status, err
+}
+
+func (h *intercepted) AssignMasterAccount(ctx context.Context, keepShareUserID string) error {
+	return h.call(ctx, "AssignMasterAccount", func(ctx context.Context) error {
+		return h.next.AssignMasterAccount(ctx, keepShareUserID)
+	})
+}
+
+func (h *intercepted) DonateRedeemCode(ctx context.Context, nickname, userID string, redeemCodes []string) error {
+	return h.call(ctx, "DonateRedeemCode", func(ctx context.Context) error {
+		return h.next.DonateRedeemCode(ctx, nickname, userID, redeemCodes)
+	})
+}
+
+func (h *intercepted) Capabilities() Capabilities {
+	return h.next.Capabilities()
+}
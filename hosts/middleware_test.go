@@ -0,0 +1,138 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package hosts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubHost implements GetStatuses and Delete only, other methods panic.
+type stubHost struct {
+	Host
+	statuses func(links []string) (map[string]share.State, error)
+	deletes  int
+}
+
+func (h *stubHost) GetStatuses(_ context.Context, _ string, links []string) (map[string]share.State, error) {
+	return h.statuses(links)
+}
+
+func (h *stubHost) Delete(context.Context, string, []string) error {
+	h.deletes++
+	return ErrTransient
+}
+
+func (h *stubHost) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+func TestMiddlewareChain(t *testing.T) {
+	var calls []string
+	trace := func(tag string) Middleware {
+		return Intercept(func(ctx context.Context, call *Call, invoke Invoker) error {
+			calls = append(calls, tag+":"+call.Host+"."+call.Method)
+			return invoke(ctx)
+		})
+	}
+
+	stub := &stubHost{statuses: func(links []string) (map[string]share.State, error) {
+		return map[string]share.State{links[0]: share.StatusOK}, nil
+	}}
+	h := wrap("stub", stub, []Middleware{trace("outer"), trace("inner")})
+
+	sts, err := h.GetStatuses(context.Background(), "user", []string{"link"})
+	require.NoError(t, err)
+	assert.Equal(t, share.StatusOK, sts["link"])
+	assert.Equal(t, []string{"outer:stub.GetStatuses", "inner:stub.GetStatuses"}, calls)
+
+	handler, ok := As[http.Handler](h)
+	assert.True(t, ok)
+	assert.Same(t, stub, handler)
+	assert.Same(t, stub, Unwrap(Unwrap(h)))
+	assert.Nil(t, Unwrap(stub))
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	var n int
+	errs := []error{fmt.Errorf("get: %w", ErrTransient), context.DeadlineExceeded, errors.New("invalid")}
+	stub := &stubHost{statuses: func([]string) (map[string]share.State, error) {
+		n++
+		if n <= len(errs) {
+			return nil, errs[n-1]
+		}
+		return map[string]share.State{}, nil
+	}}
+	h := Retry(3, time.Millisecond)("stub", stub)
+
+	_, err := h.GetStatuses(ctx, "user", []string{"link"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 2, n, "errors which are not transient are not retried")
+
+	n = 0
+	errs = []error{ErrTransient, ErrTransient, ErrTransient}
+	_, err = h.GetStatuses(ctx, "user", []string{"link"})
+	assert.ErrorIs(t, err, ErrTransient)
+	assert.Equal(t, 3, n)
+
+	assert.ErrorIs(t, h.Delete(ctx, "user", nil), ErrTransient)
+	assert.Equal(t, 1, stub.deletes, "methods with side effects are not retried")
+}
+
+func TestMetrics(t *testing.T) {
+	fail := true
+	stub := &stubHost{statuses: func([]string) (map[string]share.State, error) {
+		if fail {
+			return nil, errors.New("failed")
+		}
+		return map[string]share.State{}, nil
+	}}
+	h := Metrics()("metrics_stub", stub)
+
+	_, _ = h.GetStatuses(context.Background(), "user", nil)
+	fail = false
+	_, _ = h.GetStatuses(context.Background(), "user", nil)
+
+	s := Stats()["metrics_stub.GetStatuses"]
+	assert.Equal(t, int64(2), s.Calls)
+	assert.Equal(t, int64(1), s.Errors)
+	assert.GreaterOrEqual(t, s.TotalLatency, s.MaxLatency)
+}
+
+func TestStatusCache(t *testing.T) {
+	if os.Getenv("KS_DB_REDIS") == "" {
+		t.Skip("KS_DB_REDIS is empty")
+	}
+	opt, err := redis.ParseURL(os.Getenv("KS_DB_REDIS"))
+	require.NoError(t, err)
+	rdb := redis.NewClient(opt)
+
+	ctx := context.Background()
+	prefix := fmt.Sprintf("https://example.com/%d/", time.Now().UnixNano())
+	ok, pending := prefix+"ok", prefix+"pending"
+
+	var queried []string
+	stub := &stubHost{statuses: func(links []string) (map[string]share.State, error) {
+		queried = append(queried, links...)
+		return map[string]share.State{ok: share.StatusOK, pending: share.StatusCreated}, nil
+	}}
+	h := StatusCache(rdb, time.Minute)("stub", stub)
+
+	for i := 0; i < 2; i++ {
+		sts, err := h.GetStatuses(ctx, "user", []string{ok, pending})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]share.State{ok: share.StatusOK, pending: share.StatusCreated}, sts)
+	}
+	assert.Equal(t, []string{ok, pending, pending}, queried, "only OK statuses are cached")
+}
@@ -0,0 +1,223 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package hosts
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/redis/go-redis/v9"
+)
+
+// Keys of the log fields of host calls.
+const (
+	LogFieldHost  = "host"
+	LogFieldError = "error"
+)
+
+// Logging returns a middleware that logs method calls of hosts, failed calls are logged as warnings.
+func Logging() Middleware {
+	return Intercept(func(ctx context.Context, call *Call, invoke Invoker) error {
+		start := time.Now()
+		err := invoke(ctx)
+		l := log.WithContext(ctx).WithFields(log.Fields{
+			LogFieldHost: call.Host,
+			"method":     call.Method,
+			"duration":   time.Since(start).String(),
+		})
+		if err != nil {
+			l.WithField(LogFieldError, err).Warn("host call failed")
+		} else {
+			l.Debug("host call done")
+		}
+		return err
+	})
+}
+
+// MethodStats are the metrics of a host method.
+type MethodStats struct {
+	Calls        int64         `json:"calls"`
+	Errors       int64         `json:"errors"`
+	TotalLatency time.Duration `json:"total_latency"`
+	MaxLatency   time.Duration `json:"max_latency"`
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[Call]*MethodStats{}
+)
+
+func init() {
+	expvar.Publish("hosts", expvar.Func(func() any { return Stats() }))
+}
+
+// Stats returns the metrics recorded by the Metrics middleware, the key is `<host>.<method>`.
+// They are also published as expvar `hosts`, which is served by GET /api/admin/metrics.
+func Stats() map[string]MethodStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	m := make(map[string]MethodStats, len(stats))
+	for k, v := range stats {
+		m[k.Host+"."+k.Method] = *v
+	}
+	return m
+}
+
+// Metrics returns a middleware that records the calls, errors and latency of each host method.
+func Metrics() Middleware {
+	return Intercept(func(ctx context.Context, call *Call, invoke Invoker) error {
+		start := time.Now()
+		err := invoke(ctx)
+		latency := time.Since(start)
+
+		statsMu.Lock()
+		defer statsMu.Unlock()
+		s := stats[*call]
+		if s == nil {
+			s = &MethodStats{}
+			stats[*call] = s
+		}
+		s.Calls++
+		if err != nil {
+			s.Errors++
+		}
+		s.TotalLatency += latency
+		if latency > s.MaxLatency {
+			s.MaxLatency = latency
+		}
+		return err
+	})
+}
+
+// ErrTransient can be wrapped by hosts to report errors which may be resolved by retrying.
+var ErrTransient = errors.New("transient error")
+
+// IsTransient returns whether the error may be resolved by retrying, such as network timeouts.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrTransient) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// idempotentMethods are the host methods without side effects, they are safe to retry.
+var idempotentMethods = map[string]bool{
+	"GetStatuses":                 true,
+	"GetStatistics":               true,
+	"HostInfo":                    true,
+	"GetMasterAccountLoginStatus": true,
+}
+
+// Retry returns a middleware that retries idempotent methods on transient errors.
+// A method is called at most attempts times, the backoff doubles after each retry.
+func Retry(attempts int, backoff time.Duration) Middleware {
+	return Intercept(func(ctx context.Context, call *Call, invoke Invoker) error {
+		if !idempotentMethods[call.Method] {
+			return invoke(ctx)
+		}
+
+		var err error
+		for i, wait := 0, backoff; i < attempts; i, wait = i+1, wait*2 {
+			if i > 0 {
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(wait):
+				}
+			}
+			if err = invoke(ctx); !IsTransient(err) {
+				return err
+			}
+		}
+		return err
+	})
+}
+
+// StatusCache returns a middleware that caches the results of GetStatuses in redis for ttl.
+// Only the states to cache are cached, default to OK, since other states are likely to change.
+func StatusCache(rdb *redis.Client, ttl time.Duration, states ...share.State) Middleware {
+	if len(states) == 0 {
+		states = []share.State{share.StatusOK}
+	}
+	return func(name string, next Host) Host {
+		return &statusCache{Host: next, name: name, rdb: rdb, ttl: ttl, states: states}
+	}
+}
+
+type statusCache struct {
+	Host
+	name   string
+	rdb    *redis.Client
+	ttl    time.Duration
+	states []share.State
+}
+
+func (h *statusCache) Unwrap() Host {
+	return h.Host
+}
+
+func (h *statusCache) key(link string) string {
+	return fmt.Sprintf("host_status:%s:%s", h.name, link)
+}
+
+// GetStatuses returns the cached statuses and gets the others from the next host, redis errors are ignored.
+func (h *statusCache) GetStatuses(ctx context.Context, userID string, hostSharedLinks []string) (map[string]share.State, error) {
+	if len(hostSharedLinks) == 0 {
+		return h.Host.GetStatuses(ctx, userID, hostSharedLinks)
+	}
+
+	keys := make([]string, len(hostSharedLinks))
+	for i, link := range hostSharedLinks {
+		keys[i] = h.key(link)
+	}
+	cached, _ := h.rdb.MGet(ctx, keys...).Result()
+
+	statuses := make(map[string]share.State, len(hostSharedLinks))
+	var missed []string
+	for i, link := range hostSharedLinks {
+		if i < len(cached) {
+			if s, ok := cached[i].(string); ok && s != "" {
+				statuses[link] = share.State(s)
+				continue
+			}
+		}
+		missed = append(missed, link)
+	}
+	if len(missed) == 0 {
+		return statuses, nil
+	}
+
+	got, err := h.Host.GetStatuses(ctx, userID, missed)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := h.rdb.Pipeline()
+	for link, state := range got {
+		statuses[link] = state
+		if slices.Contains(h.states, state) {
+			pipe.Set(ctx, h.key(link), state.String(), h.ttl)
+		}
+	}
+	_, _ = pipe.Exec(ctx)
+	return statuses, nil
+}
@@ -0,0 +1,330 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package mirror downloads the originals in background and records the files in a table of the host,
+// it is shared by the hosts which keep their own copies of the originals, such as local and webdav.
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/download"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// enum all file statuses.
+const (
+	StatusRunning = "RUNNING"
+	StatusOK      = "OK"
+	StatusError   = "ERROR"
+)
+
+// recordTimeout is the timeout to record the result of a download and publish the events.
+const recordTimeout = time.Minute
+
+// File has the common columns of the file tables of hosts.
+type File struct {
+	AutoID           int64     `gorm:"column:auto_id;primaryKey;autoIncrement:true"`
+	KeepshareUserID  string    `gorm:"column:keepshare_user_id"`
+	OriginalLinkHash string    `gorm:"column:original_link_hash"`
+	OriginalLink     string    `gorm:"column:original_link"`
+	Status           string    `gorm:"column:status"`
+	Path             string    `gorm:"column:path"`
+	Name             string    `gorm:"column:name"`
+	Size             int64     `gorm:"column:size"`
+	SharedLinkHash   string    `gorm:"column:shared_link_hash"`
+	SharedLink       string    `gorm:"column:shared_link"`
+	CreatedAt        time.Time `gorm:"column:created_at"`
+	UpdatedAt        time.Time `gorm:"column:updated_at"`
+	Error            string    `gorm:"column:error"`
+	// Visitor is read only, it is zero if the table does not count visits.
+	Visitor int32 `gorm:"column:visitor;->"`
+}
+
+// Storage keeps the downloaded files, it is implemented by hosts.
+type Storage interface {
+	// Save saves the content named name of the file, size is -1 if it is unknown.
+	// It returns the path of the saved file and the shared link of it.
+	Save(ctx context.Context, f *File, name string, r io.Reader, size int64) (path string, sharedLink string, err error)
+	// Remove removes the file saved at the path.
+	Remove(ctx context.Context, path string) error
+}
+
+// Options of Mirror.
+type Options struct {
+	// Host is the name of the host in events.
+	Host string
+	// Table is the name of the file table of the host.
+	Table   string
+	DB      *gorm.DB
+	Events  *hosts.EventBus
+	Storage Storage
+
+	MaxFileSize            int64 // no limit if it is not greater than 0.
+	DownloadTimeout        time.Duration
+	MaxConcurrentDownloads int
+}
+
+// Mirror creates shared links of the originals by downloading them to the storage.
+type Mirror struct {
+	opts        Options
+	downloading chan struct{}
+}
+
+// New returns a Mirror of the options.
+func New(opts Options) *Mirror {
+	n := opts.MaxConcurrentDownloads
+	if n <= 0 {
+		n = 1
+	}
+	return &Mirror{opts: opts, downloading: make(chan struct{}, n)}
+}
+
+func (m *Mirror) table(ctx context.Context) *gorm.DB {
+	return m.opts.DB.WithContext(ctx).Table(m.opts.Table)
+}
+
+// State returns the state of shared links of the file status.
+func State(status string) share.State {
+	switch status {
+	case StatusOK:
+		return share.StatusOK
+	case StatusError:
+		return share.StatusError
+	default:
+		return share.StatusCreated
+	}
+}
+
+func toShare(f *File, createBy string) *share.Share {
+	sh := &share.Share{
+		State:        State(f.Status),
+		Title:        f.Name,
+		OriginalLink: f.OriginalLink,
+		CreatedBy:    createBy,
+		CreatedAt:    f.CreatedAt,
+		Size:         f.Size,
+		Statistics:   share.Statistics{Visitor: f.Visitor},
+	}
+	switch sh.State {
+	case share.StatusOK:
+		sh.HostSharedLink = f.SharedLink
+	case share.StatusError:
+		sh.Error = f.Error
+	}
+	return sh
+}
+
+// CreateFromLinks create shared links based on the input original links.
+// Files are downloaded in background, the state of new links is CREATED until the download is done.
+func (m *Mirror) CreateFromLinks(ctx context.Context, userID string, originalLinks []string, createBy string) (sharedLinks map[string]*share.Share, err error) {
+	hashes := make([]string, 0, len(originalLinks))
+	for _, link := range originalLinks {
+		hashes = append(hashes, lk.Hash(link))
+	}
+
+	var files []*File
+	err = m.table(ctx).Where("keepshare_user_id = ? AND original_link_hash IN ?", userID, hashes).Find(&files).Error
+	if err != nil && !gormutil.IsNotFoundError(err) {
+		return nil, fmt.Errorf("query files err: %w", err)
+	}
+	exists := make(map[string]*File, len(files))
+	for _, f := range files {
+		exists[f.OriginalLinkHash] = f
+	}
+
+	sharedLinks = make(map[string]*share.Share, len(originalLinks))
+	for i, link := range originalLinks {
+		f := exists[hashes[i]]
+
+		if f != nil && f.Status == StatusRunning && time.Since(f.UpdatedAt) > m.opts.DownloadTimeout {
+			f.Status, f.Error = StatusError, "download interrupted"
+		}
+		if f != nil && f.Status == StatusError {
+			// delete error files so that they can be created again next time.
+			_ = m.table(ctx).Where("auto_id = ?", f.AutoID).Delete(&File{}).Error
+			sharedLinks[link] = toShare(f, createBy)
+			continue
+		}
+
+		if f == nil {
+			if !download.Supported(link) {
+				sharedLinks[link] = &share.Share{
+					State:        share.StatusError,
+					OriginalLink: link,
+					CreatedBy:    createBy,
+					CreatedAt:    time.Now(),
+					Error:        download.ErrUnsupportedScheme.Error(),
+				}
+				continue
+			}
+
+			if f, err = m.createFile(ctx, userID, link); err != nil {
+				return nil, fmt.Errorf("create file err: %w, link: %s", err, link)
+			}
+		}
+
+		sharedLinks[link] = toShare(f, createBy)
+	}
+
+	return sharedLinks, nil
+}
+
+// createFile inserts a running file record and starts downloading in background,
+// if the record already exists, the existing one is returned and no new download is started.
+func (m *Mirror) createFile(ctx context.Context, userID string, link string) (*File, error) {
+	now := time.Now()
+	f := &File{
+		KeepshareUserID:  userID,
+		OriginalLinkHash: lk.Hash(link),
+		OriginalLink:     link,
+		Status:           StatusRunning,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	if err := m.table(ctx).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(f).Error; err != nil {
+		return nil, err
+	}
+	if f.AutoID == 0 { // created by others concurrently.
+		var exist File
+		err := m.table(ctx).Where("keepshare_user_id = ? AND original_link_hash = ?", userID, f.OriginalLinkHash).Take(&exist).Error
+		return &exist, err
+	}
+
+	go m.download(f)
+	return f, nil
+}
+
+func (m *Mirror) download(f *File) {
+	m.downloading <- struct{}{}
+	defer func() { <-m.downloading }()
+
+	l := log.WithFields(log.Fields{"host": m.opts.Host, "user_id": f.KeepshareUserID, "link": f.OriginalLink})
+
+	transferCtx, cancelTransfer := context.WithTimeout(context.Background(), m.opts.DownloadTimeout)
+	update, err := m.Transfer(transferCtx, f)
+	cancelTransfer()
+
+	// the context of the transfer may be expired, the results are recorded with a new one.
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	if err != nil {
+		l.WithError(err).Error("download err")
+		e := m.table(ctx).Where("auto_id = ?", f.AutoID).Updates(&File{
+			Status:    StatusError,
+			Error:     err.Error(),
+			UpdatedAt: time.Now(),
+		}).Error
+		if e != nil {
+			l.WithError(e).Error("update file err")
+		}
+		m.publish(ctx, hosts.FileErrorEvent{
+			Host:             m.opts.Host,
+			UserID:           f.KeepshareUserID,
+			OriginalLinkHash: f.OriginalLinkHash,
+			Error:            err.Error(),
+		})
+		return
+	}
+
+	ret := m.table(ctx).Where("auto_id = ?", f.AutoID).Updates(update)
+	if ret.Error != nil {
+		l.WithError(ret.Error).Error("update file err")
+		return
+	}
+	if ret.RowsAffected == 0 {
+		// deleted while downloading.
+		_ = m.opts.Storage.Remove(ctx, update.Path)
+		return
+	}
+
+	l.WithField("path", update.Path).Info("download done")
+	m.publish(ctx, hosts.FileCompleteEvent{Host: m.opts.Host, UserID: f.KeepshareUserID, OriginalLinkHash: f.OriginalLinkHash})
+	m.publish(ctx, hosts.ShareCreatedEvent{
+		Host:           m.opts.Host,
+		UserID:         f.KeepshareUserID,
+		OriginalLink:   f.OriginalLink,
+		HostSharedLink: update.SharedLink,
+	})
+}
+
+// Transfer downloads the original of the file to the storage and returns the fields to update.
+func (m *Mirror) Transfer(ctx context.Context, f *File) (*File, error) {
+	src, err := download.Open(ctx, f.OriginalLink)
+	if err != nil {
+		return nil, fmt.Errorf("open original link err: %w", err)
+	}
+	defer src.Close()
+
+	limit := m.opts.MaxFileSize
+	if limit > 0 && src.Size > limit {
+		return nil, fmt.Errorf("file size %d exceeds the limit %d", src.Size, limit)
+	}
+
+	name := download.SafeName(src.Name, f.OriginalLinkHash)
+	r := &download.CountingReader{R: src, Limit: limit}
+	p, sharedLink, err := m.opts.Storage.Save(ctx, f, name, r, src.Size)
+	if r.Err != nil {
+		err = r.Err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Status:         StatusOK,
+		Path:           p,
+		Name:           name,
+		Size:           r.N,
+		SharedLinkHash: lk.Hash(sharedLink),
+		SharedLink:     sharedLink,
+		UpdatedAt:      time.Now(),
+	}, nil
+}
+
+// RehashLinks returns the hosts.Properties.RehashLinks which updates the hashes of files of the table.
+// Files whose new hash already exists are duplicated, they are kept with the old hash.
+func RehashLinks(table string) func(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes map[string]string) (int64, error) {
+	return func(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes map[string]string) (rows int64, err error) {
+		for oldHash, newHash := range hashes {
+			ret := db.WithContext(ctx).Table(table).
+				Where("keepshare_user_id = ? AND original_link_hash = ?", keepshareUserID, oldHash).
+				Update("original_link_hash", newHash)
+			if gormutil.IsDuplicateError(ret.Error) {
+				log.WithContext(ctx).WithFields(log.Fields{
+					"table":             table,
+					"keepshare_user_id": keepshareUserID,
+					"old_hash":          oldHash,
+					"new_hash":          newHash,
+				}).Warn("file of the new hash exists, skip it")
+				continue
+			}
+			if ret.Error != nil {
+				return rows, fmt.Errorf("update file hash err: %w", ret.Error)
+			}
+			rows += ret.RowsAffected
+		}
+		return rows, nil
+	}
+}
+
+// publish publishes the event of the host, errors are logged only.
+func (m *Mirror) publish(ctx context.Context, e hosts.Event) {
+	if m.opts.Events == nil {
+		return
+	}
+	if err := m.opts.Events.Publish(ctx, e); err != nil {
+		log.WithContext(ctx).WithField("event", e).Errorf("publish event err: %v", err)
+	}
+}
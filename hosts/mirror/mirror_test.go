@@ -0,0 +1,68 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package mirror
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+type nopStorage struct{}
+
+func (nopStorage) Save(context.Context, *File, string, io.Reader, int64) (string, string, error) {
+	return "", "", nil
+}
+
+func (nopStorage) Remove(context.Context, string) error { return nil }
+
+func TestDownloadTimeoutRecorded(t *testing.T) {
+	hoststest.AllowPrivateNetworks(t)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer origin.Close()
+
+	cfg := &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true}
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), cfg)
+	require.NoError(t, err)
+	var sql string
+	var ctxErr error
+	err = db.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+		sql, ctxErr = tx.Statement.SQL.String(), tx.Statement.Context.Err()
+	})
+	require.NoError(t, err)
+
+	m := New(Options{Host: "test", Table: "test_file", DB: db, Storage: nopStorage{}, DownloadTimeout: 50 * time.Millisecond})
+	m.download(&File{AutoID: 1, OriginalLink: origin.URL + "/a.txt"})
+	assert.Contains(t, sql, "`status`=?", "the error is recorded")
+	assert.NoError(t, ctxErr, "the error is recorded by a context which is not expired")
+}
+
+func TestRehashLinks(t *testing.T) {
+	cfg := &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true}
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), cfg)
+	require.NoError(t, err)
+	var sql string
+	var vars []any
+	err = db.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+	require.NoError(t, err)
+
+	_, err = RehashLinks("test_file")(context.Background(), db, "u1", map[string]string{"old": "new"})
+	require.NoError(t, err)
+	assert.Contains(t, sql, "UPDATE `test_file` SET `original_link_hash`=? WHERE keepshare_user_id = ? AND original_link_hash = ?")
+	assert.Equal(t, []any{"new", "u1", "old"}, vars)
+}
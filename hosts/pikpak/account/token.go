@@ -3,7 +3,9 @@ package account
 import (
 	"context"
 	"encoding/json"
+	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/api"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
 	"github.com/KeepShareOrg/keepshare/pkg/log"
 	"github.com/KeepShareOrg/keepshare/server/constant"
 	"github.com/hibiken/asynq"
@@ -81,6 +83,7 @@ func (m *Manager) handleRefreshToken(ctx context.Context, task *asynq.Task) erro
 		if api.IsInvalidGrantErr(err) {
 			//if refresh token failed, delete the token
 			m.q.Token.WithContext(ctx).Where(m.q.Token.UserID.Eq(t.UserID)).Delete()
+			m.publishMasterPasswordInvalid(ctx, t.UserID)
 			return nil
 		}
 		return err
@@ -89,6 +92,20 @@ func (m *Manager) handleRefreshToken(ctx context.Context, task *asynq.Task) erro
 	return nil
 }
 
+// publishMasterPasswordInvalid publishes the event if the user is a master account.
+func (m *Manager) publishMasterPasswordInvalid(ctx context.Context, master string) {
+	ma := m.q.MasterAccount
+	account, err := ma.WithContext(ctx).Where(ma.UserID.Eq(master)).Take()
+	if err != nil {
+		return
+	}
+
+	e := hosts.MasterPasswordInvalidEvent{Host: comm.HostName, UserID: account.KeepshareUserID, MasterUserID: master}
+	if err := m.Events.Publish(ctx, e); err != nil {
+		log.WithContext(ctx).WithField("event", e).Errorf("publish event err: %v", err)
+	}
+}
+
 func (m *Manager) tokenTaskEnqueue(ctx context.Context) error {
 	tk := m.q.Token
 	ma := m.q.MasterAccount
@@ -22,15 +22,19 @@ import (
 
 // configs.
 const (
-	userServer     = "https://user.mypikpak.com"
-	apiServer      = "https://api-drive.mypikpak.com"
-	referralServer = "https://api-referral.mypikpak.com"
 	clientID       = "YNxT9w7GMdWvEOKa"
 	acceptLanguage = "en,en-US;q=0.9"
 
 	webClientID = "YUMx5nI8ZU8Ap8pm"
 )
 
+// base urls of servers, they can be changed by `pikpak.user_server`, `pikpak.api_server` and `pikpak.referral_server`.
+var (
+	userServer     = "https://user.mypikpak.com"
+	apiServer      = "https://api-drive.mypikpak.com"
+	referralServer = "https://api-referral.mypikpak.com"
+)
+
 var (
 	deviceID  = "c858a46bfca5c5f61b1702ed6c303acb"
 	userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/115.0.0.0 Safari/537.36"
@@ -67,6 +71,15 @@ func New(q *query.Query, d *hosts.Dependencies) *API {
 		cache:        freecache.NewCache(50 * 1024 * 1024),
 	}
 
+	if v := viper.GetString("pikpak.user_server"); v != "" {
+		userServer = strings.TrimRight(v, "/")
+	}
+	if v := viper.GetString("pikpak.api_server"); v != "" {
+		apiServer = strings.TrimRight(v, "/")
+	}
+	if v := viper.GetString("pikpak.referral_server"); v != "" {
+		referralServer = strings.TrimRight(v, "/")
+	}
 	if v := viper.GetString("pikpak.device_id"); v != "" {
 		deviceID = v
 		resCli = resCli.SetHeader("X-Device-Id", deviceID)
@@ -0,0 +1,139 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/mock"
+	"github.com/KeepShareOrg/keepshare/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAPI returns an api connected to a mock server, the api works without mysql if tokens are cached by login.
+func newTestAPI(t *testing.T) (*API, *mock.Server) {
+	s := mock.New()
+	t.Cleanup(s.Close)
+	s.Configure()
+	return New(nil, &hosts.Dependencies{Mailer: s.Mailer()}), s
+}
+
+// login adds a user to the mock server and caches its token.
+func (api *API) login(s *mock.Server, email string) string {
+	u, token := s.AddUser(email, "password")
+	api.cache.Set([]byte("token:"+u.ID), []byte(token), 0)
+	return u.ID
+}
+
+func TestSignUp(t *testing.T) {
+	api, s := newTestAPI(t)
+	ctx := context.Background()
+
+	user, err := api.SignUp(ctx, "", 3*time.Second)
+	require.NoError(t, err)
+	assert.NotEmpty(t, user.AccessToken)
+	assert.Equal(t, user.Email, s.User(user.UserID).Email)
+	assert.Equal(t, user.Password, s.User(user.UserID).Password)
+
+	s.Fail(mock.RouteCaptcha, "", 1, mock.ErrCaptchaInvalid)
+	_, err = api.SignUp(ctx, "", time.Second)
+	assert.True(t, IsCaptchaInvalidError(err), err)
+}
+
+func TestQueryLinkStatus(t *testing.T) {
+	api, s := newTestAPI(t)
+	ctx := context.Background()
+	link := "magnet:?xt=urn:btih:0000000000000000000000000000000000000000"
+
+	assert.Equal(t, comm.LinkStatusUnknown, api.QueryLinkStatus(ctx, link))
+
+	s.SetLinkStatus(link, comm.LinkStatusLimited)
+	assert.Equal(t, comm.LinkStatusLimited, api.QueryLinkStatus(ctx, link))
+}
+
+func TestCreateFilesFromLinkErrors(t *testing.T) {
+	api, s := newTestAPI(t)
+	ctx := context.Background()
+	worker := api.login(s, "worker@mock.mypikpak.com")
+	link := "https://example.com/a.mp4"
+
+	s.Fail(mock.RouteCreateFile, worker, 1, mock.ErrTaskDailyCreateLimit)
+	_, err := api.CreateFilesFromLink(ctx, "master", worker, link)
+	assert.True(t, IsTaskDailyCreateLimitErr(err), err)
+	assert.True(t, IsAccountLimited(err))
+
+	s.SetLinkSize(link, 7*util.GB)
+	_, err = api.CreateFilesFromLink(ctx, "master", worker, link)
+	assert.True(t, IsSpaceNotEnoughErr(err), err)
+
+	s.Fail(mock.RouteCreateFile, "", -1, mock.ErrTaskURLResolve)
+	_, err = api.CreateFilesFromLink(ctx, "master", worker, link)
+	assert.True(t, IsShouldNotRetryError(err), err)
+	assert.Equal(t, 3, s.Requests(mock.RouteCreateFile))
+}
+
+func TestStorageAndRedeem(t *testing.T) {
+	api, s := newTestAPI(t)
+	ctx := context.Background()
+	worker := api.login(s, "worker@mock.mypikpak.com")
+
+	used, limit, err := api.GetStorageSize(ctx, worker)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+	assert.Equal(t, s.DefaultLimitSize, limit)
+
+	exp, err := api.GetPremiumExpiration(ctx, worker)
+	require.NoError(t, err)
+	assert.True(t, exp.Before(time.Now()))
+
+	assert.True(t, IsInvalidRedeemCodeErr(api.Redeem(ctx, worker, "invalid")))
+
+	s.AddRedeemCodes("code")
+	require.NoError(t, api.Redeem(ctx, worker, "code"))
+	exp, err = api.GetPremiumExpiration(ctx, worker)
+	require.NoError(t, err)
+	assert.True(t, exp.After(time.Now()))
+	assert.True(t, IsInvalidRedeemCodeErr(api.Redeem(ctx, worker, "code")), "redeem codes can be used once")
+}
+
+func TestReferral(t *testing.T) {
+	api, s := newTestAPI(t)
+	ctx := context.Background()
+	master := api.login(s, "master@mock.mypikpak.com")
+	worker := api.login(s, "worker@mock.mypikpak.com")
+
+	require.NoError(t, api.JoinReferral(ctx, master))
+	assert.True(t, IsHasJoinedReferralErr(api.JoinReferral(ctx, master)))
+
+	s.UpdateUser(master, func(u *mock.User) { u.Commissions = 12.5 })
+	commissions, err := api.GetCommissions(ctx, master)
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, commissions.Total)
+
+	invite, err := api.GetInviteToken(ctx, master)
+	require.NoError(t, err)
+	require.NoError(t, api.VerifyInviteSubAccountTokenByInviteToken(ctx, invite.InviteToken, worker))
+	assert.Equal(t, master, s.User(worker).Master)
+}
+
+func TestGetShareStatus(t *testing.T) {
+	api, s := newTestAPI(t)
+	ctx := context.Background()
+
+	_, _, err := api.GetShareStatus(ctx, s.URL+"/s/unknown")
+	assert.Error(t, err)
+
+	s.Fail(mock.RouteGetShare, "", 1, mock.ErrInternal)
+	_, _, err = api.GetShareStatus(ctx, s.URL+"/s/unknown")
+	assert.True(t, IsInternalError(err), err)
+
+	_, err = getShareIDFromLink(s.URL + "/x")
+	assert.Error(t, err)
+}
@@ -11,7 +11,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/model"
 	lk "github.com/KeepShareOrg/keepshare/pkg/link"
@@ -36,13 +35,6 @@ type fileTask struct {
 	} `json:"params"`
 }
 
-var eventListeners = map[hosts.EventType][]hosts.ListenerCallback{}
-
-// AddEventListener add event listener
-func (api *API) AddEventListener(event hosts.EventType, fn hosts.ListenerCallback) {
-	eventListeners[event] = append(eventListeners[event], fn)
-}
-
 func (t *fileTask) toFile(keepshareUserID, master, worker, link string) *model.File {
 	if t == nil {
 		return nil
@@ -216,10 +216,9 @@ func (t *TaskManager) ppTasksHandler(ctx context.Context, task *asynq.Task) erro
 		UniqueHash:       fmt.Sprintf("%s:%s", ppTask.WorkerUserID, lk.Hash(ppTask.OriginalLinkHash)),
 	}
 	var callback = func() {
-		if callbackFns, ok := eventListeners[hosts.FileComplete]; ok {
-			for _, callbackFn := range callbackFns {
-				callbackFn(file.WorkerUserID, file.OriginalLinkHash)
-			}
+		e := hosts.FileCompleteEvent{Host: comm.HostName, UserID: file.WorkerUserID, OriginalLinkHash: file.OriginalLinkHash}
+		if err := t.d.Events.Publish(ctx, e); err != nil {
+			log.Errorf("publish file complete event err: %v", err)
 		}
 	}
 	switch status.Status {
@@ -236,11 +235,15 @@ func (t *TaskManager) ppTasksHandler(ctx context.Context, task *asynq.Task) erro
 			callback()
 			return nil
 		}
+		e := hosts.FileProgressEvent{Host: comm.HostName, UserID: file.WorkerUserID, OriginalLinkHash: file.OriginalLinkHash, Progress: int(status.Progress)}
+		if err := t.d.Events.Publish(ctx, e); err != nil {
+			log.Errorf("publish file progress event err: %v", err)
+		}
 		return fmt.Errorf("task status running: %v", status.Status)
 	case comm.StatusOK:
 		err := t.handleStatusOKTask(ctx, file)
 		if err != nil {
-			log.Errorf("handle status PHASE_TYPE_COMPLETE task error: %w", err)
+			log.Errorf("handle status PHASE_TYPE_COMPLETE task error: %v", err)
 			return err
 		}
 		log.Debugf("file complete: %v", file.OriginalLinkHash)
@@ -249,16 +252,20 @@ func (t *TaskManager) ppTasksHandler(ctx context.Context, task *asynq.Task) erro
 	case comm.StatusPending:
 		err := t.handleStatusPendingTask(ctx, file)
 		if err != nil {
-			log.Errorf("handle status PHASE_TYPE_PENDING task error: %w", err)
+			log.Errorf("handle status PHASE_TYPE_PENDING task error: %v", err)
 			return err
 		}
 		return nil
 	case comm.StatusError:
 		err := t.handleStatusErrorTask(ctx, file)
 		if err != nil {
-			log.Errorf("handle status PHASE_TYPE_ERROR task error: %w", err)
+			log.Errorf("handle status PHASE_TYPE_ERROR task error: %v", err)
 			return err
 		}
+		e := hosts.FileErrorEvent{Host: comm.HostName, UserID: file.WorkerUserID, OriginalLinkHash: file.OriginalLinkHash, Error: status.Message}
+		if err := t.d.Events.Publish(ctx, e); err != nil {
+			log.Errorf("publish file error event err: %v", err)
+		}
 		return nil
 	default:
 		return fmt.Errorf("task status not complete: %v", status.Status)
@@ -4,6 +4,9 @@
 
 package comm
 
+// HostName is the name of PikPak host.
+const HostName = "pikpak"
+
 // enum all statuses.
 const (
 	StatusOK      = "PHASE_TYPE_COMPLETE"
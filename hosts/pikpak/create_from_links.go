@@ -8,13 +8,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/KeepShareOrg/keepshare/config"
-
+	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/account"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/api"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
@@ -127,10 +125,13 @@ func (p *PikPak) CreateFromLinks(ctx context.Context, keepShareUserID string, or
 			}
 		}
 
-		size, err := p.queryFilesizeByLink(ctx, link)
-		if err != nil {
-			log.Errorf("query file size by link error: %v, link: %s", err, link)
-			size = 3 * util.GB
+		size, ok := hosts.SizeHint(ctx, link)
+		if !ok {
+			var err error
+			if size, err = p.queryFilesizeByLink(ctx, link); err != nil {
+				log.Errorf("query file size by link error: %v, link: %s", err, link)
+				size = 3 * util.GB
+			}
 		}
 
 		file, err := p.createFromLink(ctx, master, link, size, []string{})
@@ -158,12 +159,30 @@ func (p *PikPak) CreateFromLinks(ctx context.Context, keepShareUserID string, or
 		log.WithContext(ctx).Debug("links not completed:", lo.Keys(linksStatusNotCompleted))
 	}
 
+	// files which have been shared are re-checked, ShareCreated is published for the first share of files only.
+	var sharedFileIDs []string
+	if len(linksStatusOK) > 0 {
+		fileIDs := lo.MapToSlice(linksStatusOK, func(_ string, f *model.File) string { return f.FileID })
+		err := p.q.SharedLink.WithContext(ctx).Where(p.q.SharedLink.FileID.In(fileIDs...)).Pluck(p.q.SharedLink.FileID, &sharedFileIDs)
+		if err != nil {
+			return nil, fmt.Errorf("query shared links err: %w", err)
+		}
+	}
+
 	for _, f := range linksStatusOK {
 		sharedLink, err := p.api.CreateShare(ctx, f.MasterUserID, f.WorkerUserID, f.FileID)
 		if err != nil {
 			return nil, err
 		}
 		originalLink := hashToLink[f.OriginalLinkHash]
+		if !lo.Contains(sharedFileIDs, f.FileID) {
+			p.publish(ctx, hosts.ShareCreatedEvent{
+				Host:           comm.HostName,
+				UserID:         keepShareUserID,
+				OriginalLink:   originalLink,
+				HostSharedLink: sharedLink,
+			})
+		}
 		sharedLinks[originalLink] = &share.Share{
 			State:          share.StatusFromFileStatus(f.Status),
 			Title:          f.Name,
@@ -253,6 +272,7 @@ func (p *PikPak) createFromLink(ctx context.Context, master *model.MasterAccount
 	// try to get the limit_size is 0 byte account, if not exist, create one
 	worker, err = p.m.GetWorkerWithEnoughCapacity(ctx, master.UserID, size, workerAccountType, excludeWorkers)
 	if err != nil {
+		p.publish(ctx, hosts.WorkerExhaustedEvent{Host: comm.HostName, MasterUserID: master.UserID, Size: size, Error: err.Error()})
 		return nil, fmt.Errorf("get worker err: %v, link: %s", err, link)
 	}
 
@@ -335,36 +355,19 @@ func getInvalidUntilByCreateLinkError(err error, workerAccountType account.Statu
 	return
 }
 
-var httpClient = &http.Client{Timeout: 10 * time.Second}
-
-// queryFilesizeByLink query file size by link (call whatslink.info api)
+// queryFilesizeByLink query file size by the link info provider of dependencies.
 func (p *PikPak) queryFilesizeByLink(ctx context.Context, link string) (int64, error) {
-	addr := fmt.Sprintf("https://whatslink.info/api/v1/link?url=%s", link)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
-	if err != nil {
-		return 0, err
+	if p.LinkInfo == nil {
+		return 0, lk.ErrNoInfo
 	}
-	resp, err := httpClient.Do(req)
+	info, err := p.LinkInfo.LinkInfo(ctx, link)
 	if err != nil {
 		return 0, err
 	}
-	if resp.StatusCode != 200 {
-		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	bs, _ := io.ReadAll(resp.Body)
-	var sizeInfo struct {
-		Size int64 `json:"size"`
+	if info.Size <= 0 {
+		return 0, lk.ErrNoInfo
 	}
-	err = json.Unmarshal(bs, &sizeInfo)
-	if err != nil {
-		return 0, err
-	}
-
-	return sizeInfo.Size, nil
+	return info.Size, nil
 }
 
 // GetLinkAccessInfos get link access log list
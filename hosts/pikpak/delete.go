@@ -12,6 +12,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/model"
 	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
 	lk "github.com/KeepShareOrg/keepshare/pkg/link"
@@ -83,6 +85,8 @@ func (p *PikPak) Delete(ctx context.Context, userID string, originalLinks []stri
 	if err := p.q.DeleteQueue.WithContext(ctx).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(ds...); err != nil {
 		return fmt.Errorf("insert to delete queue err: %w", err)
 	}
+
+	p.publish(ctx, hosts.ShareDeletedEvent{Host: comm.HostName, UserID: userID, OriginalLinks: originalLinks})
 	return nil
 }
 
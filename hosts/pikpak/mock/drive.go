@@ -0,0 +1,234 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package mock
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
+	"github.com/KeepShareOrg/keepshare/pkg/util"
+	"github.com/spf13/cast"
+)
+
+func (t *Task) toJSON() map[string]any {
+	return map[string]any{
+		"id":           t.ID,
+		"created_time": t.Created.Format(time.RFC3339),
+		"file_id":      t.FileID,
+		"file_name":    t.FileName,
+		"file_size":    strconv.FormatInt(t.FileSize, 10),
+		"message":      t.Message,
+		"phase":        t.Phase,
+		"status_size":  1,
+		"progress":     t.Progress,
+		"params":       map[string]any{"predict_type": "1"},
+	}
+}
+
+// createFile creates an offline download task, the size of the file is reserved in the storage of the user.
+func (s *Server) createFile(r *request) (any, *Failure) {
+	params, _ := r.body["url"].(map[string]any)
+	link, _ := params["url"].(string)
+	if link == "" {
+		return nil, &Failure{ErrorKey: "invalid_argument", ErrorCode: 3, ErrorDescription: "url is required"}
+	}
+
+	u := s.users[r.userID]
+	size, ok := s.linkSizes[link]
+	if !ok {
+		size = s.DefaultFileSize
+	}
+	if u.UsedSize+size > u.LimitSize {
+		return nil, ErrFileSpaceNotEnough
+	}
+	u.UsedSize += size
+
+	name := link
+	if parsed, err := url.Parse(link); err == nil && path.Base(parsed.Path) != "." && path.Base(parsed.Path) != "/" {
+		name = path.Base(parsed.Path)
+	}
+
+	t := &Task{
+		ID:       s.nextID("T"),
+		UserID:   r.userID,
+		Link:     link,
+		FileID:   s.nextID("F"),
+		FileName: name,
+		FileSize: size,
+		Phase:    s.DefaultTaskPhase,
+		Created:  time.Now(),
+	}
+	if t.Phase == comm.StatusOK {
+		t.Progress = 100
+	}
+	s.tasks[t.ID] = t
+	return map[string]any{"task": t.toJSON()}, nil
+}
+
+func (s *Server) deleteFiles(r *request) (any, *Failure) {
+	ids := cast.ToStringSlice(r.body["ids"])
+	for _, id := range ids {
+		for k, t := range s.tasks {
+			if t.UserID == r.userID && t.FileID == id {
+				s.users[r.userID].UsedSize -= t.FileSize
+				delete(s.tasks, k)
+			}
+		}
+	}
+	return map[string]any{"task_id": s.nextID("T")}, nil
+}
+
+// listTasks lists the tasks of the user filtered by `{"id":{"in":"id1,id2"}}`.
+func (s *Server) listTasks(r *request) (any, *Failure) {
+	var filters struct {
+		ID struct {
+			In string `json:"in"`
+		} `json:"id"`
+	}
+	_ = json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+
+	tasks := []any{}
+	for _, id := range strings.Split(filters.ID.In, ",") {
+		if t := s.tasks[id]; t != nil && t.UserID == r.userID {
+			tasks = append(tasks, t.toJSON())
+		}
+	}
+	return map[string]any{"tasks": tasks}, nil
+}
+
+func (s *Server) taskStatuses(r *request) (any, *Failure) {
+	t := s.tasks[r.pathID]
+	if t == nil || t.UserID != r.userID {
+		return nil, &Failure{ErrorKey: "task_not_found", ErrorCode: 5, ErrorDescription: "Task not found"}
+	}
+	status := map[string]any{"file_size": strconv.FormatInt(t.FileSize, 10), "phase": t.Phase}
+	return map[string]any{"statuses": []any{status}}, nil
+}
+
+func (s *Server) linkStatus(r *request) (any, *Failure) {
+	status, ok := s.linkStatuses[r.URL.Query().Get("url")]
+	if !ok {
+		status = comm.LinkStatusUnknown
+	}
+	return map[string]any{"status": status}, nil
+}
+
+func (s *Server) about(r *request) (any, *Failure) {
+	u := s.users[r.userID]
+	return map[string]any{"quota": map[string]any{
+		"limit": strconv.FormatInt(u.LimitSize, 10),
+		"usage": strconv.FormatInt(u.UsedSize, 10),
+	}}, nil
+}
+
+func (s *Server) vip(r *request) (any, *Failure) {
+	u := s.users[r.userID]
+	data := map[string]any{"status": "invalid", "type": "novip", "expire": ""}
+	if u.PremiumExpiration.After(time.Now()) {
+		data = map[string]any{"status": "ok", "type": "platinum", "expire": u.PremiumExpiration.Format(time.RFC3339)}
+	}
+	return map[string]any{"data": data}, nil
+}
+
+// redeem activates a redeem code, the user becomes premium for 30 days with 10TB storage.
+func (s *Server) redeem(r *request) (any, *Failure) {
+	code := r.str("activation_code")
+	used, ok := s.redeemCodes[code]
+	if !ok || used {
+		return nil, ErrInvalidActivationCode
+	}
+	s.redeemCodes[code] = true
+
+	u := s.users[r.userID]
+	start := time.Now()
+	if u.PremiumExpiration.After(start) {
+		start = u.PremiumExpiration
+	}
+	u.PremiumExpiration = start.Add(30 * 24 * time.Hour)
+	u.LimitSize = 10 * 1024 * util.GB
+	return map[string]any{"code": code}, nil
+}
+
+func (s *Server) createShare(r *request) (any, *Failure) {
+	ids := cast.ToStringSlice(r.body["file_ids"])
+	if len(ids) == 0 {
+		return nil, &Failure{ErrorKey: "invalid_argument", ErrorCode: 3, ErrorDescription: "file_ids is required"}
+	}
+	for _, id := range ids {
+		if !s.hasFile(r.userID, id) {
+			return nil, ErrFileNotFound
+		}
+	}
+
+	sh := &Share{ID: s.nextID("S"), UserID: r.userID, FileIDs: ids, Status: "OK"}
+	s.shares[sh.ID] = sh
+	return map[string]any{"share_id": sh.ID, "share_url": s.URL + "/s/" + sh.ID}, nil
+}
+
+func (s *Server) hasFile(userID, fileID string) bool {
+	for _, t := range s.tasks {
+		if t.UserID == userID && t.FileID == fileID && t.Phase == comm.StatusOK {
+			return true
+		}
+	}
+	return false
+}
+
+// getShare returns the status of the share, authentication is not required.
+func (s *Server) getShare(r *request) (any, *Failure) {
+	sh := s.shares[r.URL.Query().Get("share_id")]
+	if sh == nil {
+		return nil, &Failure{ErrorKey: "share_not_found", ErrorCode: 5, ErrorDescription: "Share not found"}
+	}
+	return map[string]any{
+		"share_status": sh.Status,
+		"user_info":    map[string]any{"user_id": sh.UserID},
+	}, nil
+}
+
+func (s *Server) listShares(r *request) (any, *Failure) {
+	var filters struct {
+		ID struct {
+			In string `json:"in"`
+		} `json:"id"`
+	}
+	_ = json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+
+	data := []any{}
+	for _, id := range strings.Split(filters.ID.In, ",") {
+		if sh := s.shares[id]; sh != nil && sh.UserID == r.userID {
+			data = append(data, map[string]any{
+				"share_id":      sh.ID,
+				"share_status":  sh.Status,
+				"view_count":    strconv.Itoa(sh.Views),
+				"restore_count": strconv.Itoa(sh.Restores),
+			})
+		}
+	}
+	return map[string]any{"data": data}, nil
+}
+
+func (s *Server) deleteShares(r *request) (any, *Failure) {
+	for _, id := range cast.ToStringSlice(r.body["ids"]) {
+		if sh := s.shares[id]; sh != nil && sh.UserID == r.userID {
+			sh.Status = "DELETED"
+		}
+	}
+	return nil, nil
+}
+
+func shareIDFromLink(link string) string {
+	u, _ := url.Parse(link)
+	if u == nil {
+		return ""
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/s/"), "/")
+	return id
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package mock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/mail"
+)
+
+// Mailer is an in-memory mail.Mailer which receives the emails sent by the mock server.
+type Mailer struct {
+	domain string
+
+	mu    sync.Mutex
+	seq   int
+	boxes map[string][]*message // key: address
+}
+
+type message struct {
+	header mail.Header
+	body   mail.Body
+}
+
+var _ mail.Mailer = (*Mailer)(nil)
+
+func newMailer(domain string) *Mailer {
+	return &Mailer{domain: domain, boxes: map[string][]*message{}}
+}
+
+func (m *Mailer) deliver(to, from, subject, text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.seq++
+	m.boxes[to] = append(m.boxes[to], &message{
+		header: mail.Header{
+			Address: to,
+			ID:      fmt.Sprint(m.seq),
+			From:    from,
+			To:      []string{to},
+			Subject: subject,
+			Date:    time.Now(),
+			Size:    int64(len(text)),
+		},
+		body: mail.Body{Text: text},
+	})
+}
+
+// recipients returns the addresses which received emails containing the text.
+func (m *Mailer) recipients(text string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var addresses []string
+	for address, box := range m.boxes {
+		for _, msg := range box {
+			if strings.Contains(msg.body.Text, text) {
+				addresses = append(addresses, address)
+				break
+			}
+		}
+	}
+	return addresses
+}
+
+// List implements mail.Mailer.
+func (m *Mailer) List(_ context.Context, address string) ([]*mail.Header, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	headers := make([]*mail.Header, 0, len(m.boxes[address]))
+	for _, msg := range m.boxes[address] {
+		h := msg.header
+		headers = append(headers, &h)
+	}
+	return headers, nil
+}
+
+// Get implements mail.Mailer.
+func (m *Mailer) Get(_ context.Context, address string, id string) (*mail.Body, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, msg := range m.boxes[address] {
+		if msg.header.ID == id {
+			b := msg.body
+			return &b, nil
+		}
+	}
+	return nil, fmt.Errorf("mail %s not found", id)
+}
+
+// Del implements mail.Mailer.
+func (m *Mailer) Del(_ context.Context, address string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	box := m.boxes[address]
+	for i, msg := range box {
+		if msg.header.ID == id {
+			m.boxes[address] = append(box[:i], box[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Clear implements mail.Mailer.
+func (m *Mailer) Clear(_ context.Context, address string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.boxes, address)
+	return nil
+}
+
+// Domain implements mail.Mailer.
+func (m *Mailer) Domain() string {
+	return m.domain
+}
@@ -0,0 +1,496 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package mock provides an in-process PikPak server for tests.
+// It emulates the sign-up, token, task, share, quota, referral and redeem endpoints of
+// the user, api and referral servers on a single httptest server, and failures can be scripted:
+//
+//	s := mock.New()
+//	defer s.Close()
+//	s.Configure() // point `pikpak.*_server` configs to the mock server, before api.New.
+//	s.Fail(mock.RouteCreateFile, workerID, 1, mock.ErrTaskDailyCreateLimit)
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
+	"github.com/KeepShareOrg/keepshare/pkg/util"
+	"github.com/spf13/viper"
+)
+
+// Route is an endpoint of the PikPak servers, formatted as `METHOD /path`.
+type Route string
+
+// Enum all emulated routes.
+const (
+	RouteCaptcha          Route = "POST /v1/shield/captcha/init"
+	RouteVerification     Route = "POST /v1/auth/verification"
+	RouteVerify           Route = "POST /v1/auth/verification/verify"
+	RouteSignUp           Route = "POST /v1/auth/signup"
+	RouteSignIn           Route = "POST /v1/auth/signin"
+	RouteToken            Route = "POST /v1/auth/token"
+	RouteResetPassword    Route = "POST /v1/auth/reset"
+	RouteCreateFile       Route = "POST /drive/v1/files"
+	RouteDeleteFiles      Route = "POST /drive/v1/files:batchDelete"
+	RouteTasks            Route = "GET /drive/v1/tasks"
+	RouteTaskStatuses     Route = "GET /drive/v1/task/{id}/statuses"
+	RouteLinkStatus       Route = "GET /drive/v1/resource/status"
+	RouteAbout            Route = "GET /drive/v1/about"
+	RouteVIP              Route = "GET /drive/v1/privilege/vip"
+	RouteCreateShare      Route = "POST /drive/v1/share"
+	RouteGetShare         Route = "GET /drive/v1/share"
+	RouteListShares       Route = "GET /drive/v1/share/list"
+	RouteDeleteShares     Route = "POST /drive/v1/share:batchDelete"
+	RouteRedeem           Route = "POST /vip/v1/order/activation-code"
+	RouteJoinReferral     Route = "POST /promoting/v1/join"
+	RouteInviteSubAccount Route = "POST /promoting/v1/sub-account"
+	RouteVerifyInvitation Route = "GET /promoting/v1/sub-account/verify"
+	RouteAcceptInvitation Route = "POST /promoting/v1/sub-account/verify"
+	RouteInviteLink       Route = "GET /promoting/v1/sub-account/invite-link"
+	RouteCommissions      Route = "GET /promoting/v1/commissions/summary"
+)
+
+// Failure is an error response of the server.
+type Failure struct {
+	StatusCode       int    `json:"-"` // default to 400.
+	ErrorKey         string `json:"error"`
+	ErrorCode        int    `json:"error_code"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Failures returned by PikPak servers.
+var (
+	ErrTaskDailyCreateLimit  = &Failure{ErrorKey: "task_daily_create_limit", ErrorCode: 9, ErrorDescription: "Daily task limit reached"}
+	ErrTaskRunNumsLimit      = &Failure{ErrorKey: "task_run_nums_limit", ErrorCode: 9, ErrorDescription: "Too many running tasks"}
+	ErrFileSpaceNotEnough    = &Failure{ErrorKey: "file_space_not_enough", ErrorCode: 8, ErrorDescription: "Storage space is not enough"}
+	ErrTaskURLResolve        = &Failure{ErrorKey: "task_url_resolve_error", ErrorCode: 9, ErrorDescription: "Failed to resolve the url"}
+	ErrInvalidAccount        = &Failure{ErrorKey: "invalid_account_or_password", ErrorCode: 4, ErrorDescription: "Invalid account or password"}
+	ErrInvalidGrant          = &Failure{ErrorKey: "invalid_grant", ErrorCode: 4126, ErrorDescription: "Invalid refresh token"}
+	ErrUnauthenticated       = &Failure{StatusCode: http.StatusUnauthorized, ErrorKey: "unauthenticated", ErrorCode: 16, ErrorDescription: "Token is invalid or expired"}
+	ErrCaptchaInvalid        = &Failure{ErrorKey: "captcha_invalid", ErrorCode: 9, ErrorDescription: "Captcha is invalid"}
+	ErrInvalidActivationCode = &Failure{ErrorKey: "invalid_activation_code", ErrorCode: 4, ErrorDescription: "Invalid activation code"}
+	ErrHasJoinedReferral     = &Failure{ErrorKey: "has_joined_referral", ErrorCode: 4, ErrorDescription: "Has joined the referral program"}
+	ErrFileNotFound          = &Failure{ErrorKey: "file_not_found", ErrorCode: 5, ErrorDescription: "File not found"}
+	ErrInternal              = &Failure{StatusCode: http.StatusInternalServerError, ErrorKey: "internal", ErrorCode: 500, ErrorDescription: "Internal error"}
+)
+
+// Error implements error.
+func (f *Failure) Error() string {
+	return fmt.Sprintf("%d|%s|%s", f.ErrorCode, f.ErrorKey, f.ErrorDescription)
+}
+
+// scripted is a failure scripted by Fail.
+type scripted struct {
+	route   Route
+	userID  string
+	times   int // remaining times, negative means forever.
+	failure *Failure
+}
+
+// User is an account on the server.
+type User struct {
+	ID                string
+	Email             string
+	Password          string
+	LimitSize         int64
+	UsedSize          int64
+	PremiumExpiration time.Time
+	Commissions       float64
+
+	JoinedReferral bool
+	Master         string // the master of sub-account.
+}
+
+// Task is an offline download task, the file of a completed task is stored in the drive of the user.
+type Task struct {
+	ID       string
+	UserID   string
+	Link     string
+	FileID   string
+	FileName string
+	FileSize int64
+	Phase    string
+	Progress int64
+	Message  string
+	Created  time.Time
+}
+
+// Share is a shared link of files.
+type Share struct {
+	ID       string
+	UserID   string
+	FileIDs  []string
+	Status   string
+	Views    int
+	Restores int
+}
+
+// Server is a mock PikPak server, it is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// DefaultLimitSize is the storage limit of new users, default to 6GB.
+	DefaultLimitSize int64
+	// DefaultTaskPhase is the phase of new tasks, default to PHASE_TYPE_RUNNING.
+	// Set it to PHASE_TYPE_COMPLETE to complete tasks immediately.
+	DefaultTaskPhase string
+	// DefaultFileSize is the size of files if it is not set by SetLinkSize, default to 1MB.
+	DefaultFileSize int64
+
+	mu            sync.Mutex
+	seq           int
+	users         map[string]*User   // key: user id
+	emails        map[string]string  // key: email, value: user id
+	tokens        map[string]string  // key: access token, value: user id
+	refreshTokens map[string]string  // key: refresh token, value: user id
+	verifications map[string]*verify // key: verification id
+	verified      map[string]string  // key: verification token, value: email
+	invitations   map[string]string  // key: invite token, value: master user id
+	tasks         map[string]*Task   // key: task id
+	shares        map[string]*Share  // key: share id
+	linkStatuses  map[string]string  // key: original link
+	linkSizes     map[string]int64   // key: original link
+	redeemCodes   map[string]bool    // key: redeem code, value: used
+	failures      []*scripted
+	requests      map[Route]int
+
+	mailer *Mailer
+}
+
+type verify struct {
+	email string
+	code  string
+}
+
+// New starts a mock server, the caller should call Close when finished.
+func New() *Server {
+	s := &Server{
+		DefaultLimitSize: 6 * util.GB,
+		DefaultTaskPhase: comm.StatusRunning,
+		DefaultFileSize:  1024 * 1024,
+
+		// ids of different servers are different, so they don't conflict in the database or redis of tests.
+		seq: int(time.Now().Unix()%1e6) * 1000,
+
+		users:         map[string]*User{},
+		emails:        map[string]string{},
+		tokens:        map[string]string{},
+		refreshTokens: map[string]string{},
+		verifications: map[string]*verify{},
+		verified:      map[string]string{},
+		invitations:   map[string]string{},
+		tasks:         map[string]*Task{},
+		shares:        map[string]*Share{},
+		linkStatuses:  map[string]string{},
+		linkSizes:     map[string]int64{},
+		redeemCodes:   map[string]bool{},
+		requests:      map[Route]int{},
+
+		mailer: newMailer("mock.mypikpak.com"),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Configure sets the base urls of PikPak servers to the mock server, it must be called before api.New.
+func (s *Server) Configure() {
+	viper.Set("pikpak.user_server", s.URL)
+	viper.Set("pikpak.api_server", s.URL)
+	viper.Set("pikpak.referral_server", s.URL)
+}
+
+// Mailer returns the mailbox which receives the verification emails sent by the server.
+func (s *Server) Mailer() *Mailer {
+	return s.mailer
+}
+
+// Fail makes the next n requests to the route fail with the failure, it fails forever if n is negative.
+// If userID is not empty, only requests of the user fail, the user is identified by the token or the sign-in username.
+func (s *Server) Fail(route Route, userID string, n int, f *Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, &scripted{route: route, userID: userID, times: n, failure: f})
+}
+
+// ClearFailures removes all scripted failures.
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// Requests returns the number of requests received by the route.
+func (s *Server) Requests(route Route) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[route]
+}
+
+// AddUser creates an account directly without verification, and returns it with an access token.
+func (s *Server) AddUser(email, password string) (u *User, accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u = s.addUser(email, password)
+	accessToken, _ = s.issueToken(u.ID)
+	return u, accessToken
+}
+
+// User returns a copy of the user, nil is returned if not found.
+func (s *Server) User(userID string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u := s.users[userID]; u != nil {
+		c := *u
+		return &c
+	}
+	return nil
+}
+
+// UpdateUser updates the user by fn, it returns false if the user is not found.
+func (s *Server) UpdateUser(userID string, fn func(u *User)) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.users[userID]
+	if u == nil {
+		return false
+	}
+	fn(u)
+	return true
+}
+
+// Tasks returns copies of the tasks created from the link.
+func (s *Server) Tasks(link string) []*Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tasks []*Task
+	for _, t := range s.tasks {
+		if t.Link == link {
+			c := *t
+			tasks = append(tasks, &c)
+		}
+	}
+	return tasks
+}
+
+// SetTaskPhase sets the phase of tasks created from the link, such as PHASE_TYPE_COMPLETE or PHASE_TYPE_ERROR.
+func (s *Server) SetTaskPhase(link string, phase string, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tasks {
+		if t.Link == link {
+			t.Phase = phase
+			t.Message = message
+			if phase == comm.StatusOK {
+				t.Progress = 100
+			}
+		}
+	}
+}
+
+// SetLinkStatus sets the resource status of the link, such as OK or LIMITED, default to UNKNOWN.
+func (s *Server) SetLinkStatus(link string, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.linkStatuses[link] = status
+}
+
+// SetLinkSize sets the size of the file downloaded from the link.
+func (s *Server) SetLinkSize(link string, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.linkSizes[link] = size
+}
+
+// SetShare updates the status and statistics of the shared link, the status is such as OK or SENSITIVE_RESOURCE.
+func (s *Server) SetShare(sharedLink string, status string, views, restores int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh := s.shares[shareIDFromLink(sharedLink)]
+	if sh == nil {
+		return false
+	}
+	sh.Status, sh.Views, sh.Restores = status, views, restores
+	return true
+}
+
+// AddRedeemCodes adds valid redeem codes, each of them can be used once.
+func (s *Server) AddRedeemCodes(codes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range codes {
+		s.redeemCodes[code] = false
+	}
+}
+
+type handler func(r *request) (any, *Failure)
+
+type request struct {
+	*http.Request
+	userID string // the user of the access token.
+	body   map[string]any
+	pathID string // the {id} in the path.
+}
+
+func (r *request) str(key string) string {
+	v, _ := r.body[key].(string)
+	return v
+}
+
+func (s *Server) routes() map[Route]handler {
+	return map[Route]handler{
+		RouteCaptcha:          s.captcha,
+		RouteVerification:     s.verification,
+		RouteVerify:           s.verify,
+		RouteSignUp:           s.signUp,
+		RouteSignIn:           s.signIn,
+		RouteToken:            s.token,
+		RouteResetPassword:    s.resetPassword,
+		RouteCreateFile:       s.authed(s.createFile),
+		RouteDeleteFiles:      s.authed(s.deleteFiles),
+		RouteTasks:            s.authed(s.listTasks),
+		RouteTaskStatuses:     s.authed(s.taskStatuses),
+		RouteLinkStatus:       s.linkStatus,
+		RouteAbout:            s.authed(s.about),
+		RouteVIP:              s.authed(s.vip),
+		RouteCreateShare:      s.authed(s.createShare),
+		RouteGetShare:         s.getShare,
+		RouteListShares:       s.authed(s.listShares),
+		RouteDeleteShares:     s.authed(s.deleteShares),
+		RouteRedeem:           s.authed(s.redeem),
+		RouteJoinReferral:     s.authed(s.joinReferral),
+		RouteInviteSubAccount: s.authed(s.inviteSubAccount),
+		RouteVerifyInvitation: s.verifyInvitation,
+		RouteAcceptInvitation: s.authed(s.acceptInvitation),
+		RouteInviteLink:       s.authed(s.inviteLink),
+		RouteCommissions:      s.authed(s.commissions),
+	}
+}
+
+// authed requires a valid access token.
+func (s *Server) authed(h handler) handler {
+	return func(r *request) (any, *Failure) {
+		if r.userID == "" {
+			return nil, ErrUnauthenticated
+		}
+		return h(r)
+	}
+}
+
+func (s *Server) match(method, path string) (route Route, pathID string) {
+	const taskPrefix, taskSuffix = "/drive/v1/task/", "/statuses"
+	if method == http.MethodGet && strings.HasPrefix(path, taskPrefix) && strings.HasSuffix(path, taskSuffix) {
+		return RouteTaskStatuses, strings.TrimSuffix(strings.TrimPrefix(path, taskPrefix), taskSuffix)
+	}
+	return Route(method + " " + path), ""
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, hr *http.Request) {
+	route, pathID := s.match(hr.Method, hr.URL.Path)
+	h := s.routes()[route]
+	if h == nil {
+		writeJSON(w, http.StatusNotFound, &Failure{ErrorKey: "not_found", ErrorCode: 404})
+		return
+	}
+
+	r := &request{Request: hr, pathID: pathID, body: map[string]any{}}
+	if hr.Body != nil {
+		_ = json.NewDecoder(hr.Body).Decode(&r.body)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[route]++
+	if token, ok := strings.CutPrefix(hr.Header.Get("Authorization"), "Bearer "); ok {
+		r.userID = s.tokens[token]
+	}
+
+	scope := r.userID
+	if route == RouteSignIn {
+		scope = s.emails[r.str("username")]
+	}
+	if f := s.scriptedFailure(route, scope); f != nil {
+		writeJSON(w, f.StatusCode, f)
+		return
+	}
+
+	resp, f := h(r)
+	if f != nil {
+		writeJSON(w, f.StatusCode, f)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) scriptedFailure(route Route, userID string) *Failure {
+	for i, f := range s.failures {
+		if f.route != route || (f.userID != "" && f.userID != userID) {
+			continue
+		}
+		if f.times > 0 {
+			f.times--
+			if f.times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f.failure
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	if code == 0 {
+		code = http.StatusBadRequest
+	}
+	if v == nil {
+		v = map[string]any{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// nextID returns a unique id with the prefix, the caller must hold the lock.
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s%08d", prefix, s.seq)
+}
+
+func (s *Server) addUser(email, password string) *User {
+	u := &User{
+		ID:        s.nextID("U"),
+		Email:     email,
+		Password:  password,
+		LimitSize: s.DefaultLimitSize,
+	}
+	s.users[u.ID] = u
+	s.emails[email] = u.ID
+	return u
+}
+
+func (s *Server) issueToken(userID string) (accessToken, refreshToken string) {
+	accessToken, refreshToken = s.nextID("at."), s.nextID("rt.")
+	s.tokens[accessToken] = userID
+	s.refreshTokens[refreshToken] = userID
+	return
+}
+
+func (s *Server) tokenResponse(userID string) map[string]any {
+	accessToken, refreshToken := s.issueToken(userID)
+	return map[string]any{
+		"token_type":    "Bearer",
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"expires_in":    7200,
+		"sub":           userID,
+		"user_id":       userID,
+	}
+}
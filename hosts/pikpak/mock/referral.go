@@ -0,0 +1,79 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package mock
+
+import "fmt"
+
+func (s *Server) joinReferral(r *request) (any, *Failure) {
+	u := s.users[r.userID]
+	if u.JoinedReferral {
+		return nil, ErrHasJoinedReferral
+	}
+	u.JoinedReferral = true
+	return map[string]any{"id": s.nextID("R")}, nil
+}
+
+// inviteSubAccount sends an invitation email with a token to the email.
+func (s *Server) inviteSubAccount(r *request) (any, *Failure) {
+	email := r.str("email")
+	if email == "" {
+		return nil, &Failure{ErrorKey: "invalid_argument", ErrorCode: 3, ErrorDescription: "email is required"}
+	}
+
+	token := s.nextID("it.")
+	s.invitations[token] = r.userID
+	s.mailer.deliver(email, senderEmail, "Invitation", fmt.Sprintf("Accept the invitation: %s/promoting/v1/sub-account/verify?token=%s", s.URL, token))
+	return nil, nil
+}
+
+// verifyInvitation accepts the invitation sent by email, the invited email becomes a sub-account.
+func (s *Server) verifyInvitation(r *request) (any, *Failure) {
+	token := r.URL.Query().Get("token")
+	master := s.invitations[token]
+	if master == "" {
+		return nil, &Failure{ErrorKey: "invalid_invite_token", ErrorCode: 4, ErrorDescription: "Invalid invite token"}
+	}
+	delete(s.invitations, token)
+
+	for _, m := range s.mailer.recipients(token) {
+		if u := s.users[s.emails[m]]; u != nil {
+			u.Master = master
+		}
+	}
+	return nil, nil
+}
+
+// acceptInvitation binds the current user as a sub-account of the inviter.
+func (s *Server) acceptInvitation(r *request) (any, *Failure) {
+	master := s.invitations[r.str("token")]
+	if master == "" {
+		return nil, &Failure{ErrorKey: "invalid_invite_token", ErrorCode: 4, ErrorDescription: "Invalid invite token"}
+	}
+	if master == r.userID {
+		return nil, &Failure{ErrorKey: "invalid_argument", ErrorCode: 3, ErrorDescription: "Can not invite yourself"}
+	}
+	s.users[r.userID].Master = master
+	return nil, nil
+}
+
+// inviteLink returns a reusable invite token of the user.
+func (s *Server) inviteLink(r *request) (any, *Failure) {
+	for token, master := range s.invitations {
+		if master == r.userID {
+			return map[string]any{"invite_token": token}, nil
+		}
+	}
+	token := s.nextID("it.")
+	s.invitations[token] = r.userID
+	return map[string]any{"invite_token": token}, nil
+}
+
+func (s *Server) commissions(r *request) (any, *Failure) {
+	u := s.users[r.userID]
+	if !u.JoinedReferral {
+		return nil, &Failure{ErrorKey: "not_joined_referral", ErrorCode: 4, ErrorDescription: "Not joined the referral program"}
+	}
+	return map[string]any{"total": u.Commissions, "pending": 0, "available": u.Commissions}, nil
+}
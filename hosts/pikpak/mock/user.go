@@ -0,0 +1,99 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package mock
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// senderEmail is the sender of verification emails.
+const senderEmail = "noreply@accounts.mypikpak.com"
+
+func (s *Server) captcha(*request) (any, *Failure) {
+	return map[string]any{"captcha_token": s.nextID("ck.")}, nil
+}
+
+// verification sends a verification code to the email.
+func (s *Server) verification(r *request) (any, *Failure) {
+	if r.Header.Get("X-Captcha-Token") == "" {
+		return nil, ErrCaptchaInvalid
+	}
+	email := r.str("email")
+	if email == "" {
+		return nil, &Failure{ErrorKey: "invalid_argument", ErrorCode: 3, ErrorDescription: "email is required"}
+	}
+
+	id := s.nextID("V")
+	code := fmt.Sprintf("%06d", s.seq%1000000)
+	s.verifications[id] = &verify{email: email, code: code}
+	s.mailer.deliver(email, senderEmail, "Verification code", fmt.Sprintf("Your verification code is %s.", code))
+	return map[string]any{"verification_id": id}, nil
+}
+
+func (s *Server) verify(r *request) (any, *Failure) {
+	v := s.verifications[r.str("verification_id")]
+	if v == nil || v.code != r.str("verification_code") {
+		return nil, &Failure{ErrorKey: "invalid_verification_code", ErrorCode: 4, ErrorDescription: "Invalid verification code"}
+	}
+	delete(s.verifications, r.str("verification_id"))
+
+	token := s.nextID("vt.")
+	s.verified[token] = v.email
+	return map[string]any{"verification_token": token}, nil
+}
+
+func (s *Server) signUp(r *request) (any, *Failure) {
+	email := r.str("email")
+	if s.verified[r.str("verification_token")] != email {
+		return nil, &Failure{ErrorKey: "invalid_verification_token", ErrorCode: 4, ErrorDescription: "Invalid verification token"}
+	}
+	delete(s.verified, r.str("verification_token"))
+	if s.emails[email] != "" {
+		return nil, &Failure{ErrorKey: "already_exists", ErrorCode: 6, ErrorDescription: "Email already exists"}
+	}
+
+	u := s.addUser(email, r.str("password"))
+	return s.tokenResponse(u.ID), nil
+}
+
+func (s *Server) signIn(r *request) (any, *Failure) {
+	if r.Header.Get("X-Captcha-Token") == "" {
+		return nil, ErrCaptchaInvalid
+	}
+	u := s.users[s.emails[r.str("username")]]
+	if u == nil || u.Password == "" || u.Password != r.str("password") {
+		return nil, ErrInvalidAccount
+	}
+	return s.tokenResponse(u.ID), nil
+}
+
+// token refreshes the access token.
+func (s *Server) token(r *request) (any, *Failure) {
+	if r.str("grant_type") != "refresh_token" {
+		return nil, &Failure{ErrorKey: "unsupported_grant_type", ErrorCode: 4, ErrorDescription: "Unsupported grant type"}
+	}
+	userID := s.refreshTokens[r.str("refresh_token")]
+	if userID == "" {
+		return nil, ErrInvalidGrant
+	}
+	delete(s.refreshTokens, r.str("refresh_token"))
+	return s.tokenResponse(userID), nil
+}
+
+func (s *Server) resetPassword(r *request) (any, *Failure) {
+	email := r.str("email")
+	if s.verified[r.str("verification_token")] != email {
+		return nil, &Failure{ErrorKey: "invalid_verification_token", ErrorCode: 4, ErrorDescription: "Invalid verification token"}
+	}
+	delete(s.verified, r.str("verification_token"))
+
+	u := s.users[s.emails[email]]
+	if u == nil {
+		return nil, &Failure{StatusCode: http.StatusNotFound, ErrorKey: "user_not_found", ErrorCode: 5, ErrorDescription: "User not found"}
+	}
+	u.Password = r.str("new_password")
+	return nil, nil
+}
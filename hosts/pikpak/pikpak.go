@@ -13,6 +13,7 @@ import (
 	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/account"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/api"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/query"
 	"github.com/hibiken/asynq"
 )
@@ -35,7 +36,7 @@ func init() {
 		panic(fmt.Errorf("read sql files err: %w", err))
 	}
 
-	hosts.Register(&hosts.Properties{Name: "pikpak", New: New, CreateTableStatements: sql})
+	hosts.Register(&hosts.Properties{Name: comm.HostName, New: New, CreateTableStatements: sql, RehashLinks: rehashLinks, PremiumLinks: premiumLinks})
 }
 
 // New create a PikPak host.
@@ -61,3 +62,23 @@ func New(d *hosts.Dependencies) hosts.Host {
 
 	return p
 }
+
+// publish publishes the event of the host, errors are logged only.
+func (p *PikPak) publish(ctx context.Context, e hosts.Event) {
+	if err := p.Events.Publish(ctx, e); err != nil {
+		log.WithContext(ctx).WithField("event", e).Errorf("publish event err: %v", err)
+	}
+}
+
+// Capabilities returns what PikPak supports.
+func (p *PikPak) Capabilities() hosts.Capabilities {
+	return hosts.Capabilities{
+		LinkSchemes:           []string{"magnet", "ed2k", "http", "https", "ftp", "thunder"},
+		Statistics:            true,
+		Revenue:               true,
+		PasswordManagement:    true,
+		RedeemCodes:           true,
+		ReleaseOnlyForPremium: true,
+		RedirectParams:        []string{"act"},
+	}
+}
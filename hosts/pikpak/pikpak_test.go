@@ -0,0 +1,120 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package pikpak
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/mock"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/queue"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPikPak returns a PikPak host connected to a mock server, with mysql and redis of env KS_DB_MYSQL and KS_DB_REDIS.
+// The database must be dedicated to tests, rows of PikPak tables are deleted.
+func newTestPikPak(t *testing.T) (*PikPak, *mock.Server) {
+	if os.Getenv("KS_DB_MYSQL") == "" || os.Getenv("KS_DB_REDIS") == "" {
+		t.Skip("KS_DB_MYSQL or KS_DB_REDIS is empty")
+	}
+	require.NoError(t, config.Load())
+
+	s := mock.New()
+	t.Cleanup(s.Close)
+	s.Configure()
+	viper.Set("pikpak.master_buffer_size", 1)
+	viper.Set("pikpak.master_buffer_interval", "100ms")
+	viper.Set("pikpak.worker_buffer_size", 2)
+	viper.Set("pikpak.worker_buffer_interval", "100ms")
+
+	sql, err := hosts.ReadSQLFileFromFS(sqlFS)
+	require.NoError(t, err)
+	d := hoststest.MySQLDependencies(t, &hosts.Properties{Name: comm.HostName, CreateTableStatements: sql})
+	for _, table := range []string{"delete_queue", "file", "master_account", "redeem_code", "shared_link", "token", "worker_account"} {
+		require.NoError(t, d.Mysql.Exec(fmt.Sprintf("DELETE FROM `%s_%s`", comm.HostName, table)).Error)
+	}
+
+	d.Redis = config.Redis()
+	d.Mailer = s.Mailer()
+	d.Events = hosts.NewEventBus(nil)
+	d.Queue = queue.New(*config.Redis().Options(), map[string]int{constant.AsyncQueueInviteSubAccount: 1}).Client()
+
+	p := New(d).(*PikPak)
+
+	// wait for the buffers of accounts signed up from the mock server.
+	require.Eventually(t, func() bool {
+		masters, _ := p.q.MasterAccount.Where(p.q.MasterAccount.KeepshareUserID.Eq("")).Count()
+		workers, _ := p.q.WorkerAccount.Count()
+		return masters >= 1 && workers >= 2
+	}, 30*time.Second, 100*time.Millisecond)
+
+	return p, s
+}
+
+func TestCreateFromLinks(t *testing.T) {
+	p, s := newTestPikPak(t)
+	ctx := context.Background()
+	userID := fmt.Sprintf("t%015d", time.Now().UnixNano()%1e15)
+	link := "https://example.com/video.mp4"
+
+	completed := make(chan hosts.FileCompleteEvent, 1)
+	hosts.Subscribe(p.Events, func(_ context.Context, e hosts.FileCompleteEvent) error {
+		completed <- e
+		return nil
+	})
+
+	// the first worker reaches the daily limit, the link is created by another worker.
+	s.Fail(mock.RouteCreateFile, "", 1, mock.ErrTaskDailyCreateLimit)
+	shares, err := p.CreateFromLinks(ctx, userID, []string{link}, share.AutoShare, "")
+	require.NoError(t, err)
+	require.NotNil(t, shares[link])
+	assert.Equal(t, share.StatusCreated, shares[link].State)
+	assert.Equal(t, 2, s.Requests(mock.RouteCreateFile))
+
+	master, err := p.m.GetMaster(ctx, userID)
+	require.NoError(t, err)
+	file, err := p.api.GetFileByOriginalLinkHash(ctx, master.UserID, "", lk.Hash(link))
+	require.NoError(t, err)
+	w := &p.q.WorkerAccount
+	limited, err := w.WithContext(ctx).Where(w.MasterUserID.Eq(master.UserID), w.InvalidUntil.Gt(time.Now())).Find()
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.NotEqual(t, limited[0].UserID, file.WorkerUserID)
+
+	// the task manager publishes the event when the task is complete.
+	s.SetTaskPhase(link, comm.StatusOK, "")
+	select {
+	case e := <-completed:
+		assert.Equal(t, comm.HostName, e.Host)
+	case <-time.After(30 * time.Second):
+		t.Fatal("file complete event is not published")
+	}
+
+	shares, err = p.CreateFromLinks(ctx, userID, []string{link}, share.AutoShare, "")
+	require.NoError(t, err)
+	require.NotNil(t, shares[link])
+	assert.Equal(t, share.StatusOK, shares[link].State)
+	assert.NotEmpty(t, shares[link].HostSharedLink)
+
+	// sensitive links are not created.
+	limitedLink := "https://example.com/limited.mp4"
+	s.SetLinkStatus(limitedLink, comm.LinkStatusLimited)
+	shares, err = p.CreateFromLinks(ctx, userID, []string{limitedLink}, share.AutoShare, "")
+	require.NoError(t, err)
+	assert.Equal(t, share.StatusSensitive, shares[limitedLink].State)
+	assert.Empty(t, s.Tasks(limitedLink))
+}
@@ -0,0 +1,35 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package pikpak
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/query"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+// premiumLinks returns the hashes of original links whose files are stored by premium worker accounts for the keepshare user.
+func premiumLinks(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes []string) (premium []string, err error) {
+	q := query.Use(db)
+	f, w := q.File, q.WorkerAccount
+	now := time.Now()
+	for _, batch := range lo.Chunk(hashes, 1000) {
+		uniqueHashes := lo.Map(batch, func(h string, _ int) string { return fmt.Sprintf("%s:%s", keepshareUserID, h) })
+		var found []string
+		err := f.WithContext(ctx).
+			Join(w, w.UserID.EqCol(f.WorkerUserID)).
+			Where(f.UniqueHash.In(uniqueHashes...), w.PremiumExpiration.Gt(now)).
+			Pluck(f.OriginalLinkHash, &found)
+		if err != nil {
+			return nil, fmt.Errorf("query premium files err: %w", err)
+		}
+		premium = append(premium, found...)
+	}
+	return premium, nil
+}
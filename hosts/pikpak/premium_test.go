@@ -0,0 +1,35 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package pikpak
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestPremiumLinks(t *testing.T) {
+	cfg := &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true}
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), cfg)
+	require.NoError(t, err)
+	var sql string
+	var vars []any
+	err = db.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+	require.NoError(t, err)
+
+	_, err = premiumLinks(context.Background(), db, "u1", []string{"h1", "h2"})
+	require.NoError(t, err)
+	assert.Contains(t, sql, "SELECT `original_link_hash` FROM `pikpak_file` INNER JOIN")
+	assert.Contains(t, sql, "INNER JOIN `pikpak_worker_account` ON `pikpak_worker_account`.`user_id` = `pikpak_file`.`worker_user_id`")
+	assert.Contains(t, sql, "`pikpak_file`.`unique_hash` IN (?,?)")
+	assert.Contains(t, sql, "`pikpak_worker_account`.`premium_expiration` > ?")
+	assert.Subset(t, vars, []any{"u1:h1", "u1:h2"})
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package pikpak
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/query"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"gorm.io/gorm"
+)
+
+// rehashLinks updates the hashes of files created for the keepshare user.
+// Files whose new hash already exists are duplicated, they are kept with the old hash.
+func rehashLinks(ctx context.Context, db *gorm.DB, keepshareUserID string, hashes map[string]string) (rows int64, err error) {
+	f := query.Use(db).File
+	for oldHash, newHash := range hashes {
+		info, err := f.WithContext(ctx).
+			Where(f.UniqueHash.Eq(fmt.Sprintf("%s:%s", keepshareUserID, oldHash))).
+			UpdateSimple(
+				f.OriginalLinkHash.Value(newHash),
+				f.UniqueHash.Value(fmt.Sprintf("%s:%s", keepshareUserID, newHash)),
+			)
+		if gormutil.IsDuplicateError(err) {
+			log.WithContext(ctx).WithFields(log.Fields{
+				"keepshare_user_id": keepshareUserID,
+				"old_hash":          oldHash,
+				"new_hash":          newHash,
+			}).Warn("file of the new hash exists, skip it")
+			continue
+		}
+		if err != nil {
+			return rows, fmt.Errorf("update file hash err: %w", err)
+		}
+		rows += info.RowsAffected
+	}
+	return rows, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// errNotFound is returned when the resource does not exist on the WebDAV server.
+var errNotFound = errors.New("webdav: not found")
+
+// client is a minimal WebDAV client which only implements the methods used by the host.
+type client struct {
+	endpoint string // without trailing slash
+	username string
+	password string
+	http     *http.Client
+}
+
+func newClient(endpoint, username, password string) *client {
+	return &client{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		username: username,
+		password: password,
+		http:     &http.Client{},
+	}
+}
+
+func (c *client) url(p string) string {
+	u := &url.URL{Path: path.Clean("/" + p)}
+	return c.endpoint + u.EscapedPath()
+}
+
+func (c *client) do(ctx context.Context, method, p string, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.url(p), body)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 && body != nil {
+		req.ContentLength = size
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return c.http.Do(req)
+}
+
+// MkdirAll creates the collection and all of its parents.
+func (c *client) MkdirAll(ctx context.Context, dir string) error {
+	var cur string
+	for _, seg := range strings.Split(strings.Trim(path.Clean("/"+dir), "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		cur += "/" + seg
+		resp, err := c.do(ctx, "MKCOL", cur+"/", nil, -1, nil)
+		if err != nil {
+			return fmt.Errorf("mkcol %s err: %w", cur, err)
+		}
+		_ = resp.Body.Close()
+		// 405 Method Not Allowed means the collection already exists.
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("mkcol %s got unexpected status code: %d", cur, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+// Put uploads the content to the path, size is -1 if unknown.
+func (c *client) Put(ctx context.Context, p string, r io.Reader, size int64) error {
+	resp, err := c.do(ctx, http.MethodPut, p, r, size, nil)
+	if err != nil {
+		return fmt.Errorf("put %s err: %w", p, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return fmt.Errorf("put %s got unexpected status code: %d", p, resp.StatusCode)
+	}
+}
+
+// Exists checks whether the resource exists.
+func (c *client) Exists(ctx context.Context, p string) (bool, error) {
+	resp, err := c.do(ctx, "PROPFIND", p, nil, -1, map[string]string{"Depth": "0"})
+	if err != nil {
+		return false, fmt.Errorf("propfind %s err: %w", p, err)
+	}
+	_ = resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusMultiStatus, http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("propfind %s got unexpected status code: %d", p, resp.StatusCode)
+	}
+}
+
+// Delete removes the resource, deleting a non-existent resource returns errNotFound.
+func (c *client) Delete(ctx context.Context, p string) error {
+	resp, err := c.do(ctx, http.MethodDelete, p, nil, -1, nil)
+	if err != nil {
+		return fmt.Errorf("delete %s err: %w", p, err)
+	}
+	_ = resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return errNotFound
+	default:
+		return fmt.Errorf("delete %s got unexpected status code: %d", p, resp.StatusCode)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/webdav"
+)
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(&webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	})
+}
+
+func TestClient(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	cli := newClient(srv.URL, "", "")
+
+	assert.NoError(t, cli.MkdirAll(ctx, "/a/b c/d"))
+	assert.NoError(t, cli.MkdirAll(ctx, "/a/b c"))
+	assert.NoError(t, cli.Put(ctx, "/a/b c/d/hello.txt", strings.NewReader("hello"), 5))
+
+	ok, err := cli.Exists(ctx, "/a/b c/d/hello.txt")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.NoError(t, cli.Delete(ctx, "/a/b c/d/"))
+	ok, err = cli.Exists(ctx, "/a/b c/d/hello.txt")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, cli.Delete(ctx, "/a/b c/d/"), errNotFound)
+}
+
+func TestTransfer(t *testing.T) {
+	hoststest.AllowPrivateNetworks(t)
+
+	dav := newTestServer()
+	defer dav.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("some content"))
+	}))
+	defer origin.Close()
+
+	w := &WebDAV{cli: newClient(dav.URL, "", ""), root: "/keepshare", publicURL: "https://dav.example.com"}
+	f := &mirror.File{
+		KeepshareUserID:  "user",
+		OriginalLinkHash: "hash",
+		OriginalLink:     origin.URL + "/dir/movie%201.mp4",
+	}
+
+	update, err := mirror.New(mirror.Options{Storage: storage{w}}).Transfer(context.Background(), f)
+	assert.NoError(t, err)
+	assert.Equal(t, mirror.StatusOK, update.Status)
+	assert.Equal(t, "movie 1.mp4", update.Name)
+	assert.Equal(t, int64(len("some content")), update.Size)
+	assert.Equal(t, "https://dav.example.com/keepshare/user/hash/movie%201.mp4", update.SharedLink)
+
+	ok, err := w.cli.Exists(context.Background(), update.Path)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = mirror.New(mirror.Options{Storage: storage{w}, MaxFileSize: 4}).Transfer(context.Background(), f)
+	assert.Error(t, err)
+}
+
+func TestConformance(t *testing.T) {
+	hoststest.AllowPrivateNetworks(t)
+
+	dav := newTestServer()
+	defer dav.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer origin.Close()
+
+	sql, err := hosts.ReadSQLFileFromFS(sqlFS)
+	assert.NoError(t, err)
+	p := &hosts.Properties{Name: "webdav", New: New, CreateTableStatements: sql}
+
+	viper.Set("webdav.url", dav.URL)
+	hoststest.Run(t, p, &hoststest.Options{
+		Dependencies: hoststest.MySQLDependencies(t, p),
+		Links:        []string{origin.URL + "/a.txt", origin.URL + "/b.txt"},
+	})
+}
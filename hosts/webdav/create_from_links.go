@@ -0,0 +1,55 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"context"
+	"io"
+	"path"
+
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+)
+
+// CreateShare returns the public link of the file, the fileID is the path of the file on the WebDAV server.
+func (w *WebDAV) CreateShare(_ context.Context, _ string, _ string, fileID string) (sharedLink string, err error) {
+	if err := w.checkConfigured(); err != nil {
+		return "", err
+	}
+	return w.sharedLink(path.Clean("/" + fileID)), nil
+}
+
+// CreateFromLinks create shared links based on the input original links.
+// Files are downloaded in background, the state of new links is CREATED until the download is done.
+func (w *WebDAV) CreateFromLinks(ctx context.Context, userID string, originalLinks []string, createBy string, _ string) (sharedLinks map[string]*share.Share, err error) {
+	if err := w.checkConfigured(); err != nil {
+		return nil, err
+	}
+	return w.mirror.CreateFromLinks(ctx, userID, originalLinks, createBy)
+}
+
+// storage streams the downloaded files to the WebDAV server.
+type storage struct {
+	*WebDAV
+}
+
+// Save implements mirror.Storage.
+func (s storage) Save(ctx context.Context, f *mirror.File, name string, r io.Reader, size int64) (string, string, error) {
+	dir := path.Join(s.root, f.KeepshareUserID, f.OriginalLinkHash)
+	if err := s.cli.MkdirAll(ctx, dir); err != nil {
+		return "", "", err
+	}
+
+	p := path.Join(dir, name)
+	if err := s.cli.Put(ctx, p, r, size); err != nil {
+		return "", "", err
+	}
+	return p, s.sharedLink(p), nil
+}
+
+// Remove implements mirror.Storage.
+func (s storage) Remove(ctx context.Context, p string) error {
+	return s.cli.Delete(ctx, path.Dir(p)+"/")
+}
@@ -0,0 +1,170 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/KeepShareOrg/keepshare/hosts/webdav/model"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/samber/lo"
+)
+
+// GetStatuses return the statuses of each host shared link.
+// A completed file is checked on the WebDAV server, it is DELETED if it has been removed from the share.
+func (w *WebDAV) GetStatuses(ctx context.Context, userID string, hostSharedLinks []string) (statuses map[string]share.State, err error) {
+	if err := w.checkConfigured(); err != nil {
+		return nil, err
+	}
+
+	hashToLink := make(map[string]string, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		hashToLink[lk.Hash(link)] = link
+	}
+
+	t := &w.q.File
+	stmt := t.WithContext(ctx).Where(t.SharedLinkHash.In(lo.Keys(hashToLink)...))
+	if userID != "" {
+		stmt = stmt.Where(t.KeepshareUserID.Eq(userID))
+	}
+	files, err := stmt.Find()
+	if err != nil && !gormutil.IsNotFoundError(err) {
+		return nil, fmt.Errorf("query files err: %w", err)
+	}
+
+	statuses = make(map[string]share.State, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		statuses[link] = share.StatusNotFound
+	}
+	for _, f := range files {
+		link := hashToLink[f.SharedLinkHash]
+		st := mirror.State(f.Status)
+		if st == share.StatusOK {
+			exists, err := w.cli.Exists(ctx, f.Path)
+			if err != nil {
+				return statuses, err
+			}
+			if !exists {
+				st = share.StatusDeleted
+			}
+		}
+		statuses[link] = st
+	}
+	return statuses, nil
+}
+
+// GetStatistics return the statistics of each host shared link.
+// WebDAV servers do not count visits, so all statistics are zero.
+func (w *WebDAV) GetStatistics(_ context.Context, _ string, hostSharedLinks []string) (details map[string]share.Statistics, err error) {
+	details = make(map[string]share.Statistics, len(hostSharedLinks))
+	for _, link := range hostSharedLinks {
+		details[link] = share.Statistics{}
+	}
+	return details, nil
+}
+
+// Delete delete shared links by original links.
+func (w *WebDAV) Delete(ctx context.Context, userID string, originalLinks []string) error {
+	if len(originalLinks) == 0 {
+		return nil
+	}
+	if err := w.checkConfigured(); err != nil {
+		return err
+	}
+
+	hashes := make([]string, 0, len(originalLinks))
+	for _, link := range originalLinks {
+		hashes = append(hashes, lk.Hash(link))
+	}
+
+	t := &w.q.File
+	files, err := t.WithContext(ctx).Where(t.KeepshareUserID.Eq(userID), t.OriginalLinkHash.In(hashes...)).Find()
+	if err != nil && !gormutil.IsNotFoundError(err) {
+		return fmt.Errorf("query files err: %w", err)
+	}
+
+	for _, f := range files {
+		if f.Path != "" {
+			err := w.cli.Delete(ctx, path.Dir(f.Path)+"/")
+			if err != nil && !errors.Is(err, errNotFound) {
+				return err
+			}
+		}
+		if _, err := t.WithContext(ctx).Where(t.AutoID.Eq(f.AutoID)).Delete(); err != nil {
+			return fmt.Errorf("delete file err: %w", err)
+		}
+	}
+
+	if len(files) > 0 {
+		links := lo.Map(files, func(f *model.File, _ int) string { return f.OriginalLink })
+		w.publish(ctx, hosts.ShareDeletedEvent{Host: "webdav", UserID: userID, OriginalLinks: links})
+	}
+	return nil
+}
+
+// HostInfo returns basic information of the host.
+func (w *WebDAV) HostInfo(ctx context.Context, userID string, _ map[string]any) (resp map[string]any, err error) {
+	var res struct {
+		Files int64
+		Size  int64
+	}
+	t := &w.q.File
+	err = t.WithContext(ctx).
+		Select(t.AutoID.Count().As("files"), t.Size.Sum().As("size")).
+		Where(t.KeepshareUserID.Eq(userID), t.Status.Eq(mirror.StatusOK)).
+		Scan(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"url":   w.publicURL,
+		"files": res.Files,
+		"size":  res.Size,
+	}, nil
+}
+
+// ChangeMasterAccountPassword is not supported, the credentials are configured by `webdav.username` and `webdav.password`.
+func (w *WebDAV) ChangeMasterAccountPassword(context.Context, string, string, bool) (string, error) {
+	return "", hosts.ErrNotSupported
+}
+
+// ConfirmMasterAccountPassword is not supported.
+func (w *WebDAV) ConfirmMasterAccountPassword(context.Context, string, string, bool) error {
+	return hosts.ErrNotSupported
+}
+
+// GetMasterAccountLoginStatus returns valid if the host is configured.
+func (w *WebDAV) GetMasterAccountLoginStatus(context.Context, string) (string, error) {
+	if w.checkConfigured() != nil {
+		return "invalid", nil
+	}
+	return "valid", nil
+}
+
+// AssignMasterAccount does nothing, all users share the same WebDAV account.
+func (w *WebDAV) AssignMasterAccount(context.Context, string) error {
+	return nil
+}
+
+// DonateRedeemCode is not supported.
+func (w *WebDAV) DonateRedeemCode(context.Context, string, string, []string) error {
+	return hosts.ErrNotSupported
+}
+
+// Capabilities returns what the WebDAV host supports.
+func (w *WebDAV) Capabilities() hosts.Capabilities {
+	return hosts.Capabilities{
+		LinkSchemes: []string{"http", "https", "ftp"},
+		MaxFileSize: w.maxFileSize,
+	}
+}
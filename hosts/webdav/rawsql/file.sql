@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `webdav_file`
+(
+	`auto_id`            bigint       NOT NULL AUTO_INCREMENT,
+	`keepshare_user_id`  varchar(16)  NOT NULL,
+	`original_link_hash` char(40)     NOT NULL,
+	`original_link`      text         NOT NULL,
+	`status`             varchar(32)  NOT NULL,
+	`path`               varchar(512) NOT NULL DEFAULT '',
+	`name`               varchar(256) NOT NULL DEFAULT '',
+	`size`               bigint       NOT NULL DEFAULT 0,
+	`shared_link_hash`   char(40)     NOT NULL DEFAULT '',
+	`shared_link`        text         NOT NULL,
+	`created_at`         datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`updated_at`         datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`error`              text,
+	PRIMARY KEY (`auto_id`),
+	UNIQUE KEY (`keepshare_user_id`, `original_link_hash`),
+	KEY (`shared_link_hash`),
+	KEY (`status`, `updated_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
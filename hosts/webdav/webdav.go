@@ -0,0 +1,105 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package webdav
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/mirror"
+	"github.com/KeepShareOrg/keepshare/hosts/webdav/model"
+	"github.com/KeepShareOrg/keepshare/hosts/webdav/query"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/spf13/viper"
+)
+
+var errNotConfigured = errors.New("webdav.url is not configured")
+
+// WebDAV stores the originals to a WebDAV share, any server implementing RFC 4918 can be used.
+type WebDAV struct {
+	*hosts.Dependencies
+
+	q      *query.Query
+	cli    *client
+	mirror *mirror.Mirror
+
+	root        string
+	publicURL   string
+	maxFileSize int64
+}
+
+//go:embed  rawsql/*.sql
+var sqlFS embed.FS
+
+func init() {
+	sql, err := hosts.ReadSQLFileFromFS(sqlFS)
+	if err != nil {
+		panic(fmt.Errorf("read sql files err: %w", err))
+	}
+
+	hosts.Register(&hosts.Properties{Name: "webdav", New: New, CreateTableStatements: sql, RehashLinks: mirror.RehashLinks(model.TableNameFile)})
+}
+
+// New create a WebDAV host.
+func New(d *hosts.Dependencies) hosts.Host {
+	viper.SetDefault("webdav.root", "/keepshare")
+	viper.SetDefault("webdav.max_concurrent_downloads", 4)
+	viper.SetDefault("webdav.download_timeout", 6*time.Hour)
+
+	endpoint := viper.GetString("webdav.url")
+	w := &WebDAV{
+		Dependencies: d,
+		q:            query.Use(d.Mysql),
+		cli:          newClient(endpoint, viper.GetString("webdav.username"), viper.GetString("webdav.password")),
+		root:         path.Clean("/" + viper.GetString("webdav.root")),
+		publicURL:    strings.TrimRight(viper.GetString("webdav.public_url"), "/"),
+		maxFileSize:  viper.GetInt64("webdav.max_file_size"),
+	}
+	w.mirror = mirror.New(mirror.Options{
+		Host:                   "webdav",
+		Table:                  model.TableNameFile,
+		DB:                     d.Mysql,
+		Events:                 d.Events,
+		Storage:                storage{w},
+		MaxFileSize:            w.maxFileSize,
+		DownloadTimeout:        viper.GetDuration("webdav.download_timeout"),
+		MaxConcurrentDownloads: viper.GetInt("webdav.max_concurrent_downloads"),
+	})
+	if w.publicURL == "" {
+		w.publicURL = w.cli.endpoint
+	}
+	if endpoint == "" {
+		log.Warn("webdav.url is not configured, the webdav host is disabled")
+	}
+
+	return w
+}
+
+func (w *WebDAV) checkConfigured() error {
+	if w.cli.endpoint == "" {
+		return errNotConfigured
+	}
+	return nil
+}
+
+// sharedLink returns the public link of the file path.
+func (w *WebDAV) sharedLink(p string) string {
+	u := &url.URL{Path: p}
+	return w.publicURL + u.EscapedPath()
+}
+
+// publish publishes the event of the host, errors are logged only.
+func (w *WebDAV) publish(ctx context.Context, e hosts.Event) {
+	if err := w.Events.Publish(ctx, e); err != nil {
+		log.WithContext(ctx).WithField("event", e).Errorf("publish event err: %v", err)
+	}
+}
@@ -7,7 +7,9 @@ package main
 import (
 	"github.com/KeepShareOrg/keepshare/cmd"
 
+	_ "github.com/KeepShareOrg/keepshare/hosts/local"  // register Local host
 	_ "github.com/KeepShareOrg/keepshare/hosts/pikpak" // register PikPak host
+	_ "github.com/KeepShareOrg/keepshare/hosts/webdav" // register WebDAV host
 )
 
 func main() {
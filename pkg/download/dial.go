@@ -0,0 +1,62 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a link resolves to an address which is not public,
+// such as loopback, private and link-local addresses, so that links can not reach internal services.
+var ErrForbiddenAddress = errors.New("forbidden address")
+
+// AllowPrivateNetworks allows downloading from addresses which are not public, it is for tests only.
+var AllowPrivateNetworks = false
+
+// permitted reports whether the ip can be connected.
+var permitted = func(ip net.IP) bool {
+	return AllowPrivateNetworks || publicIP(ip)
+}
+
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// checkAddress is the Control of dialers, it checks the resolved address of each connection,
+// including the ones of redirects, FTP data connections and names resolved to other addresses later.
+func checkAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !permitted(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+var dialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: 30 * time.Second,
+	Control:   checkAddress,
+}
+
+// httpClient does not use proxies of the environment, since the addresses connected by proxies can not be checked.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	},
+}
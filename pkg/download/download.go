@@ -0,0 +1,133 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package download fetches the content of http, https and ftp original links.
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrUnsupportedScheme is returned when the scheme of the link can not be downloaded.
+var ErrUnsupportedScheme = errors.New("unsupported link scheme")
+
+// File is an opened remote file, the caller must close it after reading.
+type File struct {
+	io.ReadCloser
+	// Name is the file name, from the response header or the last element of the link path.
+	Name string
+	// Size is the content length, -1 means unknown.
+	Size int64
+}
+
+// Supported returns whether the link can be downloaded by Open.
+func Supported(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "ftp":
+		return u.Host != ""
+	default:
+		return false
+	}
+}
+
+// Open starts downloading the link and returns the file content as a stream.
+func Open(ctx context.Context, link string) (*File, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("parse link err: %w", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return openHTTP(ctx, u)
+	case "ftp":
+		return openFTP(ctx, u)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, u.Scheme)
+	}
+}
+
+func openHTTP(ctx context.Context, u *url.URL) (*File, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	name := ""
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+	if name == "" {
+		name = nameFromPath(u.Path)
+	}
+
+	return &File{ReadCloser: resp.Body, Name: name, Size: resp.ContentLength}, nil
+}
+
+func nameFromPath(p string) string {
+	name := path.Base(p)
+	if name == "." || name == "/" {
+		return "index"
+	}
+	return name
+}
+
+// SafeName returns a file name which is safe to be used as a single path element,
+// the fallback is returned if nothing is left.
+func SafeName(name, fallback string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '\\' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	name = strings.ToValidUTF8(name, "")
+	if name == "" || name == "." || name == ".." {
+		return fallback
+	}
+	return name
+}
+
+// CountingReader counts the bytes read and fails if more than Limit bytes are read.
+type CountingReader struct {
+	R     io.Reader
+	N     int64
+	Limit int64 // no limit if it is not greater than 0.
+	Err   error // error caused by the limit.
+}
+
+// Read implements io.Reader.
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.R.Read(p)
+	c.N += int64(n)
+	if c.Limit > 0 && c.N > c.Limit {
+		c.Err = fmt.Errorf("file size exceeds the limit %d", c.Limit)
+		return n, c.Err
+	}
+	return n, err
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// openFTP retrieves a file in passive mode, anonymous login is used if no user info in the link.
+func openFTP(ctx context.Context, u *url.URL) (f *File, err error) {
+	user, pass := "anonymous", "anonymous@"
+	if u.User != nil {
+		user = u.User.Username()
+		if p, ok := u.User.Password(); ok {
+			pass = p
+		}
+	}
+	// the arguments are decoded, line breaks in them would inject other commands.
+	if strings.ContainsAny(user+pass+u.Path, "\r\n") {
+		return nil, errors.New("ftp link contains line breaks")
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "21"
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c := textproto.NewConn(conn)
+	defer func() {
+		if err != nil {
+			_ = c.Close()
+		}
+	}()
+
+	if _, _, err = c.ReadResponse(220); err != nil {
+		return nil, fmt.Errorf("ftp greeting err: %w", err)
+	}
+
+	code, _, err := ftpCmd(c, 0, "USER %s", user)
+	if err != nil {
+		return nil, fmt.Errorf("ftp login err: %w", err)
+	}
+	switch code {
+	case 230:
+	case 331, 332:
+		if _, _, err = ftpCmd(c, 230, "PASS %s", pass); err != nil {
+			return nil, fmt.Errorf("ftp login err: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("ftp login err: unexpected code %d", code)
+	}
+
+	if _, _, err = ftpCmd(c, 200, "TYPE I"); err != nil {
+		return nil, fmt.Errorf("ftp set binary mode err: %w", err)
+	}
+
+	var size int64 = -1
+	if _, msg, err := ftpCmd(c, 213, "SIZE %s", u.Path); err == nil {
+		if n, err := strconv.ParseInt(strings.TrimSpace(msg), 10, 64); err == nil {
+			size = n
+		}
+	}
+
+	dataPort, err := ftpPassivePort(c)
+	if err != nil {
+		return nil, err
+	}
+	data, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(dataPort)))
+	if err != nil {
+		return nil, fmt.Errorf("ftp dial data connection err: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = data.SetDeadline(deadline)
+	}
+
+	if _, _, err = ftpCmd(c, 1, "RETR %s", u.Path); err != nil {
+		_ = data.Close()
+		return nil, fmt.Errorf("ftp retrieve err: %w", err)
+	}
+
+	return &File{
+		ReadCloser: &ftpReader{Conn: data, ctrl: c},
+		Name:       nameFromPath(u.Path),
+		Size:       size,
+	}, nil
+}
+
+func ftpCmd(c *textproto.Conn, expectCode int, format string, args ...any) (int, string, error) {
+	id, err := c.Cmd(format, args...)
+	if err != nil {
+		return 0, "", err
+	}
+	c.StartResponse(id)
+	defer c.EndResponse(id)
+	return c.ReadResponse(expectCode)
+}
+
+// ftpPassivePort tries EPSV first and falls back to PASV.
+// The data connection always uses the host of the control connection to work well behind NAT.
+func ftpPassivePort(c *textproto.Conn) (int, error) {
+	if _, msg, err := ftpCmd(c, 229, "EPSV"); err == nil {
+		// 229 Entering Extended Passive Mode (|||6446|)
+		start, end := strings.Index(msg, "(|||"), strings.LastIndex(msg, "|)")
+		if start >= 0 && end > start+4 {
+			if port, err := strconv.Atoi(msg[start+4 : end]); err == nil {
+				return port, nil
+			}
+		}
+	}
+
+	_, msg, err := ftpCmd(c, 227, "PASV")
+	if err != nil {
+		return 0, fmt.Errorf("ftp passive mode err: %w", err)
+	}
+	// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2).
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end <= start {
+		return 0, fmt.Errorf("ftp passive mode err: unexpected response %q", msg)
+	}
+	parts := strings.Split(msg[start+1:end], ",")
+	if len(parts) != 6 {
+		return 0, fmt.Errorf("ftp passive mode err: unexpected response %q", msg)
+	}
+	p1, err1 := strconv.Atoi(strings.TrimSpace(parts[4]))
+	p2, err2 := strconv.Atoi(strings.TrimSpace(parts[5]))
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("ftp passive mode err: unexpected response %q", msg)
+	}
+	return p1<<8 | p2, nil
+}
+
+type ftpReader struct {
+	net.Conn
+	ctrl *textproto.Conn
+}
+
+// Close closes the data connection, waits for the transfer result and then quits.
+func (r *ftpReader) Close() error {
+	err := r.Conn.Close()
+	if _, _, e := r.ctrl.ReadResponse(2); e != nil && err == nil {
+		err = e
+	}
+	_, _, _ = ftpCmd(r.ctrl, 221, "QUIT")
+	_ = r.ctrl.Close()
+	return err
+}
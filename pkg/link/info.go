@@ -0,0 +1,211 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package link
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Info is the metadata of the content of a link.
+type Info struct {
+	// Name is the name of the file or directory, it is empty if unknown.
+	Name string `json:"name"`
+	// Size is the total size in bytes, it is 0 if unknown.
+	Size int64 `json:"size"`
+	// Trackers are the trackers of magnet links.
+	Trackers []string `json:"trackers,omitempty"`
+}
+
+// complete reports whether both the name and the size are known.
+func (i *Info) complete() bool {
+	return i.Name != "" && i.Size > 0
+}
+
+// ErrNoInfo is returned if the provider knows nothing about the link.
+var ErrNoInfo = errors.New("no info of the link")
+
+// LinkInfoProvider provides metadata of links.
+type LinkInfoProvider interface {
+	LinkInfo(ctx context.Context, link string) (*Info, error)
+}
+
+// LinkInfoFunc is an adapter to use functions as LinkInfoProvider.
+type LinkInfoFunc func(ctx context.Context, link string) (*Info, error)
+
+// LinkInfo calls f(ctx, link).
+func (f LinkInfoFunc) LinkInfo(ctx context.Context, link string) (*Info, error) {
+	return f(ctx, link)
+}
+
+// Local returns the provider which reads metadata from links without any requests,
+// such as `dn` (display name), `xl` (exact length) and `tr` (trackers) of magnet links, and names and sizes of ed2k links.
+func Local() LinkInfoProvider {
+	return LinkInfoFunc(localInfo)
+}
+
+func localInfo(_ context.Context, link string) (*Info, error) {
+	link = strings.TrimSpace(link)
+	if hasPrefixFold(link, ThunderPrefix) {
+		if inner, ok := decodeThunder(link); ok {
+			link = strings.TrimSpace(inner)
+		}
+	}
+
+	info := &Info{}
+	switch {
+	case isMagnet(link):
+		q, _ := url.ParseQuery(link[len(MagnetPrefix):])
+		info.Name = strings.ToValidUTF8(q.Get("dn"), "")
+		info.Size, _ = strconv.ParseInt(q.Get("xl"), 10, 64)
+		info.Trackers = q["tr"]
+
+	case hasPrefixFold(link, Ed2kPrefix):
+		name, size, _, ok := parseEd2k(link)
+		if !ok {
+			return nil, ErrNoInfo
+		}
+		info.Name, _ = url.PathUnescape(name)
+		info.Size, _ = strconv.ParseInt(size, 10, 64)
+	}
+
+	if info.Name == "" && info.Size <= 0 {
+		return nil, ErrNoInfo
+	}
+	return info, nil
+}
+
+// WhatsLinkEndpoint is the default endpoint of WhatsLink.
+const WhatsLinkEndpoint = "https://whatslink.info/api/v1/link"
+
+// WhatsLink returns the provider which queries metadata from the whatslink.info api.
+// The endpoint defaults to WhatsLinkEndpoint, and the client defaults to a client with 10 seconds timeout.
+func WhatsLink(endpoint string, client *http.Client) LinkInfoProvider {
+	if endpoint == "" {
+		endpoint = WhatsLinkEndpoint
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &whatsLink{endpoint: endpoint, client: client}
+}
+
+type whatsLink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func (w *whatsLink) LinkInfo(ctx context.Context, link string) (*Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.endpoint+"?url="+url.QueryEscape(link), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("referer", "https://whatslink.info/")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var r struct {
+		Error string `json:"error"`
+		Name  string `json:"name"`
+		Size  int64  `json:"size"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("decode whatslink response err: %w", err)
+	}
+	if r.Error != "" {
+		return nil, fmt.Errorf("whatslink error: %s", r.Error)
+	}
+	if r.Name == "" && r.Size <= 0 {
+		return nil, ErrNoInfo
+	}
+	return &Info{Name: r.Name, Size: r.Size}, nil
+}
+
+// Cache caches the metadata got from the provider in redis, keyed by the hash of links.
+// Errors are not cached, and redis errors are ignored.
+func Cache(p LinkInfoProvider, rdb *redis.Client, ttl time.Duration) LinkInfoProvider {
+	return LinkInfoFunc(func(ctx context.Context, link string) (*Info, error) {
+		key := "link_info:" + Hash(link)
+		if b, err := rdb.Get(ctx, key).Bytes(); err == nil {
+			info := &Info{}
+			if json.Unmarshal(b, info) == nil {
+				return info, nil
+			}
+		}
+
+		info, err := p.LinkInfo(ctx, link)
+		if err != nil {
+			return nil, err
+		}
+		if b, err := json.Marshal(info); err == nil {
+			_ = rdb.Set(ctx, key, b, ttl).Err()
+		}
+		return info, nil
+	})
+}
+
+// Chain queries the providers in order until both the name and the size are known,
+// the missing fields of the former providers are filled by the latter ones.
+// Errors of providers are ignored if any provider knows the link, otherwise they are joined.
+func Chain(providers ...LinkInfoProvider) LinkInfoProvider {
+	return LinkInfoFunc(func(ctx context.Context, link string) (*Info, error) {
+		var (
+			info *Info
+			errs []error
+		)
+		for _, p := range providers {
+			got, err := p.LinkInfo(ctx, link)
+			if err != nil {
+				if !errors.Is(err, ErrNoInfo) {
+					errs = append(errs, err)
+				}
+				continue
+			}
+			if info == nil {
+				c := *got
+				info = &c
+			} else {
+				if info.Name == "" {
+					info.Name = got.Name
+				}
+				if info.Size <= 0 {
+					info.Size = got.Size
+				}
+				if len(info.Trackers) == 0 {
+					info.Trackers = got.Trackers
+				}
+			}
+			if info.complete() {
+				break
+			}
+		}
+
+		if info != nil {
+			return info, nil
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return nil, ErrNoInfo
+	})
+}
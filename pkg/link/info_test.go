@@ -0,0 +1,123 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package link
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want *Info
+	}{
+		{
+			name: "magnet",
+			link: "magnet:?xt=urn:btih:" + infoHash + "&dn=a%20b.mp4&xl=1024&tr=udp%3A%2F%2Ft1&tr=udp://t2",
+			want: &Info{Name: "a b.mp4", Size: 1024, Trackers: []string{"udp://t1", "udp://t2"}},
+		},
+		{
+			name: "magnet name only",
+			link: "magnet:?xt=urn:btih:" + infoHash + "&dn=a",
+			want: &Info{Name: "a"},
+		},
+		{
+			name: "magnet invalid size",
+			link: "magnet:?xt=urn:btih:" + infoHash + "&xl=abc",
+		},
+		{
+			name: "magnet without metadata",
+			link: "magnet:?xt=urn:btih:" + infoHash,
+		},
+		{
+			name: "ed2k",
+			link: "ed2k://|file|a%20b.mp4|1024|" + ed2kHash + "|/",
+			want: &Info{Name: "a b.mp4", Size: 1024},
+		},
+		{
+			name: "thunder magnet",
+			link: "thunder://QUFtYWduZXQ6P3h0PXVybjpidGloOjNFNUYwRUYxMjQwRkY4OTA2MjgxOTU1NjY2RTczRDdGOEExNEU0MkImZG49YVpa/",
+			want: &Info{Name: "a"},
+		},
+		{
+			name: "http",
+			link: "http://example.com/a.mp4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Local().LinkInfo(context.Background(), tt.link)
+			if tt.want == nil {
+				assert.ErrorIs(t, err, ErrNoInfo)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, info)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	provider := func(info *Info, err error) LinkInfoProvider {
+		return LinkInfoFunc(func(context.Context, string) (*Info, error) {
+			calls++
+			return info, err
+		})
+	}
+	errRemote := errors.New("remote error")
+
+	calls = 0
+	info, err := Chain(provider(&Info{Name: "a", Size: 1}, nil), provider(&Info{Name: "b", Size: 2}, nil)).LinkInfo(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, &Info{Name: "a", Size: 1}, info)
+	assert.Equal(t, 1, calls, "stop when complete")
+
+	info, err = Chain(provider(&Info{Name: "a"}, nil), provider(nil, errRemote), provider(&Info{Name: "b", Size: 2}, nil)).LinkInfo(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, &Info{Name: "a", Size: 2}, info, "fill missing fields")
+
+	_, err = Chain(provider(nil, ErrNoInfo), provider(nil, errRemote)).LinkInfo(ctx, "")
+	assert.ErrorIs(t, err, errRemote)
+
+	_, err = Chain(provider(nil, ErrNoInfo)).LinkInfo(ctx, "")
+	assert.ErrorIs(t, err, ErrNoInfo)
+}
+
+func TestWhatsLink(t *testing.T) {
+	link := "magnet:?xt=urn:btih:" + infoHash + "&dn=a"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("url") {
+		case link:
+			_, _ = w.Write([]byte(`{"error":"","type":"FOLDER","name":"a","size":1024,"count":2}`))
+		case "error":
+			_, _ = w.Write([]byte(`{"error":"invalid link"}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	p := WhatsLink(srv.URL, srv.Client())
+	info, err := p.LinkInfo(context.Background(), link)
+	require.NoError(t, err)
+	assert.Equal(t, &Info{Name: "a", Size: 1024}, info)
+
+	_, err = p.LinkInfo(context.Background(), "error")
+	assert.ErrorContains(t, err, "invalid link")
+
+	_, err = p.LinkInfo(context.Background(), "other")
+	assert.ErrorContains(t, err, "502")
+}
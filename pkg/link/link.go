@@ -6,6 +6,9 @@ package link
 
 import (
 	"crypto/sha1"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -15,10 +18,24 @@ import (
 const (
 	// MagnetPrefix is the prefix of magnet links.
 	MagnetPrefix = "magnet:?"
+	// Ed2kPrefix is the prefix of ed2k file links.
+	Ed2kPrefix = "ed2k://|file|"
+	// ThunderPrefix is the prefix of thunder links, which wrap other links.
+	ThunderPrefix = "thunder://"
 )
 
+// Prefixes of exact topics in magnet links.
+const (
+	btih = "urn:btih:" // BitTorrent v1 info hash
+	btmh = "urn:btmh:" // BitTorrent v2 info hash in multihash format
+)
+
+// sha256Multihash is the multihash prefix of sha2-256 digests: 0x12 is the hash function code, 0x20 is the digest length.
+const sha256Multihash = "1220"
+
 // Simplify this link and remove useless content.
-// Keep only the xt parameter of the magnet link.
+// Keep only the xt parameter of the magnet link, v1 info hashes are preferred and converted to lower case hex.
+// Thunder links are decoded to their inner links, ed2k links keep only the name, size and hash.
 func Simplify(raw string) (simple string) {
 	defer func() {
 		simple = strings.ToValidUTF8(simple, "")
@@ -26,14 +43,26 @@ func Simplify(raw string) (simple string) {
 
 	raw = strings.TrimSpace(raw)
 	switch {
+	case hasPrefixFold(raw, ThunderPrefix):
+		if inner, ok := decodeThunder(raw); ok {
+			return Simplify(inner)
+		}
+		return raw
+
 	case isMagnet(raw):
-		v, _ := url.ParseQuery(raw[len(MagnetPrefix):])
-		xt := strings.ToLower(v.Get("xt"))
+		xt := exactTopic(raw)
 		if xt == "" {
 			return raw
 		}
 		return MagnetPrefix + "xt=" + xt
 
+	case hasPrefixFold(raw, Ed2kPrefix):
+		name, size, hash, ok := parseEd2k(raw)
+		if !ok {
+			return raw
+		}
+		return fmt.Sprintf("%s%s|%s|%s|/", Ed2kPrefix, name, size, hash)
+
 	default:
 		return raw
 	}
@@ -41,23 +70,111 @@ func Simplify(raw string) (simple string) {
 
 var infoHashPattern = regexp.MustCompile("^[0-9a-zA-Z]+")
 
-// Hash the magnet link's hash is infoHash, other link's hash is the sha1 hash of the link, in lowercase
+// Hash the magnet link's hash is infoHash, other link's hash is the sha1 hash of the link, in lowercase.
+// The info hash is the v1 info hash in hex, or the v2 info hash truncated to 20 bytes if there is no v1 info hash.
+// The hash of ed2k links is the ed2k hash, and thunder links are hashed as their inner links.
+// HTTP(S) and FTP links are hashed in the form of CanonicalURL, so the same file has the same hash.
 func Hash(link string) string {
+	if hasPrefixFold(link, ThunderPrefix) {
+		if inner, ok := decodeThunder(strings.TrimSpace(link)); ok {
+			return Hash(strings.TrimSpace(inner))
+		}
+	}
+
 	if isMagnet(link) {
-		q, _ := url.ParseQuery(link[len(MagnetPrefix):])
-		xt := strings.ToLower(q.Get("xt"))
-		h := strings.TrimPrefix(xt, "urn:btih:")
-		h = infoHashPattern.FindString(h)
+		xt := exactTopic(link)
+		switch {
+		case strings.HasPrefix(xt, btmh):
+			xt = strings.TrimPrefix(xt[len(btmh):], sha256Multihash)
+		case strings.HasPrefix(xt, "urn:"):
+			if _, v, found := strings.Cut(xt[len("urn:"):], ":"); found {
+				xt = v
+			}
+		}
+		h := infoHashPattern.FindString(xt)
 		if len(h) > 40 {
 			h = h[:40]
 		}
 		return strings.ToLower(h)
 	}
 
-	h := sha1.Sum([]byte(link))
+	if hasPrefixFold(link, Ed2kPrefix) {
+		if _, _, hash, ok := parseEd2k(link); ok {
+			return hash
+		}
+	}
+
+	h := sha1.Sum([]byte(CanonicalURL(link)))
 	return fmt.Sprintf("%x", h)
 }
 
 func isMagnet(link string) bool {
 	return len(link) > len(MagnetPrefix) && strings.EqualFold(link[:len(MagnetPrefix)], MagnetPrefix)
 }
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// exactTopic returns the canonical exact topic of the magnet link in lower case.
+// The v1 info hash is preferred, base32 info hashes are converted to hex.
+func exactTopic(magnet string) string {
+	q, _ := url.ParseQuery(magnet[len(MagnetPrefix):])
+	topics := q["xt"]
+	for _, xt := range topics {
+		if hasPrefixFold(xt, btih) {
+			return btih + canonicalInfoHash(xt[len(btih):])
+		}
+	}
+	for _, xt := range topics {
+		if hasPrefixFold(xt, btmh) {
+			return strings.ToLower(xt)
+		}
+	}
+	if len(topics) == 0 {
+		return ""
+	}
+	return strings.ToLower(topics[0])
+}
+
+// canonicalInfoHash converts a base32 info hash to lower case hex.
+func canonicalInfoHash(h string) string {
+	if len(h) == 32 {
+		if b, err := base32.StdEncoding.DecodeString(strings.ToUpper(h)); err == nil {
+			return hex.EncodeToString(b)
+		}
+	}
+	return strings.ToLower(h)
+}
+
+var ed2kHashPattern = regexp.MustCompile("^[0-9a-fA-F]{32}$")
+
+// parseEd2k parses a link in the format `ed2k://|file|<name>|<size>|<hash>|...`, the hash is in lower case.
+func parseEd2k(link string) (name, size, hash string, ok bool) {
+	parts := strings.Split(link[len(Ed2kPrefix):], "|")
+	if len(parts) < 3 || parts[0] == "" || !ed2kHashPattern.MatchString(parts[2]) {
+		return "", "", "", false
+	}
+	for _, c := range parts[1] {
+		if c < '0' || c > '9' {
+			return "", "", "", false
+		}
+	}
+	return parts[0], parts[1], strings.ToLower(parts[2]), parts[1] != ""
+}
+
+// decodeThunder decodes a link in the format `thunder://base64("AA" + link + "ZZ")`.
+func decodeThunder(link string) (string, bool) {
+	encoded := link[len(ThunderPrefix):]
+	// some thunder links are suffixed with a slash, which is also a base64 character.
+	for _, e := range []string{encoded, strings.TrimSuffix(encoded, "/")} {
+		b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(e, "="))
+		if err != nil {
+			continue
+		}
+		if s := string(b); len(s) > 4 && strings.HasPrefix(s, "AA") && strings.HasSuffix(s, "ZZ") {
+			return s[2 : len(s)-2], true
+		}
+	}
+	return "", false
+}
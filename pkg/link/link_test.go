@@ -0,0 +1,124 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package link
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	infoHash = "3e5f0ef1240ff8906281955666e73d7f8a14e42b"
+	v2Hash   = "2496a9f64528aac9959b1a1f7aaf6f5de42e9d101f044e76dea499d6472e4dbd"
+	ed2kHash = "31d6cfe0d16ae931b73c59d7e0c089c0"
+)
+
+func TestSimplifyAndHash(t *testing.T) {
+	tests := []struct {
+		name   string
+		link   string
+		simple string
+		hash   string
+	}{
+		{
+			name:   "magnet hex",
+			link:   " magnet:?xt=urn:btih:3E5F0EF1240FF8906281955666E73D7F8A14E42B&dn=a&tr=udp://tracker ",
+			simple: "magnet:?xt=urn:btih:" + infoHash,
+			hash:   infoHash,
+		},
+		{
+			name:   "magnet base32",
+			link:   "magnet:?xt=urn:btih:HZPQ54JEB74JAYUBSVLGNZZ5P6FBJZBL&dn=a",
+			simple: "magnet:?xt=urn:btih:" + infoHash,
+			hash:   infoHash,
+		},
+		{
+			name:   "magnet lower case base32",
+			link:   "MAGNET:?xt=urn:btih:hzpq54jeb74jayubsvlgnzz5p6fbjzbl",
+			simple: "magnet:?xt=urn:btih:" + infoHash,
+			hash:   infoHash,
+		},
+		{
+			name:   "magnet hybrid prefers v1",
+			link:   "magnet:?xt=urn:btmh:1220" + v2Hash + "&xt=urn:btih:" + infoHash,
+			simple: "magnet:?xt=urn:btih:" + infoHash,
+			hash:   infoHash,
+		},
+		{
+			name:   "magnet v2",
+			link:   "magnet:?xt=urn:btmh:1220" + v2Hash + "&dn=a",
+			simple: "magnet:?xt=urn:btmh:1220" + v2Hash,
+			hash:   v2Hash[:40],
+		},
+		{
+			name:   "magnet other urn",
+			link:   "magnet:?xt=urn:sha1:ABCDEF&dn=a",
+			simple: "magnet:?xt=urn:sha1:abcdef",
+			hash:   "abcdef",
+		},
+		{
+			name:   "magnet without xt",
+			link:   "magnet:?dn=a",
+			simple: "magnet:?dn=a",
+			hash:   "",
+		},
+		{
+			name:   "ed2k",
+			link:   "ed2k://|file|a%20b.mp4|1024|31D6CFE0D16AE931B73C59D7E0C089C0|h=ABC|/",
+			simple: "ed2k://|file|a%20b.mp4|1024|" + ed2kHash + "|/",
+			hash:   ed2kHash,
+		},
+		{
+			name:   "ed2k invalid hash",
+			link:   "ed2k://|file|a.mp4|1024|xyz|/",
+			simple: "ed2k://|file|a.mp4|1024|xyz|/",
+			hash:   "50a0b31b5c19130861d291da382011145ba5440b",
+		},
+		{
+			name:   "thunder http",
+			link:   "thunder://QUFodHRwOi8vZXhhbXBsZS5jb20vYS5tcDRaWg==",
+			simple: "http://example.com/a.mp4",
+			hash:   "cffc433b64f3a965f2921c817ee017c2dcae185d",
+		},
+		{
+			name:   "thunder magnet without padding",
+			link:   "THUNDER://QUFtYWduZXQ6P3h0PXVybjpidGloOjNFNUYwRUYxMjQwRkY4OTA2MjgxOTU1NjY2RTczRDdGOEExNEU0MkImZG49YVpa/",
+			simple: "magnet:?xt=urn:btih:" + infoHash,
+			hash:   infoHash,
+		},
+		{
+			name:   "thunder invalid",
+			link:   "thunder://invalid",
+			simple: "thunder://invalid",
+			hash:   "e8f935f84c2a34f4a4713d147952b3c253a77f73",
+		},
+		{
+			name:   "http",
+			link:   "http://example.com/a.mp4",
+			simple: "http://example.com/a.mp4",
+			hash:   "cffc433b64f3a965f2921c817ee017c2dcae185d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.simple, Simplify(tt.link))
+			assert.Equal(t, tt.hash, Hash(Simplify(tt.link)))
+		})
+	}
+}
+
+func TestHashRawLinks(t *testing.T) {
+	// hashes of raw links equal to hashes of simplified links, so that duplicated links are detected.
+	for _, link := range []string{
+		"magnet:?xt=urn:btih:HZPQ54JEB74JAYUBSVLGNZZ5P6FBJZBL&dn=a",
+		"magnet:?xt=urn:btmh:1220" + v2Hash + "&xt=urn:btih:" + infoHash,
+		"ed2k://|file|a.mp4|1024|31D6CFE0D16AE931B73C59D7E0C089C0|/",
+		"thunder://QUFodHRwOi8vZXhhbXBsZS5jb20vYS5tcDRaWg==",
+	} {
+		assert.Equal(t, Hash(Simplify(link)), Hash(link), link)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package link
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultStripParams are the query parameters removed from URLs by default, they only track visitors.
+var DefaultStripParams = []string{"utm_*", "fbclid", "gclid"}
+
+var stripParams atomic.Pointer[[]string]
+
+func init() {
+	SetStripParams(DefaultStripParams...)
+}
+
+// SetStripParams sets the query parameters removed by CanonicalURL, case-insensitive.
+// A parameter ends with `*` matches all parameters with the prefix, e.g. `utm_*`.
+func SetStripParams(params ...string) {
+	list := make([]string, 0, len(params))
+	for _, p := range params {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			list = append(list, p)
+		}
+	}
+	stripParams.Store(&list)
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// CanonicalURL returns the canonical form of HTTP(S) and FTP links, other links are returned as is.
+// The host is in lower case without the default port, trailing slashes of the path and the fragment are removed,
+// query parameters are sorted by key and the parameters of SetStripParams are removed.
+func CanonicalURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return link
+	}
+	port, ok := defaultPorts[u.Scheme]
+	if !ok {
+		return link
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if p := u.Port(); p != "" && p != port {
+		host = net.JoinHostPort(host, p)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6
+	}
+
+	c := &url.URL{
+		Scheme:  u.Scheme,
+		User:    u.User,
+		Host:    host,
+		RawPath: strings.TrimRight(u.EscapedPath(), "/"),
+	}
+	c.Path, _ = url.PathUnescape(c.RawPath)
+
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if isStripParam(k) {
+				delete(q, k)
+			}
+		}
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+func isStripParam(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range *stripParams.Load() {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == p {
+			return true
+		}
+	}
+	return false
+}
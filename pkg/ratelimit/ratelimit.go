@@ -0,0 +1,148 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package ratelimit implements token buckets in redis.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit is the limit of a token bucket, the bucket holds up to Burst tokens which are refilled evenly in Period.
+// The zero Limit means unlimited.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Unlimited reports whether the limit is unlimited.
+func (l Limit) Unlimited() bool {
+	return l.Burst <= 0 || l.Period <= 0
+}
+
+// String returns the limit in the format of ParseLimit.
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, l.Period)
+}
+
+// ParseLimit parses limits in the format `<burst>/<period>`, such as `60/1m`, the period defaults to 1s.
+// Empty strings and `0` mean unlimited.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return Limit{}, nil
+	}
+
+	burst, period, found := strings.Cut(s, "/")
+	n, err := strconv.Atoi(strings.TrimSpace(burst))
+	if err != nil || n < 0 {
+		return Limit{}, fmt.Errorf("invalid burst of limit %q", s)
+	}
+	l := Limit{Burst: n, Period: time.Second}
+	if found {
+		period = strings.TrimSpace(period)
+		if period != "" && (period[0] < '0' || period[0] > '9') {
+			period = "1" + period // such as `60/m`
+		}
+		if l.Period, err = time.ParseDuration(period); err != nil || l.Period < time.Millisecond {
+			return Limit{}, fmt.Errorf("invalid period of limit %q", s)
+		}
+	}
+	return l, nil
+}
+
+// Bucket is a token bucket identified by the key.
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+// Result of taking tokens.
+type Result struct {
+	// Allowed is true if a token is taken from every bucket.
+	Allowed bool
+	// Denied is the key of the first bucket without tokens.
+	Denied string
+	// RetryAfter is the duration to wait until the denied bucket has a token.
+	RetryAfter time.Duration
+}
+
+// Limiter takes tokens from buckets stored in redis.
+type Limiter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// New returns a limiter, keys of buckets are prefixed with the prefix in redis.
+func New(rdb *redis.Client, prefix string) *Limiter {
+	return &Limiter{rdb: rdb, prefix: prefix}
+}
+
+// script takes a token from every bucket atomically if all of them have tokens, otherwise nothing is taken.
+// KEYS are the buckets, ARGV are the current time in milliseconds, and the burst and the period in milliseconds of each bucket.
+// It returns the index of the first denied bucket starting from 1, or 0 if allowed, and the milliseconds to retry.
+var script = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local tokens = {}
+for i, key in ipairs(KEYS) do
+	local burst = tonumber(ARGV[i*2])
+	local rate = burst / tonumber(ARGV[i*2+1])
+	local b = redis.call('HMGET', key, 'tokens', 'ts')
+	local n = tonumber(b[1]) or burst
+	local ts = tonumber(b[2]) or now
+	n = math.min(burst, n + math.max(0, now - ts) * rate)
+	if n < 1 then
+		return {i, math.ceil((1 - n) / rate)}
+	end
+	tokens[i] = n
+end
+for i, key in ipairs(KEYS) do
+	local period = tonumber(ARGV[i*2+1])
+	redis.call('HSET', key, 'tokens', tostring(tokens[i] - 1), 'ts', now)
+	redis.call('PEXPIRE', key, period)
+end
+return {0, 0}
+`)
+
+// Allow takes a token from every bucket if all of them have tokens, unlimited buckets are ignored.
+func (l *Limiter) Allow(ctx context.Context, buckets ...Bucket) (*Result, error) {
+	var (
+		keys []string
+		args = []any{time.Now().UnixMilli()}
+	)
+	for _, b := range buckets {
+		if b.Limit.Unlimited() {
+			continue
+		}
+		keys = append(keys, l.prefix+b.Key)
+		args = append(args, b.Limit.Burst, b.Limit.Period.Milliseconds())
+	}
+	if len(keys) == 0 {
+		return &Result{Allowed: true}, nil
+	}
+
+	r, err := script.Run(ctx, l.rdb, keys, args...).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("run rate limit script err: %w", err)
+	}
+	if len(r) != 2 || r[0] < 0 || int(r[0]) > len(keys) {
+		return nil, fmt.Errorf("unexpected rate limit result: %v", r)
+	}
+	if r[0] == 0 {
+		return &Result{Allowed: true}, nil
+	}
+	return &Result{
+		Denied:     strings.TrimPrefix(keys[r[0]-1], l.prefix),
+		RetryAfter: time.Duration(r[1]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Limit
+		err  bool
+	}{
+		{s: "", want: Limit{}},
+		{s: "0", want: Limit{}},
+		{s: "10", want: Limit{Burst: 10, Period: time.Second}},
+		{s: " 60 / 1m ", want: Limit{Burst: 60, Period: time.Minute}},
+		{s: "600/h", want: Limit{Burst: 600, Period: time.Hour}},
+		{s: "0/1m", want: Limit{Period: time.Minute}},
+		{s: "a/1m", err: true},
+		{s: "-1/1m", err: true},
+		{s: "10/", err: true},
+		{s: "10/0s", err: true},
+		{s: "10/abc", err: true},
+	}
+
+	for _, tt := range tests {
+		l, err := ParseLimit(tt.s)
+		if tt.err {
+			assert.Error(t, err, tt.s)
+			continue
+		}
+		require.NoError(t, err, tt.s)
+		assert.Equal(t, tt.want, l, tt.s)
+		assert.Equal(t, tt.want.Unlimited(), l.String() == "", tt.s)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	if os.Getenv("KS_DB_REDIS") == "" {
+		t.Skip("KS_DB_REDIS is empty")
+	}
+	opt, err := redis.ParseURL(os.Getenv("KS_DB_REDIS"))
+	require.NoError(t, err)
+	l := New(redis.NewClient(opt), fmt.Sprintf("ratelimit_test:%d:", time.Now().UnixNano()))
+
+	ctx := context.Background()
+	a := Bucket{Key: "a", Limit: Limit{Burst: 2, Period: time.Hour}}
+	b := Bucket{Key: "b", Limit: Limit{Burst: 3, Period: time.Hour}}
+	unlimited := Bucket{Key: "unlimited"}
+
+	for i := 0; i < 2; i++ {
+		r, err := l.Allow(ctx, a, b, unlimited)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+	}
+
+	r, err := l.Allow(ctx, b, a)
+	require.NoError(t, err)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, "a", r.Denied)
+	assert.InDelta(t, 30*time.Minute, r.RetryAfter, float64(time.Second))
+
+	// no token is taken from b if a is denied.
+	r, err = l.Allow(ctx, b)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+	r, err = l.Allow(ctx, b)
+	require.NoError(t, err)
+	assert.False(t, r.Allowed)
+
+	r, err = l.Allow(ctx, unlimited)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package torrent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// maxDepth limits the nesting of lists and dictionaries.
+const maxDepth = 64
+
+// decoder decodes bencoded data, integers are decoded as int64, strings as string,
+// lists as []any and dictionaries as map[string]any.
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+
+	// info is the raw bytes of the value of the `info` key in the top level dictionary.
+	info []byte
+}
+
+func (d *decoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("bencode: %s at offset %d", fmt.Sprintf(format, args...), d.pos)
+}
+
+func (d *decoder) decode() (any, error) {
+	if d.pos >= len(d.data) {
+		return nil, d.errorf("unexpected end")
+	}
+
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.decodeInt()
+	case c >= '0' && c <= '9':
+		return d.decodeString()
+	case c == 'l':
+		return d.decodeList()
+	case c == 'd':
+		return d.decodeDict()
+	default:
+		return nil, d.errorf("unexpected byte %q", c)
+	}
+}
+
+func (d *decoder) decodeInt() (int64, error) {
+	end := bytes.IndexByte(d.data[d.pos:], 'e')
+	if end < 0 {
+		return 0, d.errorf("unterminated integer")
+	}
+	s := string(d.data[d.pos+1 : d.pos+end])
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || (len(s) > 1 && (s[0] == '0' || s[:2] == "-0")) {
+		return 0, d.errorf("invalid integer %q", s)
+	}
+	d.pos += end + 1
+	return n, nil
+}
+
+func (d *decoder) decodeString() (string, error) {
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return "", d.errorf("invalid string length")
+	}
+	n, err := strconv.Atoi(string(d.data[d.pos : d.pos+colon]))
+	start := d.pos + colon + 1
+	if err != nil || n < 0 || n > len(d.data)-start {
+		return "", d.errorf("invalid string length")
+	}
+	d.pos = start + n
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *decoder) enter() error {
+	if d.depth++; d.depth > maxDepth {
+		return d.errorf("nested too deep")
+	}
+	d.pos++ // skip 'l' or 'd'
+	return nil
+}
+
+func (d *decoder) decodeList() ([]any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	list := []any{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, d.errorf("unterminated list")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			d.depth--
+			return list, nil
+		}
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+}
+
+func (d *decoder) decodeDict() (map[string]any, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	dict := map[string]any{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, d.errorf("unterminated dictionary")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			d.depth--
+			return dict, nil
+		}
+		if c := d.data[d.pos]; c < '0' || c > '9' {
+			return nil, d.errorf("dictionary key is not a string")
+		}
+		k, err := d.decodeString()
+		if err != nil {
+			return nil, err
+		}
+
+		start := d.pos
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		if d.depth == 1 && k == "info" {
+			d.info = d.data[start:d.pos]
+		}
+		dict[k] = v
+	}
+}
+
+// decodeTorrent decodes the top level dictionary of a torrent file and returns the raw bytes of `info`.
+func decodeTorrent(data []byte) (meta map[string]any, info []byte, err error) {
+	d := &decoder{data: data}
+	if len(data) == 0 || data[0] != 'd' {
+		return nil, nil, errors.New("bencode: torrent is not a dictionary")
+	}
+	meta, err = d.decodeDict()
+	if err != nil {
+		return nil, nil, err
+	}
+	if d.pos != len(data) {
+		return nil, nil, d.errorf("trailing data")
+	}
+	return meta, d.info, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+// Package torrent parses BitTorrent metainfo files (.torrent) of v1, v2 and hybrid torrents.
+package torrent
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+)
+
+// File is a file in the torrent.
+type File struct {
+	// Path is the slash separated path of the file, it is prefixed with the torrent name for multi-file torrents.
+	Path   string `json:"path"`
+	Length int64  `json:"length"`
+}
+
+// Torrent is the parsed metainfo of a torrent.
+type Torrent struct {
+	// Name is the suggested name of the file or directory.
+	Name string `json:"name"`
+	// Length is the total size of files, padding files are excluded.
+	Length int64 `json:"length"`
+	// Files are the files in the torrent, padding files are excluded.
+	Files []File `json:"files"`
+	// InfoHashV1 is the lower case hex SHA-1 hash of the info dictionary, it is empty for v2-only torrents.
+	InfoHashV1 string `json:"info_hash_v1"`
+	// InfoHashV2 is the lower case hex SHA-256 hash of the info dictionary, it is empty for v1-only torrents.
+	InfoHashV2 string `json:"info_hash_v2"`
+}
+
+// Errors of invalid torrents.
+var (
+	ErrNoInfo     = errors.New("torrent: info dictionary not found")
+	ErrNoFiles    = errors.New("torrent: no files in info dictionary")
+	ErrNoInfoHash = errors.New("torrent: neither v1 nor v2 torrent")
+)
+
+// Parse parses a torrent file.
+func Parse(data []byte) (*Torrent, error) {
+	meta, raw, err := decodeTorrent(data)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := meta["info"].(map[string]any)
+	if !ok || raw == nil {
+		return nil, ErrNoInfo
+	}
+
+	t := &Torrent{Name: utf8String(info, "name")}
+
+	_, v1 := info["pieces"]
+	v2 := toInt(info["meta version"]) == 2
+	if !v1 && !v2 {
+		return nil, ErrNoInfoHash
+	}
+	if v1 {
+		h := sha1.Sum(raw)
+		t.InfoHashV1 = hex.EncodeToString(h[:])
+	}
+	if v2 {
+		h := sha256.Sum256(raw)
+		t.InfoHashV2 = hex.EncodeToString(h[:])
+	}
+
+	// the file tree of v2 is preferred, since the files of hybrid torrents contain padding files.
+	if tree, ok := info["file tree"].(map[string]any); ok && v2 {
+		walkFileTree(tree, "", &t.Files)
+		sort.Slice(t.Files, func(i, j int) bool { return t.Files[i].Path < t.Files[j].Path })
+		// the file tree of single-file torrents contains the file named by the torrent name only.
+		if len(t.Files) != 1 || t.Files[0].Path != t.Name {
+			for i := range t.Files {
+				t.Files[i].Path = path.Join(t.Name, t.Files[i].Path)
+			}
+		}
+	} else if files, ok := info["files"].([]any); ok {
+		for _, v := range files {
+			f, _ := v.(map[string]any)
+			if attr, _ := f["attr"].(string); strings.Contains(attr, "p") {
+				continue // padding file
+			}
+			parts := utf8List(f, "path")
+			t.Files = append(t.Files, File{Path: path.Join(append([]string{t.Name}, parts...)...), Length: toInt(f["length"])})
+		}
+	} else if _, ok := info["length"]; ok {
+		t.Files = []File{{Path: t.Name, Length: toInt(info["length"])}}
+	}
+	if len(t.Files) == 0 {
+		return nil, ErrNoFiles
+	}
+
+	for _, f := range t.Files {
+		t.Length += f.Length
+	}
+	return t, nil
+}
+
+// walkFileTree collects files of a v2 file tree, a file is a dictionary with an empty key.
+func walkFileTree(tree map[string]any, dir string, files *[]File) {
+	for name, v := range tree {
+		node, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		if leaf, ok := node[""].(map[string]any); ok && name != "" {
+			*files = append(*files, File{Path: path.Join(dir, name), Length: toInt(leaf["length"])})
+			continue
+		}
+		walkFileTree(node, path.Join(dir, name), files)
+	}
+}
+
+// Magnet returns the magnet link of the torrent with the exact topics, display name and exact length.
+// The hash of link.Hash is the v1 info hash for v1 and hybrid torrents, or the truncated v2 info hash for v2-only torrents.
+func (t *Torrent) Magnet() string {
+	var params []string
+	if t.InfoHashV1 != "" {
+		params = append(params, "xt=urn:btih:"+t.InfoHashV1)
+	}
+	if t.InfoHashV2 != "" {
+		// multihash of sha2-256: 0x12 is the hash function code, 0x20 is the digest length.
+		params = append(params, "xt=urn:btmh:1220"+t.InfoHashV2)
+	}
+	if t.Name != "" {
+		params = append(params, "dn="+url.QueryEscape(t.Name))
+	}
+	if t.Length > 0 {
+		params = append(params, "xl="+strconv.FormatInt(t.Length, 10))
+	}
+	return lk.MagnetPrefix + strings.Join(params, "&")
+}
+
+func toInt(v any) int64 {
+	n, _ := v.(int64)
+	return n
+}
+
+// utf8String returns the value of `<key>.utf-8` if it exists, otherwise the value of the key.
+func utf8String(dict map[string]any, key string) string {
+	if s, ok := dict[key+".utf-8"].(string); ok && s != "" {
+		return strings.ToValidUTF8(s, "")
+	}
+	s, _ := dict[key].(string)
+	return strings.ToValidUTF8(s, "")
+}
+
+// utf8List returns the value of `<key>.utf-8` if it exists, otherwise the value of the key.
+func utf8List(dict map[string]any, key string) []string {
+	list, ok := dict[key+".utf-8"].([]any)
+	if !ok {
+		list, _ = dict[key].([]any)
+	}
+	parts := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			parts = append(parts, strings.ToValidUTF8(s, ""))
+		}
+	}
+	return parts
+}
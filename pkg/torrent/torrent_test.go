@@ -0,0 +1,125 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package torrent
+
+import (
+	"strings"
+	"testing"
+
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// torrentOf returns a torrent file with the bencoded info dictionary.
+func torrentOf(info string) []byte {
+	return []byte("d8:announce23:http://tracker/announce4:info" + info + "e")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		info   string
+		expect *Torrent
+	}{
+		{
+			name: "v1 single file",
+			info: "d6:lengthi1024e4:name5:a.mp412:piece lengthi16384e6:pieces20:00000000000000000000e",
+			expect: &Torrent{
+				Name:       "a.mp4",
+				Length:     1024,
+				Files:      []File{{Path: "a.mp4", Length: 1024}},
+				InfoHashV1: "3e5f0ef1240ff8906281955666e73d7f8a14e42b",
+			},
+		},
+		{
+			name: "v1 multiple files with padding",
+			info: "d5:filesld6:lengthi10e4:pathl3:sub5:a.txteed4:attr1:p6:lengthi6e4:pathl4:.pad1:6eed6:lengthi20e4:pathl5:b.txteee4:name3:dir12:piece lengthi16384e6:pieces20:11111111111111111111e",
+			expect: &Torrent{
+				Name:       "dir",
+				Length:     30,
+				Files:      []File{{Path: "dir/sub/a.txt", Length: 10}, {Path: "dir/b.txt", Length: 20}},
+				InfoHashV1: "9ec30202f8d4535acd2b7aa17b6c1d27b1c88092",
+			},
+		},
+		{
+			name: "v2 only",
+			info: "d9:file treed5:a.txtd0:d6:lengthi5e11:pieces root32:22222222222222222222222222222222ee3:subd5:b.txtd0:d6:lengthi7eeeee12:meta versioni2e4:name4:root12:piece lengthi16384ee",
+			expect: &Torrent{
+				Name:       "root",
+				Length:     12,
+				Files:      []File{{Path: "root/a.txt", Length: 5}, {Path: "root/sub/b.txt", Length: 7}},
+				InfoHashV2: "1922666d9e0ae583fc497ebdc65addca16433776456a8596ea0da03ebf16f054",
+			},
+		},
+		{
+			name: "hybrid single file",
+			info: "d9:file treed5:x.bind0:d6:lengthi3eeee6:lengthi3e12:meta versioni2e4:name5:x.bin12:piece lengthi16384e6:pieces20:33333333333333333333e",
+			expect: &Torrent{
+				Name:       "x.bin",
+				Length:     3,
+				Files:      []File{{Path: "x.bin", Length: 3}},
+				InfoHashV1: "7e30d4c8fa06ff24ccf665ddb324f987bbed552e",
+				InfoHashV2: "2496a9f64528aac9959b1a1f7aaf6f5de42e9d101f044e76dea499d6472e4dbd",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(torrentOf(tt.info))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, got)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"empty", "", "not a dictionary"},
+		{"not dictionary", "l4:infoe", "not a dictionary"},
+		{"no info", "d8:announce3:abce", ErrNoInfo.Error()},
+		{"no pieces", "d4:infod4:name1:a6:lengthi1eee", ErrNoInfoHash.Error()},
+		{"no files", "d4:infod4:name1:a6:pieces0:ee", ErrNoFiles.Error()},
+		{"unterminated", "d4:infod4:name1:a", "unterminated dictionary"},
+		{"invalid integer", "d1:ai01ee", "invalid integer"},
+		{"negative zero", "d1:ai-0ee", "invalid integer"},
+		{"string too long", "d1:a9:abce", "invalid string length"},
+		{"key not string", "di1e1:ae", "key is not a string"},
+		{"trailing data", "de1:a", "trailing data"},
+		{"nested too deep", "d1:a" + strings.Repeat("l", 100) + strings.Repeat("e", 101), "nested too deep"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestMagnet(t *testing.T) {
+	tr := &Torrent{
+		Name:       "a b.mp4",
+		Length:     1024,
+		InfoHashV1: "3e5f0ef1240ff8906281955666e73d7f8a14e42b",
+		InfoHashV2: "2496a9f64528aac9959b1a1f7aaf6f5de42e9d101f044e76dea499d6472e4dbd",
+	}
+	magnet := tr.Magnet()
+	assert.Equal(t, "magnet:?xt=urn:btih:3e5f0ef1240ff8906281955666e73d7f8a14e42b&xt=urn:btmh:12202496a9f64528aac9959b1a1f7aaf6f5de42e9d101f044e76dea499d6472e4dbd&dn=a+b.mp4&xl=1024", magnet)
+	assert.Equal(t, "magnet:?xt=urn:btih:3e5f0ef1240ff8906281955666e73d7f8a14e42b", lk.Simplify(magnet))
+	assert.Equal(t, tr.InfoHashV1, lk.Hash(magnet))
+}
+
+func TestMagnetV2(t *testing.T) {
+	tr := &Torrent{InfoHashV2: "2496a9f64528aac9959b1a1f7aaf6f5de42e9d101f044e76dea499d6472e4dbd"}
+	assert.Equal(t, "magnet:?xt=urn:btmh:12202496a9f64528aac9959b1a1f7aaf6f5de42e9d101f044e76dea499d6472e4dbd", lk.Simplify(tr.Magnet()))
+	assert.Equal(t, tr.InfoHashV2[:40], lk.Hash(tr.Magnet()))
+}
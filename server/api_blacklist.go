@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/KeepShareOrg/keepshare/config"
-	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
 	"github.com/KeepShareOrg/keepshare/pkg/i18n"
 	lk "github.com/KeepShareOrg/keepshare/pkg/link"
@@ -133,11 +132,9 @@ func addToBlackList(c *gin.Context) {
 	}
 
 	if req.Delete {
-		for _, h := range hosts.GetAll() {
-			if _, err := deleteSharedLinksWithHost(ctx, userID, h, original); err != nil {
-				mdw.RespInternal(c, err.Error())
-				return
-			}
+		if _, err := deleteSharedLinksOfUser(ctx, userID, "", original); err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
 		}
 	} else {
 		go func() {
@@ -152,6 +149,7 @@ func addToBlackList(c *gin.Context) {
 				State:     share.StatusBlocked.String(),
 				UpdatedAt: time.Now(),
 			})
+			notifySharedLinksUpdated(ctx, hashes...)
 		}()
 	}
 
@@ -7,15 +7,53 @@ package server
 import (
 	"github.com/KeepShareOrg/keepshare/pkg/log"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/KeepShareOrg/keepshare/config"
 	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/pkg/util"
 	"github.com/KeepShareOrg/keepshare/server/constant"
 	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
 	"github.com/gin-gonic/gin"
 )
 
+// listHosts returns the registered hosts and their capabilities.
+func listHosts(c *gin.Context) {
+	all := hosts.GetAll()
+	sort.Slice(all, func(i, j int) bool { return all[i].Name() < all[j].Name() })
+
+	list := make([]Map, 0, len(all))
+	for _, host := range all {
+		list = append(list, Map{
+			"name":         host.Name(),
+			"default":      strings.EqualFold(host.Name(), config.DefaultHost()),
+			"capabilities": host.Capabilities(),
+		})
+	}
+
+	c.JSON(http.StatusOK, Map{"list": list})
+}
+
+// getHostSupports returns the host by name or the default host if the name is empty.
+// If the host is not found or does not support the operation, an error is responded and nil is returned.
+func getHostSupports(c *gin.Context, hostName string, operation string, supported func(hosts.Capabilities) bool) *hosts.HostWithProperties {
+	hostName = util.FirstNotEmpty(hostName, config.DefaultHost())
+	host := hosts.Get(hostName)
+	if host == nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
+		return nil
+	}
+	if supported != nil && !supported(host.Capabilities()) {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "unsupported_operation", i18n.WithDataMap("host", host.Name(), "operation", operation)))
+		return nil
+	}
+	return host
+}
+
+func supportsPasswordManagement(caps hosts.Capabilities) bool { return caps.PasswordManagement }
+
 func getHostInfo(c *gin.Context) {
 	ctx := c.Request.Context()
 	userID := c.GetString(constant.UserID)
@@ -31,12 +69,8 @@ func getHostInfo(c *gin.Context) {
 	}
 
 	hostName, _ := opt["host"].(string)
-	if hostName == "" {
-		hostName = config.DefaultHost()
-	}
-	host := hosts.Get(hostName)
+	host := getHostSupports(c, hostName, "host_info", nil)
 	if host == nil {
-		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
 		return
 	}
 
@@ -90,8 +124,10 @@ func changeHostPassword(c *gin.Context) {
 		return
 	}
 
-	hostName := c.DefaultQuery("host", config.DefaultHost())
-	host := hosts.Get(hostName)
+	host := getHostSupports(c, c.Query("host"), "change_password", supportsPasswordManagement)
+	if host == nil {
+		return
+	}
 
 	ctx := c.Request.Context()
 	userID := c.GetString(constant.UserID)
@@ -136,8 +172,10 @@ func confirmPassword(c *gin.Context) {
 		return
 	}
 
-	hostName := c.DefaultQuery("host", config.DefaultHost())
-	host := hosts.Get(hostName)
+	host := getHostSupports(c, c.Query("host"), "confirm_password", supportsPasswordManagement)
+	if host == nil {
+		return
+	}
 
 	ksUserID := c.GetString(constant.UserID)
 	log.Infof("confirming password for keep share user %s %v", ksUserID, r.Password)
@@ -155,8 +193,10 @@ func confirmPassword(c *gin.Context) {
 func getLoginStatus(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	hostName := c.DefaultQuery("host", config.DefaultHost())
-	host := hosts.Get(hostName)
+	host := getHostSupports(c, c.Query("host"), "login_status", nil)
+	if host == nil {
+		return
+	}
 
 	ksUserID := c.GetString(constant.UserID)
 	status, err := host.GetMasterAccountLoginStatus(ctx, ksUserID)
@@ -0,0 +1,65 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/locale"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostCapabilities(t *testing.T) {
+	hoststest.Register(t, "capsfake", hoststest.NewFake())
+
+	assert.NoError(t, i18n.Load(locale.FS))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/hosts", listHosts)
+	r.PATCH("/api/host/password", changeHostPassword)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/hosts", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		List []struct {
+			Name         string             `json:"name"`
+			Capabilities hosts.Capabilities `json:"capabilities"`
+		} `json:"list"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var found bool
+	for _, h := range resp.List {
+		if h.Name == "capsfake" {
+			found = true
+			assert.Contains(t, h.Capabilities.LinkSchemes, "magnet")
+			assert.False(t, h.Capabilities.PasswordManagement)
+		}
+	}
+	assert.True(t, found)
+
+	for query, key := range map[string]string{"capsfake": "unsupported_operation", "unknown": "invalid_host"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/host/password?host="+query, strings.NewReader("{}")))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), key)
+	}
+
+	caps := hosts.Capabilities{LinkSchemes: []string{"magnet", "http"}}
+	assert.True(t, caps.SupportsLink("MAGNET:?xt=urn:btih:abc"))
+	assert.True(t, caps.SupportsLink("http://example.com/a"))
+	assert.False(t, caps.SupportsLink("ed2k://|file|a|1|hash|/"))
+	assert.False(t, caps.SupportsLink("example.com"))
+}
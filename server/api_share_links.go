@@ -7,17 +7,17 @@ package server
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/KeepShareOrg/keepshare/pkg/share"
 
 	"github.com/KeepShareOrg/keepshare/config"
 	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
 	"github.com/KeepShareOrg/keepshare/pkg/i18n"
 	lk "github.com/KeepShareOrg/keepshare/pkg/link"
 	"github.com/KeepShareOrg/keepshare/pkg/log"
@@ -26,8 +26,8 @@ import (
 	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
 	"github.com/KeepShareOrg/keepshare/server/model"
 	"github.com/KeepShareOrg/keepshare/server/query"
-	"github.com/alecthomas/participle/v2"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 	"gorm.io/gen"
 	"gorm.io/gen/field"
 )
@@ -36,43 +36,34 @@ import (
 func listSharedLinks(c *gin.Context) {
 	userID := c.GetString(constant.UserID)
 
-	searchValue := c.Query("search")
-	filter := c.Query("filter")
-
 	limit, err := strconv.Atoi(c.Query("limit"))
-	if err != nil {
+	if err != nil || limit <= 0 {
 		limit = 10
 	}
 
 	pageIndex, err := strconv.Atoi(c.Query("page_index"))
-	if err != nil {
+	if err != nil || pageIndex <= 0 {
 		pageIndex = 1
 	}
 
-	var filters []Query
-	if filter != "" {
-		if err := json.Unmarshal([]byte(filter), &filters); err != nil {
-			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params"))
-			return
-		}
+	page, err := newSharedLinkPage(pageIndex, limit, c.Query("sort"), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
 	}
 
-	if searchValue != "" {
-		dslFilters, err := parseQueryDSL(searchValue)
-
-		if err != nil {
-			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params: "+err.Error()))
-			return
-		}
-
-		// merge filters
-		for _, filter := range dslFilters {
-			filters = append(filters, filter)
-		}
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(userID)).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	conditions, ok := sharedLinkConditions(c, user)
+	if !ok {
+		return
 	}
 
-	ctx := c.Request.Context()
-	ret, total, err := conditionQuery(ctx, filters, userID, pageIndex, limit)
+	ret, total, next, err := conditionQuery(ctx, conditions, userID, page)
 	if err != nil {
 		mdw.RespInternal(c, err.Error())
 		return
@@ -89,13 +80,57 @@ func listSharedLinks(c *gin.Context) {
 
 	log.WithContext(ctx).WithField("shared_records", ret).Debugf("condition query result")
 
+	tags, err := sharedLinkTagNames(ctx, lo.Map(ret, func(s *model.SharedLink, _ int) int64 { return s.AutoID }))
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
 	c.JSON(http.StatusOK, Map{
-		"total":     total,
-		"page_size": len(ret),
-		"list":      ret,
+		"total":       total,
+		"page_size":   len(ret),
+		"list":        ret,
+		"tags":        tags,
+		"next_cursor": next,
 	})
 }
 
+// sharedLinkConditions returns the conditions of the filter and search parameters in the time zone of the user,
+// it responds the error and returns false if they are invalid.
+func sharedLinkConditions(c *gin.Context, user *model.User) ([]gen.Condition, bool) {
+	loc := userLocation(user)
+
+	var filters []Query
+	if filter := c.Query("filter"); filter != "" {
+		if err := json.Unmarshal([]byte(filter), &filters); err != nil {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params"))
+			return nil, false
+		}
+	}
+	conditions := filterConditions(filters, loc)
+
+	if tags := c.QueryArray("tag"); len(tags) > 0 {
+		names, err := parseTagNames(tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+			return nil, false
+		}
+		if len(names) > 0 {
+			conditions = append(conditions, taggedWith(user.ID, names...))
+		}
+	}
+
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		cond, err := compileQueryDSL(search, user.ID, loc)
+		if err != nil {
+			respDSLError(c, err)
+			return nil, false
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, true
+}
+
 // querySharedLinkInfo query shared link current status and this shared link's info
 func querySharedLinkInfo(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -138,8 +173,7 @@ func querySharedLinkInfo(c *gin.Context) {
 	})
 
 	if res.State == share.StatusOK.String() {
-		// We can add parameters to the PikPak sharing page to automatically play, but we need to add that only the current host is PikPak.
-		hostLink := fmt.Sprintf("%v?act=play", res.HostSharedLink)
+		hostLink := hostLinkWithParams(userRedirectSetting(ctx, res.UserID), res.Host, res.HostSharedLink)
 		report.Sets(Map{
 			keyRedirectType: "share",
 			keyHostLink:     hostLink,
@@ -158,7 +192,10 @@ func manualQueryFileStatus(ctx context.Context, sharedLink *model.SharedLink, ip
 	if ok, _ := config.Redis().SetNX(ctx, fmt.Sprintf("manual_query:%v", sharedLink.OriginalLink), 1, time.Second*20).Result(); !ok {
 		return
 	}
-	host := hosts.Get(config.DefaultHost())
+	host := hosts.Get(util.FirstNotEmpty(sharedLink.Host, config.DefaultHost()))
+	if host == nil {
+		return
+	}
 	sharedLinks, err := host.CreateFromLinks(context.Background(), sharedLink.UserID, []string{sharedLink.OriginalLink}, sharedLink.CreatedBy, ip)
 	if err != nil {
 		log.Errorf("shared links still not ok: %v", err)
@@ -166,11 +203,12 @@ func manualQueryFileStatus(ctx context.Context, sharedLink *model.SharedLink, ip
 	}
 	log.Debugf("complete shared links: %#v", sharedLinks)
 	if v, ok := sharedLinks[sharedLink.OriginalLink]; ok && v.State == share.StatusOK {
-		query.SharedLink.WithContext(ctx).Where(query.SharedLink.AutoID.Eq(sharedLink.AutoID)).Updates(&model.SharedLink{
+		_, _ = updateSharedLinks(ctx, &model.SharedLink{
 			State:          share.StatusOK.String(),
 			HostSharedLink: v.HostSharedLink,
 			UpdatedAt:      time.Now(),
-		})
+		}, query.SharedLink.AutoID.Eq(sharedLink.AutoID))
+		notifySharedLinksUpdated(ctx, sharedLink.OriginalLinkHash)
 	}
 }
 
@@ -240,6 +278,10 @@ func initQueryTypes() {
 	table := query.SharedLink
 	for _, f := range []field.Expr{
 		table.Title,
+		table.CustomTitle,
+		table.Note,
+		table.Pinned,
+		table.ExpiresAt,
 		table.OriginalLink,
 		table.HostSharedLink,
 		table.CreatedAt,
@@ -254,8 +296,8 @@ func initQueryTypes() {
 	}
 }
 
-// conditionQuery shared link query method, support query by filter condition
-func conditionQuery(ctx context.Context, filters []Query, userID string, pageIndex, limit int) ([]*model.SharedLink, int64, error) {
+// filterConditions returns conditions of the filters, times are in the location.
+func filterConditions(filters []Query, loc *time.Location) []gen.Condition {
 	conditions := make([]gen.Condition, 0)
 
 	for _, f := range filters {
@@ -269,12 +311,6 @@ func conditionQuery(ctx context.Context, filters []Query, userID string, pageInd
 					if ok1 && ok2 {
 						conditions = append(conditions, v.Between(int32(v1), int32(v2)))
 					}
-				} else {
-					// []float64 from parse dsl
-					val, ok := f.Value.([]float64)
-					if ok && len(val) == 2 {
-						conditions = append(conditions, v.Between(int32(val[0]), int32(val[1])))
-					}
 				}
 			} else if val, ok := f.Value.(float64); ok {
 				switch f.Operator {
@@ -301,12 +337,6 @@ func conditionQuery(ctx context.Context, filters []Query, userID string, pageInd
 					if ok1 && ok2 {
 						conditions = append(conditions, v.Between(int64(v1), int64(v2)))
 					}
-				} else {
-					// []float64 from parse dsl
-					val, ok := f.Value.([]float64)
-					if ok && len(val) == 2 {
-						conditions = append(conditions, v.Between(int64(val[0]), int64(val[1])))
-					}
 				}
 			} else if val, ok := f.Value.(float64); ok {
 				switch f.Operator {
@@ -328,14 +358,14 @@ func conditionQuery(ctx context.Context, filters []Query, userID string, pageInd
 			if f.Operator == OpBetween {
 				val, ok := f.Value.([]interface{})
 				if ok && len(val) == 2 {
-					t1, err1 := time.ParseInLocation("2006-01-02 15:04:05", val[0].(string), time.Local)
-					t2, err2 := time.ParseInLocation("2006-01-02 15:04:05", val[1].(string), time.Local)
+					t1, err1 := time.ParseInLocation("2006-01-02 15:04:05", val[0].(string), loc)
+					t2, err2 := time.ParseInLocation("2006-01-02 15:04:05", val[1].(string), loc)
 					if err1 == nil && err2 == nil {
 						conditions = append(conditions, v.Between(t1, t2))
 					}
 				}
 			} else if val, ok := f.Value.(string); ok {
-				if t, err := time.ParseInLocation("2006-01-02 15:04:05", val, time.Local); err == nil {
+				if t, err := time.ParseInLocation("2006-01-02 15:04:05", val, loc); err == nil {
 					switch f.Operator {
 					case OpEquals:
 						conditions = append(conditions, v.Eq(t))
@@ -374,110 +404,43 @@ func conditionQuery(ctx context.Context, filters []Query, userID string, pageInd
 		}
 	}
 
+	return conditions
+}
+
+// conditionQuery shared link query method, support query by filter condition.
+// It returns the cursor of the next page, which is empty if there are no more shared links.
+func conditionQuery(ctx context.Context, conditions []gen.Condition, userID string, page *sharedLinkPage) ([]*model.SharedLink, int64, string, error) {
 	conditions = append(conditions, query.SharedLink.UserID.Eq(userID))
 
 	// Only the first query returns the real total num
 	total := int64(0)
-	if pageIndex == 1 {
+	if page.Cursor == nil && page.Index == 1 {
 		num, err := query.SharedLink.WithContext(ctx).Where(conditions...).Count()
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, "", err
 		}
 		total = num
 	}
 
-	ret, err := query.SharedLink.WithContext(ctx).Where(conditions...).Order(query.SharedLink.AutoID.Desc()).Offset((pageIndex - 1) * limit).Limit(limit).Find()
-	if err != nil {
-		return nil, 0, err
+	do := query.SharedLink.WithContext(ctx).Where(conditions...).Order(page.Sort.orders()...)
+	if page.Cursor != nil {
+		do = do.Where(page.Sort.after(page.Cursor))
+	} else {
+		do = do.Offset((page.Index - 1) * page.Limit)
 	}
-
-	return ret, total, nil
-}
-
-type Condition struct {
-	Key   string       `parser:"@Ident"`
-	Op    string       `parser:"@('!'? '=' | '<' '='? | '>' '='? | ':')"`
-	Value *FilterValue `parser:"@@"`
-}
-
-type FilterValue struct {
-	Bool          *bool     `parser:"@('true' | 'false')"`
-	String        *string   `parser:"| @String"`
-	Number        *float64  `parser:"| @(Int | Float)"`
-	BetweenString []string  `parser:"| '[' @String (',' @String)* ']'"`
-	BetweenNumber []float64 `parser:"| '[' @(Int | Float) (',' @(Int | Float))* ']'"`
-}
-
-// keepShare query dsl parser
-var parser = participle.MustBuild[struct {
-	Conditions []Condition `parser:"@@*"`
-}](participle.Unquote("String"))
-
-var GBReg = regexp.MustCompile(`(?i)([\d.]+)GB$`)
-
-// transferSupportUnit transfer support unit string to float64
-func transferSupportUnit(input string) (float64, error) {
-	ret := GBReg.FindAllStringSubmatch(input, -1)
-	if len(ret) > 0 {
-		unit, err := strconv.ParseFloat(ret[0][1], 64)
-		if err != nil {
-			return 0, err
-		}
-		// per GB equal 1024 * 1024 * 1024 bytes
-		return unit * 1024 * 1024 * 1024, nil
-	}
-
-	return 0, errors.New("unsupported unit")
-}
-
-// parseQueryDSL parse keepShare dsl string to []Query
-func parseQueryDSL(queryString string) ([]Query, error) {
-	ret, err := parser.ParseString("", queryString)
+	// query one more to know whether there is a next page.
+	ret, err := do.Limit(page.Limit + 1).Find()
 	if err != nil {
-		return nil, err
+		return nil, 0, "", err
 	}
 
-	filters := make([]Query, 0)
-
-	for _, f := range ret.Conditions {
-		filter := Query{
-			Key:      SupportQueryKey(string(f.Key)),
-			Operator: QueryOperator(string(f.Op)),
-		}
-
-		v := f.Value
-		if v.Bool != nil {
-			filter.Value = *v.Bool
-		} else if v.Number != nil {
-			filter.Value = *v.Number
-		} else if v.String != nil {
-			if unit, err := transferSupportUnit(*v.String); err != nil {
-				filter.Value = *v.String
-			} else {
-				filter.Value = unit
-			}
-		} else if v.BetweenNumber != nil {
-			filter.Value = v.BetweenNumber
-		} else if v.BetweenString != nil {
-			filter.Value = v.BetweenString
-			tempFloats := make([]float64, 0)
-			for _, str := range v.BetweenString {
-				if unit, err := transferSupportUnit(str); err != nil {
-					filter.Value = v.BetweenString
-					break
-				} else {
-					tempFloats = append(tempFloats, unit)
-				}
-			}
-			if len(tempFloats) == 2 {
-				filter.Value = tempFloats
-			}
-		}
-
-		filters = append(filters, filter)
+	next := ""
+	if len(ret) > page.Limit {
+		ret = ret[:page.Limit]
+		next = newSharedLinkCursor(page.Sort, ret[len(ret)-1]).String()
 	}
 
-	return filters, nil
+	return ret, total, next, nil
 }
 
 func deleteSharedLinks(c *gin.Context) {
@@ -500,10 +463,8 @@ func deleteSharedLinks(c *gin.Context) {
 		return
 	}
 
-	hostName := util.FirstNotEmpty(req.Host, config.DefaultHost())
-	host := hosts.Get(hostName)
-	if host == nil {
-		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
+	if req.Host != "" && hosts.Get(req.Host) == nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", req.Host)))
 		return
 	}
 
@@ -512,7 +473,7 @@ func deleteSharedLinks(c *gin.Context) {
 	original, invalid := getOriginalLinks(req.Links)
 	resp.ErrorLinks = invalid
 
-	rows, err := deleteSharedLinksWithHost(ctx, userID, host, original)
+	rows, err := deleteSharedLinksOfUser(ctx, userID, req.Host, original)
 	if err != nil {
 		mdw.RespInternal(c, err.Error())
 		return
@@ -521,29 +482,56 @@ func deleteSharedLinks(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
-func deleteSharedLinksWithHost(ctx context.Context, userID string, host hosts.Host, original []string) (rowsAffected int64, err error) {
+// deleteSharedLinksOfUser deletes the shared links of the original links from the hosts which served them,
+// and then deletes the records. Only the shared links of the host are deleted if hostName is not empty.
+func deleteSharedLinksOfUser(ctx context.Context, userID string, hostName string, original []string) (rowsAffected int64, err error) {
 	if len(original) == 0 {
 		return 0, nil
 	}
 
-	if err := host.Delete(ctx, userID, original); err != nil {
-		return 0, err
-	}
-
 	hashes := make([]string, 0, len(original))
 	for _, link := range original {
 		hashes = append(hashes, lk.Hash(link))
 	}
 
+	t := query.SharedLink
 	conditions := []gen.Condition{
-		query.SharedLink.OriginalLinkHash.In(hashes...),
-		query.SharedLink.UserID.Eq(userID),
+		t.OriginalLinkHash.In(hashes...),
+		t.UserID.Eq(userID),
+	}
+	if hostName != "" {
+		conditions = append(conditions, t.Host.Eq(hostName))
+	}
+	rows, err := t.WithContext(ctx).Where(conditions...).Find()
+	if err != nil && !gormutil.IsNotFoundError(err) {
+		return 0, err
 	}
 
-	ret, err := query.SharedLink.WithContext(ctx).Where(conditions...).Delete()
+	// since failover, the shared links of a user may be served by different hosts.
+	var ids []int64
+	for name, group := range lo.GroupBy(rows, func(row *model.SharedLink) string { return row.Host }) {
+		host := hosts.Get(name)
+		if host == nil {
+			log.WithContext(ctx).WithFields(Map{constant.UserID: userID, constant.Host: name}).Warn("host of shared links to delete not found, skip them")
+			continue
+		}
+		links := lo.Map(group, func(row *model.SharedLink, _ int) string { return row.OriginalLink })
+		if err := host.Delete(ctx, userID, links); err != nil {
+			return 0, fmt.Errorf("delete shared links of host %s err: %w", name, err)
+		}
+		ids = append(ids, lo.Map(group, func(row *model.SharedLink, _ int) int64 { return row.AutoID })...)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	ret, err := t.WithContext(ctx).Where(t.AutoID.In(ids...)).Delete()
 	if err != nil {
 		return 0, err
 	}
+	if err := untagSharedLinks(ctx, ids); err != nil {
+		log.WithContext(ctx).WithField(constant.UserID, userID).Errorf("untag deleted shared links err: %v", err)
+	}
 
 	return ret.RowsAffected, nil
 }
@@ -5,8 +5,21 @@
 package server
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
 )
 
 func TestParserDSL(t *testing.T) {
@@ -24,10 +37,264 @@ yy between [123.456, 789.123];
 name match "this is string test\"ok\"";
 `
 	ret, err := parseQueryDSL(searchStr)
-	if err != nil {
-		t.Errorf("err: %v", err)
+	require.NoError(t, err)
+	require.Len(t, ret.And, 1)
+	terms := ret.And[0].Terms
+	require.Len(t, terms, 11)
+	assert.Equal(t, "username", terms[0].Cmp.Key)
+	assert.Equal(t, "admin", *terms[0].Cmp.Value.Scalar.String)
+	assert.Equal(t, "!=", terms[1].Cmp.Op)
+	assert.Equal(t, "true", *terms[6].Cmp.Value.Scalar.Bool)
+	assert.Len(t, terms[7].Cmp.Value.List, 2)
+	assert.Equal(t, `this is string test"ok"`, *terms[10].Cmp.Value.Scalar.String)
+
+	tests := []struct {
+		dsl string
+		// err is the position of the error, e.g. "1:5".
+		err string
+	}{
+		{dsl: `title:"a"`},
+		{dsl: `title : "a" size > 1GB`},
+		{dsl: `a=1 and b=2 or c=3 && d=4 || e=5`},
+		{dsl: `A=1 AND B=2 Or NOT c=3`},
+		{dsl: `not (a=1 or b=2); !(c=3) ! d=4`},
+		{dsl: `((a=1))`},
+		{dsl: `a in (1, 2, 3) b not in ["x"] c not between [1, 2] d not match "x"`},
+		{dsl: `created_at > -7d; size <= .5TB; x = +1.5h`},
+		{dsl: "a=1\nb=2"},
+		{dsl: ``, err: "1:1"},
+		{dsl: `title`, err: "1:6"},
+		{dsl: `title "a"`, err: "1:7"},
+		{dsl: `a=1 and`, err: "1:8"},
+		{dsl: `a=1 or or b=2`, err: "1:8"},
+		{dsl: `(a=1`, err: "1:5"},
+		{dsl: `a=1)`, err: "1:4"},
+		{dsl: `a in ()`, err: "1:7"},
+		{dsl: `a in (1,)`, err: "1:8"},
+		{dsl: `a = "unterminated`, err: "1:5"},
+		{dsl: "a=1\nb=2\nc = = 3", err: "3:5"},
+		{dsl: `a=1 @ b=2`, err: "1:5"},
+	}
+	for _, tt := range tests {
+		_, err := parseQueryDSL(tt.dsl)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.dsl)
+			continue
+		}
+		var e *dslError
+		if assert.True(t, errors.As(err, &e), "%q: %v", tt.dsl, err) {
+			assert.True(t, strings.HasPrefix(e.Error(), tt.err+": "), "%q: %v", tt.dsl, e)
+		}
+	}
+}
+
+func TestCompileQueryDSL(t *testing.T) {
+	useDryRunSharedLink(t)
+	initQueryTypes()
+
+	loc := time.FixedZone("UTC+8", 8*3600)
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	compile := func(dsl string) (field.Expr, error) {
+		e, err := parseQueryDSL(dsl)
+		if err != nil {
+			return nil, err
+		}
+		return (&dslCompiler{userID: "u1", loc: loc, now: now}).expr(e)
+	}
+	where := func(x field.Expr) (string, []any) {
+		var links []*model.SharedLink
+		stmt := query.SharedLink.WithContext(context.Background()).Where(x).UnderlyingDB().Find(&links).Statement
+		sql := stmt.SQL.String()
+		sql = sql[strings.Index(sql, " WHERE ")+len(" WHERE "):]
+		return strings.ReplaceAll(sql, "`keepshare_shared_link`.", ""), stmt.Vars
+	}
+
+	tests := []struct {
+		dsl  string
+		sql  string
+		vars []any
+	}{
+		{dsl: `title:"a_b%"`, sql: "`title` LIKE ?", vars: []any{`%a\_b\%%`}},
+		{dsl: `title MATCH "x"`, sql: "`title` LIKE ?", vars: []any{"%x%"}},
+		{dsl: `title not match "x"`, sql: "`title` NOT LIKE ?", vars: []any{"%x%"}},
+		{dsl: `state = "OK"; visitor > 10`, sql: "(`state` = ? AND `visitor` > ?)", vars: []any{"OK", int32(10)}},
+		{
+			dsl:  `state = "OK" or visitor >= 10 and stored < 2`,
+			sql:  "(`state` = ? OR (`visitor` >= ? AND `stored` < ?))",
+			vars: []any{"OK", int32(10), int32(2)},
+		},
+		{
+			dsl:  `(state = "OK" or visitor != 10) and stored <= 2`,
+			sql:  "((`state` = ? OR `visitor` <> ?) AND `stored` <= ?)",
+			vars: []any{"OK", int32(10), int32(2)},
+		},
+		{dsl: `not state = "OK"`, sql: "`state` <> ?", vars: []any{"OK"}},
+		{
+			dsl:  `!(state = "OK" or visitor = 0)`,
+			sql:  "NOT (`state` = ? OR `visitor` = ?)",
+			vars: []any{"OK", int32(0)},
+		},
+		{dsl: `state in ("OK", "ERROR")`, sql: "`state` IN (?,?)", vars: []any{"OK", "ERROR"}},
+		{dsl: `state not in ["BLOCKED", "ERROR"]`, sql: "`state` NOT IN (?,?)", vars: []any{"BLOCKED", "ERROR"}},
+		{dsl: `size > 1.5GB`, sql: "`size` > ?", vars: []any{int64(3 << 29)}},
+		{dsl: `size = 1024`, sql: "`size` = ?", vars: []any{int64(1024)}},
+		{
+			dsl:  `size between ["1kb", 2MB]`,
+			sql:  "`size` BETWEEN ? AND ?",
+			vars: []any{int64(1 << 10), int64(2 << 20)},
+		},
+		{dsl: `size not between [1B, 1TB]`, sql: "NOT (`size` BETWEEN ? AND ?)", vars: []any{int64(1), int64(1 << 40)}},
+		{dsl: `created_at > -7d`, sql: "`created_at` > ?", vars: []any{now.Add(-7 * 24 * time.Hour)}},
+		{dsl: `last_visited_at < -1.5h`, sql: "`last_visited_at` < ?", vars: []any{now.Add(-90 * time.Minute)}},
+		{dsl: `created_at >= +2w`, sql: "`created_at` >= ?", vars: []any{now.Add(14 * 24 * time.Hour)}},
+		{
+			dsl:  `created_at between ["2023-01-02", "2023-01-02 15:04"]`,
+			sql:  "`created_at` BETWEEN ? AND ?",
+			vars: []any{time.Date(2023, 1, 2, 0, 0, 0, 0, loc), time.Date(2023, 1, 2, 15, 4, 0, 0, loc)},
+		},
+		{
+			dsl:  `created_at = "2023-01-02 15:04:05"`,
+			sql:  "`created_at` = ?",
+			vars: []any{time.Date(2023, 1, 2, 15, 4, 5, 0, loc)},
+		},
+		{
+			dsl:  `created_at < "2023-01-02T15:04:05Z"`,
+			sql:  "`created_at` < ?",
+			vars: []any{time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)},
+		},
+		{
+			dsl:  `tag:"movies"`,
+			sql:  "`auto_id` IN (SELECT `keepshare_shared_link_tag`.`shared_link_id` FROM `keepshare_shared_link_tag` WHERE `keepshare_shared_link_tag`.`tag_id` IN (SELECT `keepshare_tag`.`id` FROM `keepshare_tag` WHERE `keepshare_tag`.`user_id` = ? AND `keepshare_tag`.`name` = ?))",
+			vars: []any{"u1", "movies"},
+		},
+		{
+			dsl:  `tag not in ("a", "b") and size > 1GB`,
+			sql:  "(NOT `auto_id` IN (SELECT `keepshare_shared_link_tag`.`shared_link_id` FROM `keepshare_shared_link_tag` WHERE `keepshare_shared_link_tag`.`tag_id` IN (SELECT `keepshare_tag`.`id` FROM `keepshare_tag` WHERE `keepshare_tag`.`user_id` = ? AND `keepshare_tag`.`name` IN (?,?))) AND `size` > ?)",
+			vars: []any{"u1", "a", "b", int64(1 << 30)},
+		},
+	}
+	for _, tt := range tests {
+		x, err := compile(tt.dsl)
+		require.NoError(t, err, tt.dsl)
+		sql, vars := where(x)
+		assert.Equal(t, tt.sql, sql, tt.dsl)
+		require.Len(t, vars, len(tt.vars), tt.dsl)
+		for i := range vars {
+			if want, ok := tt.vars[i].(time.Time); ok {
+				assert.True(t, want.Equal(vars[i].(time.Time)), "%s: %v != %v", tt.dsl, want, vars[i])
+			} else {
+				assert.Equal(t, tt.vars[i], vars[i], tt.dsl)
+			}
+		}
+	}
+
+	errs := []struct {
+		dsl string
+		err string
+	}{
+		{dsl: `unknown = 1`, err: `1:1: unknown key "unknown"`},
+		{dsl: `title = "a" and (visitor : 1)`, err: "1:18: visitor can not be matched"},
+		{dsl: `visitor = "many"`, err: `1:11: expected a number, got "many"`},
+		{dsl: `visitor = 1KB`, err: "1:11: visitor has no units"},
+		{dsl: `visitor = true`, err: "1:11: expected a number, got true"},
+		{dsl: `visitor = 3000000000`, err: "1:11: 3000000000 is out of range"},
+		{dsl: `size > 1PB`, err: `1:8: unknown size unit "pb"`},
+		{dsl: `title = false`, err: "1:9: expected text, got false"},
+		{dsl: `title in "a"`, err: "1:1: in needs a list"},
+		{dsl: `title = ("a")`, err: "1:1: = needs a single value"},
+		{dsl: `size between [1]`, err: "1:1: between needs a list of 2 values"},
+		{dsl: `size not = 1`, err: "1:1: not is only allowed before in, between and match"},
+		{dsl: `created_at > 7d`, err: `1:14: invalid relative time "7d"`},
+		{dsl: `created_at > -7y`, err: `1:14: invalid relative time "-7y"`},
+		{dsl: "state = \"OK\"\n  created_at > \"yesterday\"", err: `2:16: invalid time "yesterday"`},
+		{dsl: `tag > "a"`, err: "1:1: tag can not be compared by >"},
+		{dsl: `tag = true`, err: "1:7: expected text, got true"},
+	}
+	for _, tt := range errs {
+		_, err := compile(tt.dsl)
+		var e *dslError
+		if assert.True(t, errors.As(err, &e), "%q: %v", tt.dsl, err) {
+			assert.True(t, strings.HasPrefix(e.Error(), tt.err), "%q: %v", tt.dsl, e)
+		}
+	}
+
+	// the location of the user is used by compileQueryDSL.
+	cond, err := compileQueryDSL(`created_at = "2023-01-02"`, "u1", loc)
+	require.NoError(t, err)
+	_, vars := where(cond.(field.Expr))
+	assert.Equal(t, loc, vars[0].(time.Time).Location())
+}
+
+func TestParseTimezone(t *testing.T) {
+	for _, tz := range []string{"", " ", "UTC", "Asia/Shanghai", "America/New_York"} {
+		_, err := parseTimezone(tz)
+		assert.NoError(t, err, tz)
+	}
+	for _, tz := range []string{"Local", "Mars/Olympus", "+08:00"} {
+		_, err := parseTimezone(tz)
+		assert.Error(t, err, tz)
 	}
-	if bs, err := json.MarshalIndent(ret, "", "  "); err == nil {
-		t.Logf("ret: %v", string(bs))
+	assert.Equal(t, time.Local, userLocation(&model.User{}))
+	assert.Equal(t, "Asia/Shanghai", userLocation(&model.User{Timezone: "Asia/Shanghai"}).String())
+}
+
+func TestParseTagNames(t *testing.T) {
+	names, err := parseTagNames([]string{" movies ", "a,b", "", "movies", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"movies", "a", "b"}, names)
+
+	_, err = parseTagNames([]string{strings.Repeat("x", maxTagNameLength+1)})
+	assert.Error(t, err)
+	many := make([]string, maxTagsPerRequest+1)
+	for i := range many {
+		many[i] = strconv.Itoa(i)
 	}
+	_, err = parseTagNames(many)
+	assert.Error(t, err)
+
+	for _, name := range []string{"", " ", "a,b", strings.Repeat("字", maxTagNameLength+1)} {
+		_, err := checkTagName(name)
+		assert.Error(t, err, name)
+	}
+	name, err := checkTagName(" 电影 ")
+	require.NoError(t, err)
+	assert.Equal(t, "电影", name)
+}
+
+func TestDeleteSharedLinksOfUser(t *testing.T) {
+	db := useDryRunSharedLink(t)
+	defaultHost, failover := hoststest.NewFake(), hoststest.NewFake()
+	failover.CompleteAfter = -1
+	hoststest.Register(t, "deletedefault", defaultHost)
+	hoststest.Register(t, "deletefailover", failover)
+
+	ctx := context.Background()
+	link := "magnet:?xt=urn:btih:3e5f0ef1240ff8906281955666e73d7f8a14e42b"
+	_, err := failover.CreateFromLinks(ctx, "u1", []string{link}, "test", "")
+	require.NoError(t, err)
+	failover.Complete("u1", link)
+
+	// the row of the link was served by the failover host instead of the default one.
+	var deleted []any
+	err = db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		if rows, ok := tx.Statement.Dest.(*[]*model.SharedLink); ok {
+			*rows = []*model.SharedLink{{AutoID: 7, UserID: "u1", Host: "deletefailover", OriginalLink: link}}
+		}
+	})
+	require.NoError(t, err)
+	err = db.Callback().Delete().After("gorm:delete").Register("test:delete", func(tx *gorm.DB) {
+		if tx.Statement.Table == query.SharedLink.TableName() {
+			deleted = tx.Statement.Vars
+		}
+	})
+	require.NoError(t, err)
+
+	_, err = deleteSharedLinksOfUser(ctx, "u1", "", []string{link})
+	require.NoError(t, err)
+	assert.Equal(t, []any{int64(7)}, deleted)
+
+	sharedLinks, err := failover.CreateFromLinks(ctx, "u1", []string{link}, "test", "")
+	require.NoError(t, err)
+	assert.Equal(t, share.StatusCreated, sharedLinks[link].State, "the file of the failover host is deleted")
+	assert.Equal(t, 0, defaultHost.Created())
 }
@@ -5,6 +5,7 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
@@ -15,11 +16,13 @@ import (
 	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
 	"github.com/KeepShareOrg/keepshare/pkg/i18n"
-	"github.com/KeepShareOrg/keepshare/pkg/util"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
 	"github.com/KeepShareOrg/keepshare/server/constant"
 	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
 	"github.com/KeepShareOrg/keepshare/server/query"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 const notStoredDaysMax = 36500 // to avoid exceeding the minimum time.
@@ -31,14 +34,21 @@ func storageStatistics(c *gin.Context) {
 		Host            string  `json:"host"`
 		StoredCountLt   []int32 `json:"stored_count_lt"`
 		NotStoredDaysGt []int32 `json:"not_stored_days_gt"`
+		// Tags limits the statistics to shared links with any of the tags.
+		Tags []string `json:"tags"`
 	}
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
 		return
 	}
+	tags, err := parseTagNames(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
 
 	total := len(req.StoredCountLt) + len(req.NotStoredDaysGt)
-	if total == 0 {
+	if total == 0 && len(tags) == 0 {
 		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "at least one condition is required")))
 		return
 	}
@@ -47,7 +57,11 @@ func storageStatistics(c *gin.Context) {
 		return
 	}
 
-	hostName := util.FirstNotEmpty(req.Host, config.DefaultHost())
+	host := getHostSupports(c, req.Host, "storage_statistics", nil)
+	if host == nil {
+		return
+	}
+	hostName := host.Name()
 	userID := c.GetString(constant.UserID)
 
 	colStored := query.SharedLink.Stored.ColumnName().String()
@@ -78,10 +92,18 @@ func storageStatistics(c *gin.Context) {
 		results = append(results, &sql.NullInt64{}, &sql.NullInt64{})
 	}
 
-	db := config.MySQL().WithContext(ctx).Table(query.SharedLink.TableName())
-	err := db.
+	if len(tags) > 0 {
+		selects = append(selects, "COUNT(*)", fmt.Sprintf("SUM(`%s`)", colSize))
+		results = append(results, &sql.NullInt64{}, &sql.NullInt64{})
+	}
+
+	db := config.MySQL().WithContext(ctx).Table(query.SharedLink.TableName()).
+		Where("`user_id` = ? AND `host` = ?", userID, hostName)
+	if len(tags) > 0 {
+		db = db.Where(taggedWith(userID, tags...))
+	}
+	err = db.
 		Select(strings.Join(selects, ", ")).
-		Where("`user_id` = ? AND `host` = ?", userID, hostName).
 		Row().
 		Scan(results...)
 	if err != nil {
@@ -97,6 +119,8 @@ func storageStatistics(c *gin.Context) {
 	var resp struct {
 		StoredCountLt   []data `json:"stored_count_lt"`
 		NotStoredDaysGt []data `json:"not_stored_days_gt"`
+		// Tagged is the total of shared links with the tags.
+		Tagged *data `json:"tagged,omitempty"`
 	}
 
 	var index int
@@ -116,6 +140,12 @@ func storageStatistics(c *gin.Context) {
 		})
 		index += 2
 	}
+	if len(tags) > 0 {
+		resp.Tagged = &data{
+			TotalCount: results[index].(*sql.NullInt64).Int64,
+			TotalSize:  results[index+1].(*sql.NullInt64).Int64,
+		}
+	}
 
 	c.JSON(http.StatusOK, resp)
 }
@@ -126,22 +156,31 @@ func storageRelease(c *gin.Context) {
 		StoredCountLt   int32  `json:"stored_count_lt"`
 		NotStoredDaysGt int32  `json:"not_stored_days_gt"`
 		OnlyForPremium  bool   `json:"only_for_premium"`
+		// Tags limits the release to shared links with any of the tags,
+		// all of them are released if neither stored_count_lt nor not_stored_days_gt is set.
+		Tags []string `json:"tags"`
 	}
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
 		return
 	}
+	tags, err := parseTagNames(req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
 
-	hostName := util.FirstNotEmpty(req.Host, config.DefaultHost())
 	if req.NotStoredDaysGt > notStoredDaysMax {
 		req.NotStoredDaysGt = notStoredDaysMax // Avoid exceeding the minimum time.
 	}
 
-	host := hosts.Get(hostName)
+	host := getHostSupports(c, req.Host, "release_only_for_premium", func(caps hosts.Capabilities) bool {
+		return !req.OnlyForPremium || caps.ReleaseOnlyForPremium
+	})
 	if host == nil {
-		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
 		return
 	}
+	hostName := host.Name()
 
 	ctx := c.Request.Context()
 	userID := c.GetString(constant.UserID)
@@ -149,8 +188,14 @@ func storageRelease(c *gin.Context) {
 	lastTime := time.Now().Add(time.Duration(-1*req.NotStoredDaysGt) * 24 * time.Hour)
 
 	stmt := t.WithContext(ctx).Where(t.UserID.Eq(userID), t.Host.Eq(hostName))
+	if len(tags) > 0 {
+		stmt = stmt.Where(taggedWith(userID, tags...))
+	}
 
 	switch {
+	case req.StoredCountLt <= 0 && req.NotStoredDaysGt <= 0 && len(tags) > 0:
+		// release the whole collection.
+
 	case req.StoredCountLt <= 0 && req.NotStoredDaysGt <= 0:
 		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "one of stored_count_lt or not_stored_days_gt is required")))
 		return
@@ -170,9 +215,11 @@ func storageRelease(c *gin.Context) {
 		mdw.RespInternal(c, err.Error())
 		return
 	}
-
 	if req.OnlyForPremium {
-		// TODO
+		if rows, err = premiumSharedLinks(ctx, host, userID, rows); err != nil {
+			mdw.RespInternal(c, fmt.Sprintf("filter premium shared links err: %v", err))
+			return
+		}
 	}
 
 	var resp struct {
@@ -199,7 +246,27 @@ func storageRelease(c *gin.Context) {
 		mdw.RespInternal(c, fmt.Sprintf("delete shared records err: %v", err))
 		return
 	}
+	if err := untagSharedLinks(ctx, autoIDs); err != nil {
+		log.WithContext(ctx).WithField(constant.UserID, userID).Errorf("untag released shared links err: %v", err)
+	}
 
 	resp.RowsAffected = int(ret.RowsAffected)
 	c.JSON(http.StatusOK, resp)
 }
+
+// premiumSharedLinks returns the shared links stored by premium accounts of the host.
+func premiumSharedLinks(ctx context.Context, host *hosts.HostWithProperties, userID string, rows []*model.SharedLink) ([]*model.SharedLink, error) {
+	if len(rows) == 0 {
+		return rows, nil
+	}
+	hashes := lo.Map(rows, func(row *model.SharedLink, _ int) string { return row.OriginalLinkHash })
+	premium, err := host.PremiumLinks(ctx, config.MySQL(), userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+	set := lo.SliceToMap(premium, func(h string) (string, struct{}) { return h, struct{}{} })
+	return lo.Filter(rows, func(row *model.SharedLink, _ int) bool {
+		_, ok := set[row.OriginalLinkHash]
+		return ok
+	}), nil
+}
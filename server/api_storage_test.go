@@ -0,0 +1,44 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/locale"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageReleaseOnlyForPremium(t *testing.T) {
+	useDryRunSharedLink(t)
+	require.NoError(t, i18n.Load(locale.FS))
+
+	premium := hoststest.NewFake()
+	premium.Caps.ReleaseOnlyForPremium = true
+	hoststest.Register(t, "releasepremium", premium)
+	hoststest.Register(t, "releaseplain", hoststest.NewFake())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/storage/release", storageRelease)
+
+	for host, code := range map[string]int{"releasepremium": http.StatusOK, "releaseplain": http.StatusBadRequest} {
+		body := `{"host":"` + host + `","stored_count_lt":1,"only_for_premium":true}`
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/storage/release", strings.NewReader(body)))
+		assert.Equal(t, code, w.Code, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/storage/release", strings.NewReader(`{"host":"releaseplain","stored_count_lt":1}`)))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -6,16 +6,12 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/samber/lo"
-	"github.com/spf13/viper"
-	"io"
 	"net/http"
 	"net/url"
 	"regexp"
-	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,6 +38,7 @@ const (
 	keyHostLink     = "host_link"
 	keyRedirectType = "redirect_type"
 	keyState        = "state"
+	keySignature    = "signature"
 	keyTotalMS      = "total_ms"
 )
 
@@ -51,24 +48,62 @@ func autoSharingLink(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_request", i18n.WithDataMap("error", "invalid auto sharing url")))
 		return
 	}
+	serveAutoSharingLink(c, channel, link, acceptsJSON(c))
+}
+
+// resolveAutoSharingLink responds the outcome of the auto sharing link `/<channel>/<link>` as data instead of redirects.
+func resolveAutoSharingLink(c *gin.Context) {
+	channel := strings.ToLower(strings.TrimSpace(c.Query("channel")))
+	link := strings.TrimSpace(c.Query("link"))
+	if channel == "" || link == "" {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_request", i18n.WithDataMap("error", "channel and link are required")))
+		return
+	}
+	serveAutoSharingLink(c, channel, link, true)
+}
+
+// acceptsJSON reports whether the client prefers JSON to pages, such as browser extensions and bots.
+func acceptsJSON(c *gin.Context) bool {
+	accept := c.GetHeader("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// Hints of the duration to resolve again before shared links are OK.
+const (
+	resolveRetryAfter     = 3 * time.Second
+	resolveSlowRetryAfter = 10 * time.Second
+)
+
+// resolution is the outcome of an auto sharing link in JSON mode.
+type resolution struct {
+	AutoID         int64  `json:"auto_id"`
+	State          string `json:"state"`
+	HostSharedLink string `json:"host_shared_link"`
+	Title          string `json:"title"`
+	Size           int64  `json:"size"`
+	// RedirectURL is the location redirected to without JSON mode.
+	RedirectURL string `json:"redirect_url"`
+	// RetryAfter is the seconds to resolve again, 0 means the state is final.
+	RetryAfter int `json:"retry_after"`
+}
+
+// serveAutoSharingLink gets or creates the shared link, and redirects to the host shared link or the status page.
+// The outcome is responded as resolution in JSON mode.
+func serveAutoSharingLink(c *gin.Context, channel, link string, asJSON bool) {
+	if asJSON {
+		c.Set(keyJSONMode, true)
+	}
 	if !channelIDPattern.MatchString(channel) {
 		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_channel", i18n.WithDataMap("channel", channel)))
 		return
 	}
-	linkRaw, _, ok := validateLink(link)
+	linkRaw, linkHash, ok := validateLink(link)
 	if !ok {
 		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_link", i18n.WithDataMap("link", link)))
 		return
 	}
 
 	ctx := c.Request.Context()
-	hostName := util.FirstNotEmpty(c.Query("host"), config.DefaultHost())
-	host := hosts.Get(hostName)
-	if host == nil {
-		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
-		return
-	}
-
 	user, err := query.User.WithContext(ctx).Where(query.User.Channel.Eq(channel)).Take()
 	if err != nil && !gormutil.IsNotFoundError(err) {
 		mdw.RespInternal(c, err.Error())
@@ -79,6 +114,21 @@ func autoSharingLink(c *gin.Context) {
 		return
 	}
 
+	// the host specified by the request is used only, otherwise try hosts in the order of the user's policy.
+	candidates := hostPolicy(user)
+	if hostName := c.Query("host"); hostName != "" {
+		candidates = nil
+		if host := hosts.Get(hostName); host != nil {
+			candidates = append(candidates, host)
+		}
+	}
+	if len(candidates) == 0 {
+		hostName := util.FirstNotEmpty(c.Query("host"), config.DefaultHost())
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
+		return
+	}
+	hostName := candidates[0].Name()
+
 	requestID, _ := log.RequestIDFromContext(ctx)
 	fields := Map{
 		constant.IP:        c.ClientIP(),
@@ -95,176 +145,205 @@ func autoSharingLink(c *gin.Context) {
 		keyHostLink:     "error",
 		keyRedirectType: "error",
 		keyState:        "error",
+		keyJSONMode:     asJSON,
 	})
 	defer report.Done()
 
-	shouldSkipCreateLink := false
-	if c.Query("wsl") != "" {
-		shouldSkipCreateLink = true
-	}
-	warningChannels := viper.GetStringSlice("warning_channels")
-	if warningChannels != nil {
-		log.Debugf("warning channels: %v", channel)
-		shouldSkipCreateLink = slices.Contains(warningChannels, channel)
+	// in signed mode, links without valid signatures are served only if the shared link is OK or in progress,
+	// they never create or re-create shared links on hosts.
+	var signatureErr error
+	if user.SignedMode == 1 {
+		report.Set(keySignature, "valid")
+		if signatureErr = verifyLinkSignature(user, linkHash, c.Request.URL.Query(), time.Now()); signatureErr != nil {
+			report.Set(keySignature, signatureErr.Error())
+			ctx = context.WithValue(ctx, constant.IsExistingLinkOnly, true)
+		}
 	}
 
-	l := log.WithContext(ctx)
-	// if the channel and the link are warning, forbid to create shared link
-	hitForbidden := checkForbiddenRules(ctx, channel, link)
-	if hitForbidden {
-		shouldSkipCreateLink = true
+	report.Set(keyRateLimit, "allowed")
+	shouldSkipCreateLink := c.Query("wsl") != ""
+	target := &moderationTarget{Channel: channel, UserID: user.ID, Link: link, Simple: linkRaw, Hash: linkHash}
+	if rule := moderation.evaluate(ctx, target); rule != nil {
+		report.Set(keyModerationRule, rule.AutoID)
+		switch rule.Action {
+		case moderationBlock:
+			report.Sets(Map{keyRedirectType: "blocked", constant.Error: "link_blocked"})
+			c.JSON(http.StatusForbidden, mdw.ErrResp(c, "link_blocked"))
+			return
+		case moderationRedirect:
+			// the link is not created in hosts, visitors are redirected to the whatslink info page.
+			shouldSkipCreateLink = true
+		}
 	}
 
+	l := log.WithContext(ctx)
 	ctx = context.WithValue(ctx, constant.IsShouldSkipCreateLink, shouldSkipCreateLink)
-	sh, lastState, err := createShareLinkIfNotExist(ctx, user.ID, host, link, share.AutoShare, c.ClientIP())
+	sh, lastState, err := createShareLinkIfNotExist(ctx, user, candidates, link, share.AutoShare, c.ClientIP())
+	var limited *rateLimitedError
+	if errors.As(err, &limited) {
+		respondRateLimited(c, report, limited)
+		return
+	}
+	if errors.Is(err, errCreateDenied) {
+		report.Set(constant.Error, signatureErr.Error())
+		c.JSON(http.StatusForbidden, mdw.ErrResp(c, "invalid_signature", i18n.WithDataMap("error", signatureErr.Error())))
+		return
+	}
+	if errors.Is(err, errSharedLinkExpired) {
+		report.Sets(Map{keyRedirectType: "expired", constant.Error: err.Error()})
+		c.JSON(http.StatusGone, mdw.ErrResp(c, "shared_link_expired"))
+		return
+	}
 	if err != nil {
 		report.Set(constant.Error, err.Error())
 		mdw.RespInternal(c, err.Error())
 		return
 	}
 
-	report.Set(keyState, lastState)
+	report.Sets(Map{keyState: lastState, constant.Host: sh.Host})
 	l = l.WithFields(Map{constant.SharedLink: sh.HostSharedLink, constant.ShareStatus: sh.State})
 
+	setting := userRedirectSetting(ctx, user.ID)
+	var redirectURL string
+	var retryAfter time.Duration
+	switch {
 	// if the link refer to the warning channel id, we need redirect to the whatslink info page
-	if shouldSkipCreateLink {
+	case shouldSkipCreateLink:
 		l.Debug("redirect to whatslink info page")
-		c.Redirect(http.StatusFound, fmt.Sprintf("https://%s/console/shared/wsl-status?id=%d&request_id=%s", config.RootDomain(), sh.AutoID, requestID))
-		return
-	}
+		redirectURL = fmt.Sprintf("https://%s/console/shared/wsl-status?id=%d&request_id=%s", config.RootDomain(), sh.AutoID, requestID)
 
-	switch share.State(sh.State) {
-	case share.StatusOK:
-		// We can add parameters to the PikPak sharing page to automatically play, but we need to add that only the current host is PikPak.
-		hostLink := fmt.Sprintf("%s?act=play", sh.HostSharedLink)
+	case share.State(sh.State) == share.StatusOK:
+		redirectURL = hostLinkWithParams(setting, sh.Host, sh.HostSharedLink)
 		report.Sets(Map{
 			keyRedirectType: "share",
-			keyHostLink:     hostLink,
+			keyHostLink:     redirectURL,
 		})
 		l.Debug("got shared_link")
-		c.Redirect(http.StatusFound, hostLink)
 
-	default: // include StatusSensitive
+	default: // include StatusSensitive and StatusBlocked
 		l.Debug("share status:", sh.State)
 		RecordLinkAccessLog(ctx, sh.OriginalLinkHash, GetRequestIP(c.Request))
-		statusPage := fmt.Sprintf("https://%s/console/shared/status?id=%d&request_id=%s", config.RootDomain(), sh.AutoID, requestID)
-		// skip the status page loading if not yet create host task
-		if lastState == share.StatusCreated {
-			// st: slow task, tasks that have been created for a while but have not yet been completed
-			statusPage = fmt.Sprintf("%v&st=%d", statusPage, 1)
+		switch unavailableAction(setting, share.State(sh.State)) {
+		case unavailableOriginal:
+			redirectURL = sh.OriginalLink
+			report.Sets(Map{
+				keyRedirectType: "original",
+				keyHostLink:     redirectURL,
+			})
+		case unavailableNotFound:
+			report.Sets(Map{
+				keyRedirectType: "not_found",
+				keyHostLink:     "",
+			})
+		default:
+			redirectURL = statusPageURL(setting, sh, requestID)
+			if !isFinalState(share.State(sh.State)) {
+				retryAfter = resolveRetryAfter
+			}
+			// skip the status page loading if not yet create host task
+			if lastState == share.StatusCreated {
+				// st: slow task, tasks that have been created for a while but have not yet been completed
+				redirectURL = fmt.Sprintf("%v&st=%d", redirectURL, 1)
+				retryAfter = resolveSlowRetryAfter
+			}
+			report.Sets(Map{
+				keyRedirectType: "status",
+				keyHostLink:     redirectURL,
+			})
 		}
-		report.Sets(Map{
-			keyRedirectType: "status",
-			keyHostLink:     statusPage,
-		})
-		c.Redirect(http.StatusFound, statusPage)
 	}
-}
 
-// checkForbiddenRules check if the channel and the link are forbidden
-func checkForbiddenRules(ctx context.Context, channelID, link string) bool {
-	type Rule struct {
-		ChannelID       string   `mapstructure:"channel_id"`
-		FilenameContain []string `mapstructure:"filename_contain"`
+	if !asJSON {
+		switch {
+		case redirectURL == "":
+			c.JSON(http.StatusNotFound, mdw.ErrResp(c, "shared_link_unavailable", i18n.WithDataMap("state", sh.State)))
+		case setting.Interstitial == 1 && !shouldSkipCreateLink && share.State(sh.State) == share.StatusOK:
+			report.Set(keyRedirectType, "interstitial")
+			respInterstitial(c, sharedLinkTitle(sh), redirectURL)
+		default:
+			c.Redirect(http.StatusFound, redirectURL)
+		}
+		return
 	}
-
-	var rules []*Rule
-	if err := viper.UnmarshalKey("forbidden_rules", &rules); err != nil {
-		log.Errorf("failed to unmarshal forbidden_rules: %v", err)
-		return false
+	res := &resolution{
+		AutoID:      sh.AutoID,
+		State:       sh.State,
+		Title:       sharedLinkTitle(sh),
+		Size:        sh.Size,
+		RedirectURL: redirectURL,
+		RetryAfter:  int(retryAfter.Seconds()),
 	}
-
-	rule, hit := lo.Find(rules, func(item *Rule) bool {
-		return item.ChannelID == channelID
-	})
-	if !hit || rule == nil {
-		return false
+	if share.State(sh.State) == share.StatusOK {
+		res.HostSharedLink = sh.HostSharedLink
 	}
-
-	info, err := queryLinkInfoByWhatsLinks(ctx, link)
-	if err != nil {
-		log.Errorf("query what's link info error: %v", err)
-		return false
+	if res.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(res.RetryAfter))
 	}
-	hit = lo.SomeBy(rule.FilenameContain, func(item string) bool {
-		return strings.Contains(strings.ToLower(info.Name), strings.ToLower(item))
-	})
-
-	return hit
+	c.JSON(http.StatusOK, res)
 }
 
-type WhatsLinkInfo struct {
-	Error       string `json:"error"`
-	Type        string `json:"type"`
-	FileType    string `json:"file_type"`
-	Name        string `json:"name"`
-	Size        int    `json:"size"`
-	Count       int    `json:"count"`
-	Screenshots []struct {
-		Time       int    `json:"time"`
-		Screenshot string `json:"screenshot"`
-	} `json:"screenshots"`
-}
-
-func queryLinkInfoByWhatsLinks(ctx context.Context, link string) (*WhatsLinkInfo, error) {
-	addr := fmt.Sprintf("https://whatslink.info/api/v1/link?url=%s", link)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("referer", "https://whatslink.info/")
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	bs, _ := io.ReadAll(resp.Body)
-	log.Debugf("whats link return: %s", bs)
-
-	var linkInfo *WhatsLinkInfo
-	err = json.Unmarshal(bs, &linkInfo)
-	if err != nil {
-		return nil, err
-	}
-
-	return linkInfo, nil
-}
+// errCreateDenied is returned by createShareLinkIfNotExist if the shared link has to be created or re-created,
+// but only existing shared links are allowed by the context.
+var errCreateDenied = errors.New("creating shared links is denied")
 
 // createShareLinkIfNotExist if the shared link does not exist, create a new one and return it.
-func createShareLinkIfNotExist(ctx context.Context, userID string, host *hosts.HostWithProperties, link string, createBy string, ip string) (sharedLink *model.SharedLink, lastStatus share.State, err error) {
+// The candidate hosts are tried in order, see createShareByLink.
+// If constant.IsExistingLinkOnly is set in the context, shared links which are not OK or in progress are not served.
+func createShareLinkIfNotExist(ctx context.Context, user *model.User, candidates []*hosts.HostWithProperties, link string, createBy string, ip string) (sharedLink *model.SharedLink, lastStatus share.State, err error) {
 	linkRaw, linkHash, ok := validateLink(link)
 	if !ok || linkHash == "" {
 		return nil, "", errors.New("invalid link")
 	}
+	if len(candidates) == 0 {
+		return nil, "", errors.New("no available host")
+	}
 
 	var sh *model.SharedLink
 	sh, err = query.SharedLink.WithContext(ctx).Where(
-		query.SharedLink.UserID.Eq(userID),
+		query.SharedLink.UserID.Eq(user.ID),
 		query.SharedLink.OriginalLinkHash.Eq(linkHash),
 	).Take()
 	if err != nil && !gormutil.IsNotFoundError(err) {
 		return nil, "", fmt.Errorf("query shared link error: %w", err)
 	}
 
+	existingOnly, _ := ctx.Value(constant.IsExistingLinkOnly).(bool)
 	lastStatus = share.StatusNotFound
+	if sh == nil && existingOnly {
+		return nil, lastStatus, errCreateDenied
+	}
+	if sh != nil && sharedLinkExpired(sh, time.Now()) {
+		return nil, share.State(sh.State), errSharedLinkExpired
+	}
 	if sh != nil {
-		lastStatus = getShareStatus(ctx, userID, host, sh)
+		// the status is queried from the host which served the shared link.
+		if host := hosts.Get(sh.Host); host != nil {
+			lastStatus = getShareStatus(ctx, user.ID, host, sh)
+		}
 		go updateVisitTimeAndState(ctx, sh, lastStatus)
+		if existingOnly && !inProgressOrOK(lastStatus) {
+			return nil, lastStatus, errCreateDenied
+		}
 		switch lastStatus {
 		case share.StatusUnknown, share.StatusOK, share.StatusCreated, share.StatusPending:
 			break
 
 		case share.StatusDeleted, share.StatusNotFound, share.StatusSensitive, share.StatusError:
-			sh = nil // re-create a shared link
+			// re-create a shared link, move the record to the first host to try
+			// so that the user has only one record of the link.
+			candidates = failoverHosts(candidates, sh.Host, lastStatus)
+			if name := candidates[0].Name(); name != sh.Host {
+				t := query.SharedLink
+				if _, err := t.WithContext(ctx).Where(t.AutoID.Eq(sh.AutoID)).UpdateSimple(t.Host.Value(name)); err != nil {
+					return nil, lastStatus, fmt.Errorf("update shared link host error: %w", err)
+				}
+			}
+			sh = nil
 
 		case share.StatusBlocked:
-			return nil, lastStatus, errors.New("link_blocked")
+			// blocked by the host, the record is returned to be handled by the blocked action of the redirect setting.
+			sh.State = lastStatus.String()
+			return sh, lastStatus, nil
 
 		default:
 			return nil, lastStatus, fmt.Errorf("unexpected share status: %s", lastStatus)
@@ -272,7 +351,19 @@ func createShareLinkIfNotExist(ctx context.Context, userID string, host *hosts.H
 	}
 
 	if sh == nil {
-		sh, err = createShareByLink(ctx, userID, host, linkRaw, createBy, ip)
+		// new host tasks are limited by the channel and the client IP of auto sharing, and globally,
+		// unless the creation is skipped. Existing shared links are never limited.
+		if skip, _ := ctx.Value(constant.IsShouldSkipCreateLink).(bool); !skip {
+			if createBy == share.AutoShare {
+				if e := limitAutoSharing(ctx, user, ip); e != nil {
+					return nil, lastStatus, e
+				}
+			}
+			if e := limitHostTasks(ctx); e != nil {
+				return nil, lastStatus, e
+			}
+		}
+		sh, err = createShareByLink(ctx, user.ID, candidates, linkRaw, createBy, ip)
 		if err != nil {
 			return nil, lastStatus, fmt.Errorf("create share error: %w", err)
 		}
@@ -280,6 +371,15 @@ func createShareLinkIfNotExist(ctx context.Context, userID string, host *hosts.H
 	return sh, lastStatus, nil
 }
 
+// inProgressOrOK reports whether the shared link in the state is served without creating it again.
+func inProgressOrOK(state share.State) bool {
+	switch state {
+	case share.StatusUnknown, share.StatusOK, share.StatusCreated, share.StatusPending:
+		return true
+	}
+	return false
+}
+
 func getChannelAndLinkFromURL(u *url.URL) (channel, link string, ok bool) {
 	path := strings.TrimPrefix(u.Path, "/")
 	channel, link, _ = strings.Cut(path, "/")
@@ -289,7 +389,7 @@ func getChannelAndLinkFromURL(u *url.URL) (channel, link string, ok bool) {
 		return "", "", false
 	}
 
-	// compatible for not url encoded magnet
+	// compatible for not url encoded magnet, other parameters such as the signature are not parts of the link.
 	if link == "magnet:" {
 		link = link + "?xt=" + u.Query().Get("xt")
 	}
@@ -317,14 +417,18 @@ func validateLink(link string) (simple string, hash string, ok bool) {
 		return "", "", false
 	}
 
-	u, _ := url.Parse(link)
-	if u == nil || u.Scheme == "" {
-		return "", "", false
-	}
-
 	simple = lk.Simplify(link)
 	hash = lk.Hash(link)
 
+	// ed2k links can not be parsed as urls, they are valid only with an ed2k hash.
+	if strings.HasPrefix(simple, lk.Ed2kPrefix) {
+		if len(hash) != 32 {
+			return "", "", false
+		}
+	} else if u, _ := url.Parse(simple); u == nil || u.Scheme == "" {
+		return "", "", false
+	}
+
 	if hash == "" {
 		return "", "", false
 	}
@@ -332,13 +436,16 @@ func validateLink(link string) (simple string, hash string, ok bool) {
 	return simple, hash, true
 }
 
-func createShareByLink(ctx context.Context, userID string, host *hosts.HostWithProperties, link string, createBy string, ip string) (s *model.SharedLink, err error) {
+// createShareByLink creates the shared record with the first candidate host,
+// and creates the shared link on hosts in background. If a host fails or flags the content as sensitive,
+// the next host is tried, the record is updated with the host which served it at last.
+func createShareByLink(ctx context.Context, userID string, candidates []*hosts.HostWithProperties, link string, createBy string, ip string) (s *model.SharedLink, err error) {
 	now := time.Now()
 	s = &model.SharedLink{
 		State:            string(share.StatusCreated),
 		UserID:           userID,
 		CreatedBy:        createBy,
-		Host:             host.Name(),
+		Host:             candidates[0].Name(),
 		CreatedAt:        now,
 		UpdatedAt:        now,
 		OriginalLinkHash: lk.Hash(link),
@@ -368,21 +475,35 @@ func createShareByLink(ctx context.Context, userID string, host *hosts.HostWithP
 	}
 	go func() {
 		ctx = context.Background()
-		sharedLinks, err := host.CreateFromLinks(ctx, userID, []string{link}, createBy, ip)
-		if err != nil {
-			log.WithContext(ctx).WithField("shared_record", s).Error(fmt.Errorf("create share from links err: %w", err))
-			return
+		l := log.WithContext(ctx).WithField("shared_record", s)
+
+		var served *hosts.HostWithProperties
+		var sh *share.Share
+		for i, host := range candidates {
+			sharedLinks, err := host.CreateFromLinks(ctx, userID, []string{link}, createBy, ip)
+			if err != nil {
+				l.WithField(constant.Host, host.Name()).Error(fmt.Errorf("create share from links err: %w", err))
+				continue
+			}
+
+			if sharedLinks[link] == nil {
+				l.WithField(constant.Host, host.Name()).Error(errors.New("get nil keepshare"))
+				continue
+			}
+			served, sh = host, sharedLinks[link]
+			if (sh.State == share.StatusSensitive || sh.State == share.StatusError) && i < len(candidates)-1 {
+				l.WithField(constant.Host, host.Name()).Infof("share state is %s, try next host", sh.State)
+				continue
+			}
+			break
 		}
-
-		sh := sharedLinks[link]
-
 		if sh == nil {
-			log.WithContext(ctx).WithField("shared_record", s).Error(errors.New("get nil keepshare"))
 			return
 		}
 
 		update := &model.SharedLink{
 			State:              sh.State.String(),
+			Host:               served.Name(),
 			Size:               sh.Size,
 			Visitor:            sh.Visitor,
 			Stored:             sh.Stored,
@@ -392,12 +513,13 @@ func createShareByLink(ctx context.Context, userID string, host *hosts.HostWithP
 			HostSharedLink:     sh.HostSharedLink,
 			Error:              sh.Error,
 		}
-		log.WithContext(ctx).WithField("shared_record", s).Infof("sharedLinks update :%+v", update)
-		_, err = t.WithContext(ctx).Where(t.AutoID.Eq(s.AutoID)).Updates(update)
+		l.Infof("sharedLinks update :%+v", update)
+		_, err = updateSharedLinks(ctx, update, t.AutoID.Eq(s.AutoID))
 		if err != nil {
-			log.WithContext(ctx).WithField("shared_record", s).WithField("autoID", s.AutoID).Error(errors.New("get nil share"))
+			l.WithField("autoID", s.AutoID).Error(errors.New("get nil share"))
 			return
 		}
+		notifySharedLinksUpdated(ctx, s.OriginalLinkHash)
 	}()
 
 	return s, nil
@@ -410,15 +532,6 @@ func getShareStatus(ctx context.Context, userID string, host hosts.Host, record
 		return share.State(record.State)
 	}
 
-	const statusCacheTTL = time.Minute
-	id := record.AutoID
-	key := fmt.Sprintf("status:%d", id)
-
-	cache, _ := config.Redis().Get(ctx, key).Result()
-	if cache != "" {
-		return share.State(cache)
-	}
-
 	sts, err := host.GetStatuses(ctx, userID, []string{link})
 	if err != nil {
 		log.WithContext(ctx).WithFields(Map{
@@ -434,11 +547,7 @@ func getShareStatus(ctx context.Context, userID string, host hosts.Host, record
 		status = share.StatusUnknown
 	}
 
-	async.Run(func() { getStatisticsLater(id) })
-
-	if status == share.StatusOK {
-		config.Redis().Set(ctx, key, status.String(), statusCacheTTL)
-	}
+	async.Run(func() { getStatisticsLater(record.AutoID) })
 	return status
 }
 
@@ -446,7 +555,6 @@ func getShareStatus(ctx context.Context, userID string, host hosts.Host, record
 func updateVisitTimeAndState(ctx context.Context, record *model.SharedLink, status share.State) {
 	now := time.Now()
 	updates := &model.SharedLink{
-		AutoID:        record.AutoID,
 		LastVisitedAt: now,
 		UpdatedAt:     now,
 	}
@@ -461,5 +569,8 @@ func updateVisitTimeAndState(ctx context.Context, record *model.SharedLink, stat
 		return
 	}
 
-	_, _ = query.SharedLink.WithContext(ctx).Updates(updates)
+	_, _ = updateSharedLinks(ctx, updates, query.SharedLink.AutoID.Eq(record.AutoID))
+	if updates.State != "" {
+		notifySharedLinksUpdated(ctx, record.OriginalLinkHash)
+	}
 }
@@ -0,0 +1,99 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/locale"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateLink(t *testing.T) {
+	tests := []struct {
+		link   string
+		simple string
+		hash   string
+		ok     bool
+	}{
+		{"/magnet:?xt=urn:btih:HZPQ54JEB74JAYUBSVLGNZZ5P6FBJZBL&dn=a", "magnet:?xt=urn:btih:3e5f0ef1240ff8906281955666e73d7f8a14e42b", "3e5f0ef1240ff8906281955666e73d7f8a14e42b", true},
+		{"ed2k://|file|a.mp4|1024|31D6CFE0D16AE931B73C59D7E0C089C0|/", "ed2k://|file|a.mp4|1024|31d6cfe0d16ae931b73c59d7e0c089c0|/", "31d6cfe0d16ae931b73c59d7e0c089c0", true},
+		{"thunder://QUFodHRwOi8vZXhhbXBsZS5jb20vYS5tcDRaWg==", "http://example.com/a.mp4", "cffc433b64f3a965f2921c817ee017c2dcae185d", true},
+		{"ed2k://|file|a.mp4|1024|xyz|/", "", "", false},
+		{"example.com/a.mp4", "", "", false},
+		{" ", "", "", false},
+	}
+
+	for _, tt := range tests {
+		simple, hash, ok := validateLink(tt.link)
+		assert.Equal(t, tt.ok, ok, tt.link)
+		if ok {
+			assert.Equal(t, tt.simple, simple)
+			assert.Equal(t, tt.hash, hash)
+		}
+	}
+}
+
+func TestResolveAutoSharingLinkParams(t *testing.T) {
+	require.NoError(t, i18n.Load(locale.FS))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/resolve", resolveAutoSharingLink)
+
+	for _, target := range []string{
+		"/api/resolve",
+		"/api/resolve?channel=abcd1234",
+		"/api/resolve?channel=ABC&link=" + url.QueryEscape("https://example.com/a.mp4"),
+		"/api/resolve?channel=abcd1234&link=example.com",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json", target)
+	}
+}
+
+func TestAcceptsJSON(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                                  false,
+		"*/*":                               false,
+		"application/json":                  true,
+		"application/json, text/plain, */*": true,
+		"text/html,application/xhtml+xml,*/*;q=0.8": false,
+	} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/abcd1234/https://example.com/a.mp4", nil)
+		c.Request.Header.Set("Accept", accept)
+		assert.Equal(t, want, acceptsJSON(c), accept)
+	}
+}
+
+func TestAutoRouter(t *testing.T) {
+	require.NoError(t, i18n.Load(locale.FS))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.NoRoute(autoRouter)
+
+	// the links with control characters are rejected by autoSharingLink, other paths are redirected to the home page.
+	for target, code := range map[string]int{
+		"/abcd1234/thunder:%7F": http.StatusBadRequest,
+		"/abcd1234/THUNDER:%7F": http.StatusBadRequest,
+		"/abcd1234/magnet:%7F":  http.StatusBadRequest,
+		"/abcd1234/ed2k:%7F":    http.StatusBadRequest,
+		"/abcd1234/other:%7F":   http.StatusFound,
+		"/abcd/thunder:%7F":     http.StatusFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, code, w.Code, target)
+	}
+}
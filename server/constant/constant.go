@@ -44,6 +44,7 @@ const (
 	AsyncQueueStatisticTask    = "statistic"
 	AsyncQueueResetPassword    = "reset_password"
 	AsyncQueueRefreshToken     = "refresh_token"
+	AsyncQueueImportJob        = "import_job"
 )
 
 // enum all statuses.
@@ -60,4 +61,6 @@ const (
 
 const (
 	IsShouldSkipCreateLink = "skip_create_link"
+	// IsExistingLinkOnly is set if only existing shared links which are OK or in progress can be served.
+	IsExistingLinkOnly = "existing_link_only"
 )
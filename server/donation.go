@@ -7,7 +7,6 @@ import (
 	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
 	"github.com/KeepShareOrg/keepshare/server/query"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm/utils"
 	"net/http"
 	"regexp"
 	"strings"
@@ -29,8 +28,14 @@ func donationRedeemCode(c *gin.Context) {
 	}
 
 	hostName := strings.ToLower(req.Drive)
-	if !utils.Contains([]string{"pikpak"}, hostName) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid drive"})
+	host := hosts.Get(hostName)
+	if host == nil {
+		log.Errorf("invalid host: %s", hostName)
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
+		return
+	}
+	if !host.Capabilities().RedeemCodes {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "unsupported_operation", i18n.WithDataMap("host", hostName, "operation", "donate_redeem_codes")))
 		return
 	}
 
@@ -43,12 +48,6 @@ func donationRedeemCode(c *gin.Context) {
 	if len(nickname) > 128 {
 		nickname = req.Nickname[:128]
 	}
-	host := hosts.Get(hostName)
-	if host == nil {
-		log.Errorf("invalid host: %s", hostName)
-		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
-		return
-	}
 	channelID := strings.TrimSpace(req.ChannelID)
 
 	targetID := ""
@@ -0,0 +1,256 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gen"
+	"gorm.io/gorm"
+)
+
+// Formats of exported shared links.
+const (
+	exportCSV   = "csv"
+	exportJSONL = "jsonl"
+)
+
+// exportBatchSize is the number of shared links read at a time, which bounds the memory of exports.
+const exportBatchSize = 500
+
+// exportedSharedLink is a row of exported shared links.
+type exportedSharedLink struct {
+	ID              int64     `json:"id"`
+	State           string    `json:"state"`
+	Host            string    `json:"host"`
+	Title           string    `json:"title"`
+	Size            int64     `json:"size"`
+	Visitor         int32     `json:"visitor"`
+	Stored          int32     `json:"stored"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastVisitedAt   time.Time `json:"last_visited_at"`
+	OriginalLink    string    `json:"original_link"`
+	HostSharedLink  string    `json:"host_shared_link"`
+	KeepSharingLink string    `json:"keep_sharing_link"`
+}
+
+var exportCSVHeader = []string{
+	"id", "state", "host", "title", "size", "visitor", "stored", "created_by", "created_at", "last_visited_at",
+	"original_link", "host_shared_link", "keep_sharing_link",
+}
+
+// csvCell prevents cells from being evaluated as formulas by spreadsheets.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsAny(s[:1], "=+-@\t\r") {
+		return "'" + s
+	}
+	return s
+}
+
+func (e *exportedSharedLink) csvRecord(loc *time.Location) []string {
+	const layout = "2006-01-02 15:04:05"
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		e.State,
+		e.Host,
+		csvCell(e.Title),
+		strconv.FormatInt(e.Size, 10),
+		strconv.Itoa(int(e.Visitor)),
+		strconv.Itoa(int(e.Stored)),
+		e.CreatedBy,
+		e.CreatedAt.In(loc).Format(layout),
+		e.LastVisitedAt.In(loc).Format(layout),
+		csvCell(e.OriginalLink),
+		csvCell(e.HostSharedLink),
+		csvCell(e.KeepSharingLink),
+	}
+}
+
+// sharedLinkWriter writes shared links in the format.
+type sharedLinkWriter struct {
+	loc   *time.Location
+	csv   *csv.Writer
+	json  *json.Encoder
+	flush func() error
+}
+
+func newSharedLinkWriter(w io.Writer, format string, loc *time.Location) (*sharedLinkWriter, error) {
+	sw := &sharedLinkWriter{loc: loc}
+	switch format {
+	case exportCSV:
+		sw.csv = csv.NewWriter(w)
+		sw.flush = func() error {
+			sw.csv.Flush()
+			return sw.csv.Error()
+		}
+		if err := sw.csv.Write(exportCSVHeader); err != nil {
+			return nil, err
+		}
+	case exportJSONL:
+		sw.json = json.NewEncoder(w)
+		sw.json.SetEscapeHTML(false)
+		sw.flush = func() error { return nil }
+	default:
+		return nil, fmt.Errorf("unsupported format %q, supported formats: %s, %s", format, exportCSV, exportJSONL)
+	}
+	return sw, nil
+}
+
+func (sw *sharedLinkWriter) write(e *exportedSharedLink) error {
+	if sw.csv != nil {
+		return sw.csv.Write(e.csvRecord(sw.loc))
+	}
+	e.CreatedAt, e.LastVisitedAt = e.CreatedAt.In(sw.loc), e.LastVisitedAt.In(sw.loc)
+	return sw.json.Encode(e)
+}
+
+// writeSharedLinks writes the shared links of the user matching the conditions in the order,
+// they are read in batches by keyset pagination, so that the memory is bounded however many they are.
+// w is flushed after each batch if it is a http.Flusher. It returns the number of shared links written.
+func writeSharedLinks(ctx context.Context, w io.Writer, format string, user *model.User, conditions []gen.Condition, sort *sharedLinkSort) (int64, error) {
+	sw, err := newSharedLinkWriter(w, format, userLocation(user))
+	if err != nil {
+		return 0, err
+	}
+
+	t := query.SharedLink
+	conditions = append(conditions, t.UserID.Eq(user.ID))
+	var (
+		cursor  *sharedLinkCursor
+		written int64
+	)
+	for {
+		do := t.WithContext(ctx).Where(conditions...).Order(sort.orders()...)
+		if cursor != nil {
+			do = do.Where(sort.after(cursor))
+		}
+		rows, err := do.Limit(exportBatchSize).Find()
+		if err != nil {
+			return written, err
+		}
+
+		for _, s := range rows {
+			err := sw.write(&exportedSharedLink{
+				ID:              s.AutoID,
+				State:           s.State,
+				Host:            s.Host,
+				Title:           sharedLinkTitle(s),
+				Size:            s.Size,
+				Visitor:         s.Visitor,
+				Stored:          s.Stored,
+				CreatedBy:       s.CreatedBy,
+				CreatedAt:       s.CreatedAt,
+				LastVisitedAt:   s.LastVisitedAt,
+				OriginalLink:    s.OriginalLink,
+				HostSharedLink:  s.HostSharedLink,
+				KeepSharingLink: makeKeepSharingLink(user.Channel, s.OriginalLink),
+			})
+			if err != nil {
+				return written, err
+			}
+			written++
+		}
+		if err := sw.flush(); err != nil {
+			return written, err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if len(rows) < exportBatchSize {
+			return written, nil
+		}
+		cursor = newSharedLinkCursor(sort, rows[len(rows)-1])
+	}
+}
+
+// exportSharedLinks streams the shared links of the current user matching the filter and search parameters,
+// which are the same as listSharedLinks.
+func exportSharedLinks(c *gin.Context) {
+	format := c.DefaultQuery("format", exportCSV)
+	if format != exportCSV && format != exportJSONL {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "format must be csv or jsonl")))
+		return
+	}
+	sort, err := parseSharedLinkSort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	conditions, ok := sharedLinkConditions(c, user)
+	if !ok {
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == exportJSONL {
+		contentType = "application/x-ndjson; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="keepshare-shared-links-%s.%s"`, time.Now().Format("20060102150405"), format))
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	start := time.Now()
+	n, err := writeSharedLinks(ctx, c.Writer, format, user, conditions, sort)
+	l := log.WithContext(ctx).WithFields(Map{constant.UserID: user.ID, "format": format, "rows": n, keyTotalMS: time.Since(start).Milliseconds()})
+	if err != nil {
+		// the status has been sent, the response is truncated.
+		l.Errorf("export shared links err: %v", err)
+		return
+	}
+	l.Info("export shared links done")
+}
+
+// ExportSharedLinks writes the shared links of the user matching the search DSL to w in the format csv or jsonl.
+// The user is the id, email or channel of the user. It is used by the command `keepshare links export`,
+// and sets the default query to the db.
+func ExportSharedLinks(ctx context.Context, db *gorm.DB, w io.Writer, user, format, search, sort string) (int64, error) {
+	query.SetDefault(db)
+	initQueryTypes()
+
+	t := query.User
+	u, err := t.WithContext(ctx).Where(t.WithContext(ctx).Where(t.ID.Eq(user)).Or(t.Email.Eq(user)).Or(t.Channel.Eq(user))).Take()
+	if err != nil {
+		return 0, fmt.Errorf("query user %s err: %w", user, err)
+	}
+
+	order, err := parseSharedLinkSort(sort)
+	if err != nil {
+		return 0, err
+	}
+	var conditions []gen.Condition
+	if search = strings.TrimSpace(search); search != "" {
+		cond, err := compileQueryDSL(search, u.ID, userLocation(u))
+		if err != nil {
+			return 0, err
+		}
+		conditions = append(conditions, cond)
+	}
+	return writeSharedLinks(ctx, w, format, u, conditions, order)
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedLinkWriter(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	row := func() *exportedSharedLink {
+		return &exportedSharedLink{
+			ID:              1,
+			State:           "OK",
+			Host:            "pikpak",
+			Title:           "=HYPERLINK(\"x\")",
+			Size:            1 << 30,
+			Visitor:         2,
+			Stored:          1,
+			CreatedBy:       "auto_share",
+			CreatedAt:       created,
+			LastVisitedAt:   created.Add(time.Hour),
+			OriginalLink:    "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567",
+			HostSharedLink:  "https://mypikpak.com/s/abc",
+			KeepSharingLink: "https://keepshare.org/abcd1234/magnet%3A",
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := newSharedLinkWriter(&buf, exportCSV, loc)
+	require.NoError(t, err)
+	require.NoError(t, w.write(row()))
+	require.NoError(t, w.write(row()))
+	require.NoError(t, w.flush())
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, exportCSVHeader, records[0])
+	assert.Equal(t, []string{
+		"1", "OK", "pikpak", "'=HYPERLINK(\"x\")", "1073741824", "2", "1", "auto_share", "2023-01-02 11:04:05", "2023-01-02 12:04:05",
+		"magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567", "https://mypikpak.com/s/abc", "https://keepshare.org/abcd1234/magnet%3A",
+	}, records[1])
+
+	buf.Reset()
+	w, err = newSharedLinkWriter(&buf, exportJSONL, loc)
+	require.NoError(t, err)
+	require.NoError(t, w.write(row()))
+	require.NoError(t, w.write(row()))
+	require.NoError(t, w.flush())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &got))
+	assert.Equal(t, "=HYPERLINK(\"x\")", got["title"], "only csv cells are escaped")
+	assert.Equal(t, "2023-01-02T11:04:05+08:00", got["created_at"])
+	assert.Equal(t, "https://keepshare.org/abcd1234/magnet%3A", got["keep_sharing_link"])
+
+	_, err = newSharedLinkWriter(&buf, "xml", loc)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/chaos"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/spf13/viper"
+)
+
+// hostMiddlewares returns the middlewares to wrap all hosts in the configured order,
+// the fault injection of `chaos` is the innermost one if it is enabled.
+func hostMiddlewares() ([]hosts.Middleware, error) {
+	var middlewares []hosts.Middleware
+	for _, name := range strings.Split(config.HostMiddlewares(), ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+		case "logging":
+			middlewares = append(middlewares, hosts.Logging())
+		case "metrics":
+			middlewares = append(middlewares, hosts.Metrics())
+		case "status_cache":
+			if ttl := config.HostStatusCacheTTL(); ttl > 0 {
+				middlewares = append(middlewares, hosts.StatusCache(config.Redis(), ttl))
+			}
+		case "retry":
+			if attempts := config.HostRetryAttempts(); attempts > 1 {
+				middlewares = append(middlewares, hosts.Retry(attempts, config.HostRetryBackoff()))
+			}
+		default:
+			return nil, fmt.Errorf("unknown host middleware: %s", name)
+		}
+	}
+
+	var c chaos.Config
+	if err := viper.UnmarshalKey("chaos", &c); err != nil {
+		return nil, fmt.Errorf("unmarshal chaos config err: %w", err)
+	}
+	if c.Enabled {
+		log.WithField("config", c).Warn("chaos: fault injection of hosts is enabled")
+		middlewares = append(middlewares, chaos.Middleware(&c, events))
+	}
+	return middlewares, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// parseHostPolicy splits the host policy such as `pikpak,webdav` into host names.
+func parseHostPolicy(policy string) []string {
+	names := strings.FieldsFunc(strings.ToLower(policy), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	return lo.Uniq(names)
+}
+
+// hostPolicy returns the registered hosts to create shared links for the user in order,
+// the user's own policy is preferred, then the configured policy and the default host.
+func hostPolicy(user *model.User) []*hosts.HostWithProperties {
+	policy := config.HostPolicy()
+	if user != nil && user.HostPolicy != "" {
+		policy = user.HostPolicy
+	}
+
+	var candidates []*hosts.HostWithProperties
+	for _, name := range parseHostPolicy(policy) {
+		if host := hosts.Get(name); host != nil {
+			candidates = append(candidates, host)
+		}
+	}
+	if len(candidates) == 0 {
+		if host := hosts.Get(config.DefaultHost()); host != nil {
+			candidates = append(candidates, host)
+		}
+	}
+	return candidates
+}
+
+// failoverHosts returns the hosts to re-create a shared link which was served by the host with the state.
+// If the content was flagged as sensitive, the host and the hosts before it are skipped unless it is the last one.
+func failoverHosts(candidates []*hosts.HostWithProperties, servedBy string, state share.State) []*hosts.HostWithProperties {
+	if state != share.StatusSensitive {
+		return candidates
+	}
+
+	_, i, found := lo.FindIndexOf(candidates, func(h *hosts.HostWithProperties) bool {
+		return h.Name() == strings.ToLower(servedBy)
+	})
+	if !found || i == len(candidates)-1 {
+		return candidates
+	}
+	return candidates[i+1:]
+}
+
+func getHostPolicy(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{
+		"hosts": parseHostPolicy(user.HostPolicy),
+		"effective": lo.Map(hostPolicy(user), func(h *hosts.HostWithProperties, _ int) string {
+			return h.Name()
+		}),
+	})
+}
+
+func setHostPolicy(c *gin.Context) {
+	var req struct {
+		Hosts []string `json:"hosts"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	names := parseHostPolicy(strings.Join(req.Hosts, ","))
+	policy := strings.Join(names, ",")
+	if len(policy) > 64 {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "too many hosts")))
+		return
+	}
+	for _, name := range names {
+		if hosts.Get(name) == nil {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", name)))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString(constant.UserID)
+	if _, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(userID)).Update(query.User.HostPolicy, policy); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"hosts": names})
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostPolicy(t *testing.T) {
+	for _, name := range []string{"policya", "policyb", "policyc"} {
+		hoststest.Register(t, name, hoststest.NewFake())
+	}
+
+	names := func(hs []*hosts.HostWithProperties) []string {
+		return lo.Map(hs, func(h *hosts.HostWithProperties, _ int) string { return h.Name() })
+	}
+
+	assert.Equal(t, []string{"a", "b"}, parseHostPolicy(" A, b,a ,"))
+
+	viper.Set("host_default", "policyc")
+	viper.Set("host_policy", "")
+	assert.Equal(t, []string{"policyc"}, names(hostPolicy(&model.User{})))
+
+	viper.Set("host_policy", "policyb,policya")
+	assert.Equal(t, []string{"policyb", "policya"}, names(hostPolicy(&model.User{})))
+
+	candidates := hostPolicy(&model.User{HostPolicy: "policya,unknown,policyb,policyc"})
+	assert.Equal(t, []string{"policya", "policyb", "policyc"}, names(candidates))
+
+	assert.Equal(t, []string{"policya", "policyb", "policyc"}, names(failoverHosts(candidates, "policya", share.StatusError)))
+	assert.Equal(t, []string{"policyb", "policyc"}, names(failoverHosts(candidates, "policya", share.StatusSensitive)))
+	assert.Equal(t, []string{"policyc"}, names(failoverHosts(candidates, "policyb", share.StatusSensitive)))
+	assert.Equal(t, []string{"policya", "policyb", "policyc"}, names(failoverHosts(candidates, "policyc", share.StatusSensitive)))
+	assert.Equal(t, []string{"policya", "policyb", "policyc"}, names(failoverHosts(candidates, "other", share.StatusSensitive)))
+}
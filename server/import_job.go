@@ -0,0 +1,531 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/ratelimit"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/pkg/util"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	importTask = "import_job"
+
+	maxImportLinks    = 100000
+	maxImportFileSize = 64 << 20
+	// importChunkSize is the number of links created on the host by a task.
+	importChunkSize = 20
+	// importBatchSize is the number of shared links queried or inserted at a time.
+	importBatchSize = 1000
+	// importCheckInterval is the interval to check the shared links not in final states after created on the host,
+	// they are counted as errors if not completed in importCheckTimeout after the job is created.
+	importCheckInterval = time.Minute
+	importCheckTimeout  = 24 * time.Hour
+)
+
+// States of import jobs, which are computed from the counters.
+const (
+	importJobRunning = "running"
+	importJobDone    = "done"
+)
+
+var errTooManyImportLinks = fmt.Errorf("up to %d links are allowed", maxImportLinks)
+
+// readImportLinks reads the links of an upload, a link per line for text and the link column for CSV.
+// The link column is the one named link, original_link or url in the header, otherwise the first column,
+// and the first row is a link if there is no such header. Empty lines and lines starting with `#` are skipped.
+func readImportLinks(r io.Reader, isCSV bool, maxLinks int) ([]string, error) {
+	var links []string
+	add := func(s string) error {
+		if s = strings.TrimSpace(s); s == "" || strings.HasPrefix(s, "#") {
+			return nil
+		}
+		if len(links) >= maxLinks {
+			return errTooManyImportLinks
+		}
+		links = append(links, s)
+		return nil
+	}
+
+	if !isCSV {
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		for sc.Scan() {
+			if err := add(sc.Text()); err != nil {
+				return nil, err
+			}
+		}
+		return links, sc.Err()
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+	column := -1
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return links, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if column < 0 {
+			column = 0
+			i := slices.IndexFunc(record, func(s string) bool {
+				s = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(s, "\ufeff")))
+				return s == "link" || s == "original_link" || s == "url"
+			})
+			if i >= 0 {
+				column = i
+				continue
+			}
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+		}
+		if column < len(record) {
+			if err := add(record[column]); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// classifyImportLinks returns the pending shared links of the valid links in the order of the upload,
+// and the numbers of invalid links and links repeated in the upload.
+func classifyImportLinks(userID, hostName string, links []string) (tasks []*model.SharedLink, invalid, duplicate int) {
+	seen := make(map[string]struct{}, len(links))
+	for _, link := range links {
+		simple, _, ok := validateLink(link)
+		if !ok {
+			invalid++
+			continue
+		}
+		task := pendingSharedLink(userID, hostName, simple)
+		if _, ok := seen[task.OriginalLinkHash]; ok {
+			duplicate++
+			continue
+		}
+		seen[task.OriginalLinkHash] = struct{}{}
+		tasks = append(tasks, task)
+	}
+	return
+}
+
+// importChunkDelay returns the interval between chunks of an import job to create links at the rate of the limit.
+func importChunkDelay(limit ratelimit.Limit, chunkSize int) time.Duration {
+	if limit.Unlimited() {
+		return 0
+	}
+	return limit.Period * time.Duration(chunkSize) / time.Duration(limit.Burst)
+}
+
+// importJobProgress is the response of import jobs.
+type importJobProgress struct {
+	*model.ImportJob
+	State string `json:"state"`
+	// Pending is the number of accepted links which are not created on the host yet.
+	Pending int32 `json:"pending"`
+}
+
+func newImportJobProgress(job *model.ImportJob) *importJobProgress {
+	p := &importJobProgress{ImportJob: job, State: importJobDone}
+	if pending := job.Accepted - job.Moderated - job.Ok - job.Error; pending > 0 {
+		p.State, p.Pending = importJobRunning, pending
+	}
+	return p
+}
+
+// importSource returns the upload of the multipart form field `file` or the request body,
+// it is CSV if the file name ends with .csv, the content type is text/csv or the format parameter is csv.
+func importSource(c *gin.Context) (r io.ReadCloser, filename string, isCSV bool, err error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+	contentType := c.ContentType()
+	if contentType == gin.MIMEMultipartPOSTForm {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", false, err
+		}
+		if r, err = fh.Open(); err != nil {
+			return nil, "", false, err
+		}
+		filename, contentType = fh.Filename, fh.Header.Get("Content-Type")
+	} else {
+		r = c.Request.Body
+	}
+	isCSV = c.Query("format") == "csv" || strings.EqualFold(path.Ext(filename), ".csv") || strings.HasPrefix(contentType, "text/csv")
+	return r, filename, isCSV, nil
+}
+
+// createImportJob imports up to maxImportLinks links of a text or CSV upload to the host.
+// Valid links which are new to the user are saved as pending shared links and created on the host
+// by queued tasks at the rate of config.RateLimitImport, the progress is returned by getImportJob.
+func createImportJob(c *gin.Context) {
+	hostName := util.FirstNotEmpty(c.Query("host"), config.DefaultHost())
+	if hosts.Get(hostName) == nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
+		return
+	}
+
+	r, filename, isCSV, err := importSource(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	links, err := readImportLinks(r, isCSV, maxImportLinks)
+	r.Close()
+	if errors.Is(err, errTooManyImportLinks) {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "submit_too_many_links", i18n.WithDataMap("count", strconv.Itoa(maxImportLinks))))
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	if len(links) == 0 {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "links_is_empty"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString(constant.UserID)
+	tasks, invalid, duplicate := classifyImportLinks(userID, hostName, links)
+	job := &model.ImportJob{
+		UserID:    userID,
+		Host:      hostName,
+		Filename:  filename,
+		Total:     int32(len(links)),
+		Invalid:   int32(invalid),
+		Duplicate: int32(duplicate),
+	}
+	if err := startImportJob(ctx, job, tasks); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, newImportJobProgress(job))
+}
+
+// startImportJob saves the job and the pending shared links which are new to the user, the counters of the job are updated in place.
+// The shared links are created on the host by queued tasks at the rate of config.RateLimitImport.
+func startImportJob(ctx context.Context, job *model.ImportJob, tasks []*model.SharedLink) error {
+	limit, err := ratelimit.ParseLimit(config.RateLimitImport())
+	if err != nil {
+		return err
+	}
+
+	job.CreatedAt, job.UpdatedAt = time.Now(), time.Now()
+	if err := query.ImportJob.WithContext(ctx).Create(job); err != nil {
+		return err
+	}
+	l := log.WithContext(ctx).WithFields(Map{constant.UserID: job.UserID, constant.Host: job.Host, "import_job": job.ID})
+
+	ids, err := insertImportLinks(ctx, job.UserID, job.Host, tasks)
+	if err != nil {
+		l.Errorf("insert import links err: %v", err)
+		return err
+	}
+	job.Accepted = int32(len(ids))
+	job.Duplicate += int32(len(tasks) - len(ids))
+
+	// the links of chunks which can not be queued are failed at once.
+	delay := importChunkDelay(limit, importChunkSize)
+	var failed []int64
+	for i, chunk := range lo.Chunk(ids, importChunkSize) {
+		payload, _ := json.Marshal(importChunkMessage{JobID: job.ID, IDs: chunk})
+		_, err := queue.Enqueue(importTask, payload, asynq.Queue(constant.AsyncQueueImportJob), asynq.ProcessIn(time.Duration(i)*delay))
+		if err != nil {
+			l.Errorf("enqueue import chunk %d err: %v", i, err)
+			failed = append(failed, chunk...)
+		}
+	}
+	if len(failed) > 0 {
+		t := query.SharedLink
+		_, _ = t.WithContext(ctx).Where(t.AutoID.In(failed...)).UpdateSimple(t.State.Value(share.StatusError.String()), t.Error.Value("enqueue failed"))
+		job.Error = int32(len(failed))
+	}
+
+	j := query.ImportJob
+	_, err = j.WithContext(ctx).Where(j.ID.Eq(job.ID)).UpdateSimple(
+		j.Accepted.Value(job.Accepted),
+		j.Duplicate.Value(job.Duplicate),
+		j.Error.Value(job.Error),
+		j.UpdatedAt.Value(time.Now()),
+	)
+	if err != nil {
+		return err
+	}
+	l.WithFields(Map{"total": job.Total, "accepted": job.Accepted, "duplicate": job.Duplicate, "invalid": job.Invalid}).Info("create import job done")
+	return nil
+}
+
+// insertImportLinks saves the pending shared links which do not exist, and returns their ids.
+// Links shared by the user on the host before are skipped, so are the ones shared concurrently.
+func insertImportLinks(ctx context.Context, userID, hostName string, tasks []*model.SharedLink) ([]int64, error) {
+	t := query.SharedLink
+	var ids []int64
+	for _, batch := range lo.Chunk(tasks, importBatchSize) {
+		hashes := lo.Map(batch, func(s *model.SharedLink, _ int) string { return s.OriginalLinkHash })
+
+		var existing []string
+		err := t.WithContext(ctx).Where(t.UserID.Eq(userID), t.Host.Eq(hostName), t.OriginalLinkHash.In(hashes...)).Pluck(t.OriginalLinkHash, &existing)
+		if err != nil {
+			return nil, err
+		}
+		batch = lo.Filter(batch, func(s *model.SharedLink, _ int) bool { return !slices.Contains(existing, s.OriginalLinkHash) })
+		if len(batch) == 0 {
+			continue
+		}
+		if err := t.WithContext(ctx).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(batch...); err != nil {
+			return nil, err
+		}
+
+		// ids assigned to multi-row inserts are not reliable, they are queried back.
+		hashes = lo.Map(batch, func(s *model.SharedLink, _ int) string { return s.OriginalLinkHash })
+		var inserted []int64
+		err = t.WithContext(ctx).
+			Where(t.UserID.Eq(userID), t.Host.Eq(hostName), t.OriginalLinkHash.In(hashes...), t.State.Eq(share.StatusPending.String())).
+			Order(t.AutoID).
+			Pluck(t.AutoID, &inserted)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, inserted...)
+	}
+	return ids, nil
+}
+
+// getImportJob returns the progress of an import job of the current user.
+func getImportJob(c *gin.Context) {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	j := query.ImportJob
+	job, err := j.WithContext(c.Request.Context()).Where(j.ID.Eq(id), j.UserID.Eq(c.GetString(constant.UserID))).Take()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, mdw.ErrResp(c, "import_job_not_found", i18n.WithDataMap("id", c.Param("id"))))
+		return
+	}
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, newImportJobProgress(job))
+}
+
+type importChunkMessage struct {
+	JobID int64   `json:"job"`
+	IDs   []int64 `json:"ids"`
+	// Check is true if the shared links are created on the host already, and the task waits for their final states.
+	Check bool `json:"check,omitempty"`
+}
+
+// handleImportChunk creates the pending shared links of a chunk on the host, and counts the results to the job.
+// Only pending shared links are handled, so retries do not count them twice.
+func handleImportChunk(ctx context.Context, task *asynq.Task) error {
+	var msg importChunkMessage
+	_ = json.Unmarshal(task.Payload(), &msg)
+	if msg.JobID <= 0 || len(msg.IDs) == 0 {
+		return nil // ignore invalid msg
+	}
+
+	j, t := query.ImportJob, query.SharedLink
+	job, err := j.WithContext(ctx).Where(j.ID.Eq(msg.JobID)).Take()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // job deleted
+	}
+	if err != nil {
+		return err // auto retry later
+	}
+	l := log.WithContext(ctx).WithFields(Map{constant.UserID: job.UserID, constant.Host: job.Host, "import_job": job.ID})
+
+	if msg.Check {
+		rows, err := t.WithContext(ctx).Where(t.AutoID.In(msg.IDs...)).Find()
+		if err != nil {
+			return err
+		}
+		states := make(map[int64]share.State, len(rows))
+		for _, s := range rows {
+			states[s.AutoID] = share.State(s.State)
+		}
+		countImportLinks(ctx, job, msg.IDs, states)
+		return nil
+	}
+
+	rows, err := t.WithContext(ctx).Where(t.AutoID.In(msg.IDs...), t.State.Eq(share.StatusPending.String())).Find()
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(job.UserID)).Take()
+	if err != nil {
+		return err
+	}
+
+	// links blocked or redirected by moderation rules are not created, like createSharedLinks.
+	var moderated []int64
+	rows = lo.Filter(rows, func(s *model.SharedLink, _ int) bool {
+		target := &moderationTarget{Channel: user.Channel, UserID: user.ID, Link: s.OriginalLink, Simple: s.OriginalLink, Hash: s.OriginalLinkHash}
+		if s.Size > 0 {
+			target.Info = &lk.Info{Name: s.Title, Size: s.Size} // parsed from the uploaded torrent
+		}
+		if rule := moderation.evaluate(ctx, target); rule != nil && rule.Action != moderationAllow {
+			moderated = append(moderated, s.AutoID)
+			return false
+		}
+		return true
+	})
+	if len(moderated) > 0 {
+		// moderated links are counted along with the deletion, so they are neither lost nor counted twice
+		// if creating the other links fails and the task is retried.
+		err := query.Q.Transaction(func(tx *query.Query) error {
+			t, j := &tx.SharedLink, &tx.ImportJob
+			ret, err := t.WithContext(ctx).Where(t.AutoID.In(moderated...), t.State.Eq(share.StatusPending.String())).Delete()
+			if err != nil {
+				return err
+			}
+			_, err = j.WithContext(ctx).Where(j.ID.Eq(job.ID)).UpdateSimple(j.Moderated.Add(int32(ret.RowsAffected)), j.UpdatedAt.Value(time.Now()))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var created map[string]*share.Share
+	if host := hosts.Get(job.Host); host == nil {
+		err = fmt.Errorf("host %s not found", job.Host)
+	} else {
+		links := lo.Map(rows, func(s *model.SharedLink, _ int) string { return s.OriginalLink })
+		hints := make(map[string]int64)
+		for _, s := range rows {
+			if s.Size > 0 {
+				hints[s.OriginalLink] = s.Size
+			}
+		}
+		created, err = host.CreateFromLinks(hosts.WithSizeHints(ctx, hints), job.UserID, links, share.LinkToShare, "")
+	}
+	if err != nil {
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+		if retried < maxRetry {
+			l.Errorf("create import chunk err: %v, retry later", err)
+			return err
+		}
+		l.Errorf("create import chunk err: %v", err)
+	}
+
+	ids := make([]int64, 0, len(rows))
+	states := make(map[int64]share.State, len(rows))
+	var hashes []string
+	now := time.Now()
+	for _, s := range rows {
+		update := &model.SharedLink{State: share.StatusError.String(), UpdatedAt: now}
+		if sh := created[s.OriginalLink]; sh == nil {
+			update.Error = "create on host failed"
+			if err != nil {
+				update.Error = err.Error()
+			}
+		} else {
+			update.State = sh.State.String()
+			update.Size = sh.Size
+			update.Title = sh.Title
+			update.HostSharedLink = sh.HostSharedLink
+			update.HostSharedLinkHash = lk.Hash(sh.HostSharedLink)
+			update.Error = sh.Error
+		}
+		if _, err := updateSharedLinks(ctx, update, t.AutoID.Eq(s.AutoID)); err != nil {
+			l.WithField("autoID", s.AutoID).Errorf("update import link err: %v", err)
+		}
+		ids = append(ids, s.AutoID)
+		states[s.AutoID] = share.State(update.State)
+		hashes = append(hashes, s.OriginalLinkHash)
+	}
+	notifySharedLinksUpdated(ctx, hashes...)
+
+	countImportLinks(ctx, job, ids, states)
+	return nil
+}
+
+// countImportLinks adds the shared links in final states to the counters of the job.
+// The others are still pending, they are checked again by a queued task until importCheckTimeout
+// after the job is created, and are counted as errors then. Missing links are deleted by the user, which are errors too.
+func countImportLinks(ctx context.Context, job *model.ImportJob, ids []int64, states map[int64]share.State) {
+	l := log.WithContext(ctx).WithFields(Map{constant.UserID: job.UserID, constant.Host: job.Host, "import_job": job.ID})
+	ok, failed, pending := classifyImportStates(ids, states)
+	if len(pending) > 0 {
+		err := fmt.Errorf("not completed in %v", importCheckTimeout)
+		if time.Since(job.CreatedAt) < importCheckTimeout {
+			payload, _ := json.Marshal(importChunkMessage{JobID: job.ID, IDs: pending, Check: true})
+			_, err = queue.Enqueue(importTask, payload, asynq.Queue(constant.AsyncQueueImportJob), asynq.ProcessIn(importCheckInterval))
+		}
+		if err != nil {
+			l.Errorf("check %d pending import links err: %v", len(pending), err)
+			failed += int32(len(pending))
+			pending = nil
+		}
+	}
+
+	j := query.ImportJob
+	_, err := j.WithContext(ctx).Where(j.ID.Eq(job.ID)).UpdateSimple(
+		j.Ok.Add(ok),
+		j.Error.Add(failed),
+		j.UpdatedAt.Value(time.Now()),
+	)
+	if err != nil {
+		l.Errorf("update import job err: %v", err)
+	}
+	l.WithFields(Map{"ok": ok, "error": failed, "pending": len(pending)}).Debug("count import links done")
+}
+
+// classifyImportStates returns the numbers of OK and failed shared links, and the ids of the ones not in final states.
+func classifyImportStates(ids []int64, states map[int64]share.State) (ok, failed int32, pending []int64) {
+	for _, id := range ids {
+		st, found := states[id]
+		switch {
+		case !found:
+			failed++
+		case st == share.StatusOK:
+			ok++
+		case isFinalState(st):
+			failed++
+		default:
+			pending = append(pending, id)
+		}
+	}
+	return
+}
@@ -0,0 +1,91 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/ratelimit"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadImportLinks(t *testing.T) {
+	const (
+		magnet = "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567"
+		http   = "https://example.com/a.zip"
+	)
+
+	links, err := readImportLinks(strings.NewReader("# comment\n"+magnet+"\r\n\n  "+http+"  \n"), false, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{magnet, http}, links)
+
+	// the link column is found by the header.
+	links, err = readImportLinks(strings.NewReader("\ufefftitle,Link\na,"+magnet+"\nb,\"https://example.com/a.zip\"\nc\n"), true, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{magnet, http}, links)
+
+	// the first column is used without a header.
+	links, err = readImportLinks(strings.NewReader("\ufeff"+magnet+",a\n"+http+",b\n"), true, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{magnet, http}, links)
+
+	_, err = readImportLinks(strings.NewReader("a\nb\nc\n"), false, 2)
+	assert.ErrorIs(t, err, errTooManyImportLinks)
+}
+
+func TestClassifyImportLinks(t *testing.T) {
+	links := []string{
+		"magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567",
+		"not a link",
+		"magnet:?xt=urn:btih:0123456789ABCDEF0123456789ABCDEF01234567&dn=x",
+		"https://example.com/a.zip",
+		"",
+		"https://example.com/a.zip",
+	}
+	tasks, invalid, duplicate := classifyImportLinks("u1", "pikpak", links)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, 2, invalid)
+	assert.Equal(t, 2, duplicate)
+	for _, task := range tasks {
+		assert.Equal(t, "u1", task.UserID)
+		assert.Equal(t, "pikpak", task.Host)
+		assert.Equal(t, "PENDING", task.State)
+	}
+}
+
+func TestImportChunkDelay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), importChunkDelay(ratelimit.Limit{}, 20))
+	assert.Equal(t, 2*time.Second, importChunkDelay(ratelimit.Limit{Burst: 600, Period: time.Minute}, 20))
+	assert.Equal(t, 20*time.Second, importChunkDelay(ratelimit.Limit{Burst: 60, Period: time.Minute}, 20))
+}
+
+func TestImportJobProgress(t *testing.T) {
+	p := newImportJobProgress(&model.ImportJob{Accepted: 10, Moderated: 1, Ok: 5, Error: 1})
+	assert.Equal(t, importJobRunning, p.State)
+	assert.Equal(t, int32(3), p.Pending)
+
+	p = newImportJobProgress(&model.ImportJob{Accepted: 10, Moderated: 1, Ok: 8, Error: 1})
+	assert.Equal(t, importJobDone, p.State)
+	assert.Equal(t, int32(0), p.Pending)
+}
+
+func TestClassifyImportStates(t *testing.T) {
+	states := map[int64]share.State{
+		1: share.StatusOK,
+		2: share.StatusCreated,
+		3: share.StatusError,
+		4: share.StatusPending,
+		5: share.StatusBlocked,
+	}
+	ok, failed, pending := classifyImportStates([]int64{1, 2, 3, 4, 5, 6}, states)
+	assert.Equal(t, int32(1), ok)
+	assert.Equal(t, int32(3), failed, "errors, other final states and deleted links")
+	assert.Equal(t, []int64{2, 4}, pending)
+}
@@ -0,0 +1,38 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+)
+
+// linkInfo provides names and sizes of original links, it is replaced by the configured chain on start.
+var linkInfo = lk.Local()
+
+// linkInfoProvider returns the chain of link info providers in the configured order,
+// remote providers are cached in redis.
+func linkInfoProvider() (lk.LinkInfoProvider, error) {
+	var providers []lk.LinkInfoProvider
+	for _, name := range strings.Split(config.LinkInfoProviders(), ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "":
+		case "local":
+			providers = append(providers, lk.Local())
+		case "whatslink":
+			p := lk.WhatsLink(config.WhatsLinkEndpoint(), nil)
+			if ttl := config.LinkInfoCacheTTL(); ttl > 0 {
+				p = lk.Cache(p, config.Redis(), ttl)
+			}
+			providers = append(providers, p)
+		default:
+			return nil, fmt.Errorf("unknown link info provider: %s", name)
+		}
+	}
+	return lk.Chain(providers...), nil
+}
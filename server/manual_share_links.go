@@ -5,7 +5,11 @@
 package server
 
 import (
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/KeepShareOrg/keepshare/config"
@@ -13,6 +17,7 @@ import (
 	"github.com/KeepShareOrg/keepshare/pkg/i18n"
 	lk "github.com/KeepShareOrg/keepshare/pkg/link"
 	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/pkg/torrent"
 	"github.com/KeepShareOrg/keepshare/pkg/util"
 	"github.com/KeepShareOrg/keepshare/server/constant"
 	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
@@ -45,41 +50,162 @@ func createSharedLinks(c *gin.Context) {
 		return
 	}
 
+	ctx := c.Request.Context()
+	userID := c.GetString(constant.UserID)
 	links := make([]string, 0)
+	// moderated are the links blocked or redirected by moderation rules, which are not created.
+	moderated := make(map[string]string)
 	for _, link := range req.Links {
-		if simple, _, ok := validateLink(link); ok {
-			links = append(links, simple)
+		simple, hash, ok := validateLink(link)
+		if !ok {
+			continue
+		}
+		target := &moderationTarget{Channel: c.GetString(constant.Channel), UserID: userID, Link: link, Simple: simple, Hash: hash}
+		if rule := moderation.evaluate(ctx, target); rule != nil && rule.Action != moderationAllow {
+			moderated[link] = rule.Action
+			continue
 		}
+		links = append(links, simple)
 	}
 
-	userID := c.GetString(constant.UserID)
 	tasks := make([]*model.SharedLink, 0)
 	for _, link := range links {
-		t := &model.SharedLink{
-			UserID:             userID,
-			State:              share.StatusPending.String(),
-			Host:               hostName,
-			CreatedBy:          share.LinkToShare,
-			CreatedAt:          time.Now(),
-			UpdatedAt:          time.Now(),
-			OriginalLinkHash:   lk.Hash(link),
-			HostSharedLinkHash: "",
-			OriginalLink:       link,
-			HostSharedLink:     "",
+		tasks = append(tasks, pendingSharedLink(userID, hostName, link))
+	}
+
+	if len(tasks) > 0 {
+		if err := query.SharedLink.WithContext(ctx).
+			Clauses(clause.Insert{Modifier: "IGNORE"}).
+			Create(tasks...); err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
 		}
-		tasks = append(tasks, t)
 	}
 
-	ctx := c.Request.Context()
-	if err := query.SharedLink.WithContext(ctx).
-		Clauses(clause.Insert{Modifier: "IGNORE"}).
-		Create(tasks...); err != nil {
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "ok",
+		"links":     tasks,
+		"moderated": moderated,
+	})
+}
+
+// pendingSharedLink returns a record of the link which is created on the host later.
+func pendingSharedLink(userID, hostName, link string) *model.SharedLink {
+	return &model.SharedLink{
+		UserID:             userID,
+		State:              share.StatusPending.String(),
+		Host:               hostName,
+		CreatedBy:          share.LinkToShare,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+		OriginalLinkHash:   lk.Hash(link),
+		HostSharedLinkHash: "",
+		OriginalLink:       link,
+		HostSharedLink:     "",
+	}
+}
+
+const (
+	maxTorrentFileSize = 10 << 20
+	maxTorrentFiles    = 100
+	// maxTorrentUploadSize is the limit of the request body of all torrent files,
+	// torrents are small usually and the larger ones are rejected by maxTorrentFileSize.
+	maxTorrentUploadSize = 32 << 20
+)
+
+// createSharedLinksFromTorrents creates shared links from the torrent files uploaded by the multipart form field `file`.
+// The magnet links of torrents are imported like createImportJob, with the torrent names as titles
+// and the total sizes of files as size hints of hosts, the progress is returned by getImportJob.
+func createSharedLinksFromTorrents(c *gin.Context) {
+	hostName := util.FirstNotEmpty(c.Query("host"), config.DefaultHost())
+	if hosts.Get(hostName) == nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_host", i18n.WithDataMap("host", hostName)))
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTorrentUploadSize)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "links_is_empty"))
+		return
+	}
+	if len(files) > maxTorrentFiles {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "submit_too_many_links", i18n.WithDataMap("count", strconv.Itoa(maxTorrentFiles))))
+		return
+	}
+
+	type torrentInfo struct {
+		*torrent.Torrent
+		Magnet string `json:"magnet"`
+	}
+
+	userID := c.GetString(constant.UserID)
+	job := &model.ImportJob{UserID: userID, Host: hostName, Total: int32(len(files))}
+	tasks := make([]*model.SharedLink, 0, len(files))
+	torrents := make([]*torrentInfo, 0, len(files))
+	seen := make(map[string]struct{}, len(files))
+	for _, fh := range files {
+		t, err := parseTorrentFile(fh)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_torrent", i18n.WithDataMap("name", fh.Filename, "error", err.Error())))
+			return
+		}
+
+		magnet := t.Magnet()
+		link, hash, ok := validateLink(magnet)
+		if !ok {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_link", i18n.WithDataMap("link", magnet)))
+			return
+		}
+		torrents = append(torrents, &torrentInfo{Torrent: t, Magnet: magnet})
+		if _, ok := seen[hash]; ok {
+			job.Duplicate++
+			continue
+		}
+		seen[hash] = struct{}{}
+
+		// moderation rules are evaluated by the import tasks, with the title and size as the info of the link.
+		task := pendingSharedLink(userID, hostName, link)
+		task.Title = util.FirstNotEmpty(t.Name, fh.Filename)
+		task.Size = t.Length
+		tasks = append(tasks, task)
+	}
+	if len(files) == 1 {
+		job.Filename = files[0].Filename
+	}
+
+	if err := startImportJob(c.Request.Context(), job, tasks); err != nil {
 		mdw.RespInternal(c, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "ok",
-		"links":   tasks,
+		"message":  "ok",
+		"links":    tasks,
+		"torrents": torrents,
+		"job":      newImportJobProgress(job),
 	})
 }
+
+// parseTorrentFile parses an uploaded torrent file.
+func parseTorrentFile(fh *multipart.FileHeader) (*torrent.Torrent, error) {
+	if fh.Size > maxTorrentFileSize {
+		return nil, fmt.Errorf("file size exceeds %d bytes", maxTorrentFileSize)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxTorrentFileSize))
+	if err != nil {
+		return nil, err
+	}
+	return torrent.Parse(data)
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/locale"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateSharedLinksFromTorrentsErrors(t *testing.T) {
+	hoststest.Register(t, "torrentfake", hoststest.NewFake())
+	require.NoError(t, i18n.Load(locale.FS))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/shared_links/torrent", createSharedLinksFromTorrents)
+
+	tests := []struct {
+		name  string
+		files map[string]string // key: file name
+		key   string
+	}{
+		{name: "no files", key: "links_is_empty"},
+		{name: "not bencoded", files: map[string]string{"a.torrent": "not a torrent"}, key: "invalid_torrent"},
+		{name: "no info hash", files: map[string]string{"b.torrent": "d4:infod4:name1:a6:lengthi1eee"}, key: "neither v1 nor v2 torrent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			for name, content := range tt.files {
+				fw, err := mw.CreateFormFile("file", name)
+				require.NoError(t, err)
+				_, _ = fw.Write([]byte(content))
+			}
+			require.NoError(t, mw.Close())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/shared_links/torrent?host=torrentfake", body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), tt.key)
+		})
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// Admin allows users in the config `admin_users` only, it must be used after Auth.
+// Users are matched by IDs only, since emails can be changed by users.
+func Admin(c *gin.Context) {
+	userID := c.GetString(constant.UserID)
+	for _, u := range strings.Split(viper.GetString("admin_users"), ",") {
+		if u = strings.TrimSpace(u); u != "" && u == userID {
+			c.Next()
+			return
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, ErrResp(c, "permission_denied"))
+}
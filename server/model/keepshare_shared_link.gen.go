@@ -27,6 +27,11 @@ type SharedLink struct {
 	LastStoredAt       time.Time `gorm:"column:last_stored_at;not null;default:2000-01-01 00:00:00" json:"last_stored_at"`
 	Revenue            int64     `gorm:"column:revenue;not null" json:"revenue"`
 	Title              string    `gorm:"column:title;not null" json:"title"`
+	CustomTitle        string    `gorm:"column:custom_title;not null" json:"custom_title"`
+	Note               string    `gorm:"column:note;not null" json:"note"`
+	Pinned             int32     `gorm:"column:pinned;not null" json:"pinned"`
+	ExpiresAt          time.Time `gorm:"column:expires_at;not null;default:2000-01-01 00:00:00" json:"expires_at"`
+	Version            int64     `gorm:"column:version;not null" json:"version"`
 	OriginalLinkHash   string    `gorm:"column:original_link_hash;not null" json:"original_link_hash"`
 	HostSharedLinkHash string    `gorm:"column:host_shared_link_hash;not null" json:"host_shared_link_hash"`
 	OriginalLink       string    `gorm:"column:original_link;not null" json:"original_link"`
@@ -18,6 +18,11 @@ type User struct {
 	PasswordHash  string    `gorm:"column:password_hash;not null" json:"password_hash"`
 	Channel       string    `gorm:"column:channel;not null" json:"channel"`
 	EmailVerified int32     `gorm:"column:email_verified;not null" json:"email_verified"`
+	HostPolicy    string    `gorm:"column:host_policy;not null" json:"host_policy"`
+	SignedMode    int32     `gorm:"column:signed_mode;not null" json:"signed_mode"`
+	LinkSecret    string    `gorm:"column:link_secret;not null" json:"link_secret"`
+	RateLimits    string    `gorm:"column:rate_limits;not null" json:"rate_limits"`
+	Timezone      string    `gorm:"column:timezone;not null" json:"timezone"`
 	CreatedAt     time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt     time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
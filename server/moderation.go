@@ -0,0 +1,484 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/async"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+// Actions of moderation rules.
+const (
+	// moderationBlock refuses to create shared links.
+	moderationBlock = "block"
+	// moderationRedirect redirects visitors to the info page of the link without creating host tasks.
+	moderationRedirect = "redirect"
+	// moderationAllow stops evaluating the rules with lower priorities.
+	moderationAllow = "allow"
+)
+
+const keyModerationRule = "moderation_rule"
+
+// moderationRule is a compiled rule, all the non-empty matchers must match.
+type moderationRule struct {
+	*model.ModerationRule
+	urlRegex      *regexp.Regexp
+	filenameRegex *regexp.Regexp
+	extensions    []string
+}
+
+func compileModerationRule(r *model.ModerationRule) (*moderationRule, error) {
+	switch r.Action {
+	case moderationBlock, moderationRedirect, moderationAllow:
+	default:
+		return nil, fmt.Errorf("invalid action %q", r.Action)
+	}
+	if r.MinSize < 0 || r.MaxSize < 0 || (r.MaxSize > 0 && r.MinSize > r.MaxSize) {
+		return nil, fmt.Errorf("invalid size range [%d, %d]", r.MinSize, r.MaxSize)
+	}
+
+	c := &moderationRule{ModerationRule: r}
+	var err error
+	if r.URLRegex != "" {
+		if c.urlRegex, err = regexp.Compile(r.URLRegex); err != nil {
+			return nil, fmt.Errorf("invalid url_regex: %w", err)
+		}
+	}
+	if r.FilenameRegex != "" {
+		if c.filenameRegex, err = regexp.Compile(r.FilenameRegex); err != nil {
+			return nil, fmt.Errorf("invalid filename_regex: %w", err)
+		}
+	}
+	for _, ext := range strings.Split(r.Extensions, ",") {
+		if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+			c.extensions = append(c.extensions, ext)
+		}
+	}
+
+	if r.Channel == "" && r.UserID == "" && r.InfoHash == "" && c.urlRegex == nil && !c.needsInfo() {
+		return nil, errors.New("no matchers")
+	}
+	return c, nil
+}
+
+// needsInfo reports whether the name or the size of the link is required to match the rule.
+func (r *moderationRule) needsInfo() bool {
+	return r.filenameRegex != nil || len(r.extensions) > 0 || r.MinSize > 0 || r.MaxSize > 0
+}
+
+// moderationTarget is the link to moderate.
+type moderationTarget struct {
+	Channel string
+	UserID  string
+	// Link is the original link to query the info, Simple and Hash are returned by validateLink.
+	Link   string
+	Simple string
+	Hash   string
+	// Info is the known info of the link, such as the info parsed from torrents, it is queried if nil.
+	Info *lk.Info
+
+	queried bool
+}
+
+// info returns the info of the link, it is queried at most once. The name of HTTP links defaults to the last element of the path.
+func (t *moderationTarget) info(ctx context.Context) *lk.Info {
+	if t.Info != nil || t.queried {
+		return t.Info
+	}
+	t.queried = true
+
+	info, err := linkInfo.LinkInfo(ctx, t.Link)
+	if err != nil {
+		log.WithContext(ctx).WithField(constant.Link, t.Link).Debugf("query link info for moderation err: %v", err)
+		info = &lk.Info{}
+	}
+	if info.Name == "" {
+		if u, err := url.Parse(t.Simple); err == nil && u.Host != "" {
+			if name := path.Base(u.Path); name != "/" && name != "." {
+				info.Name = name
+			}
+		}
+	}
+	t.Info = info
+	return t.Info
+}
+
+func (r *moderationRule) match(ctx context.Context, t *moderationTarget) bool {
+	if r.Channel != "" && r.Channel != t.Channel {
+		return false
+	}
+	if r.UserID != "" && r.UserID != t.UserID {
+		return false
+	}
+	if r.InfoHash != "" && r.InfoHash != t.Hash {
+		return false
+	}
+	if r.urlRegex != nil && !r.urlRegex.MatchString(t.Simple) {
+		return false
+	}
+	if !r.needsInfo() {
+		return true
+	}
+
+	info := t.info(ctx)
+	if r.filenameRegex != nil && (info.Name == "" || !r.filenameRegex.MatchString(info.Name)) {
+		return false
+	}
+	if len(r.extensions) > 0 {
+		ext := strings.ToLower(strings.TrimPrefix(path.Ext(info.Name), "."))
+		if ext == "" || !slices.Contains(r.extensions, ext) {
+			return false
+		}
+	}
+	if r.MinSize > 0 || r.MaxSize > 0 {
+		if info.Size <= 0 || info.Size < r.MinSize || (r.MaxSize > 0 && info.Size > r.MaxSize) {
+			return false
+		}
+	}
+	return true
+}
+
+// moderator holds the enabled rules in the order of evaluation.
+type moderator struct {
+	rules atomic.Pointer[[]*moderationRule]
+}
+
+var moderation = &moderator{}
+
+// load loads the enabled rules, invalid rules are logged and ignored.
+func (m *moderator) load(ctx context.Context) error {
+	t := query.ModerationRule
+	rows, err := t.WithContext(ctx).Where(t.Enabled.Eq(1)).Order(t.Priority.Desc(), t.AutoID).Find()
+	if err != nil {
+		return fmt.Errorf("query moderation rules err: %w", err)
+	}
+
+	rules := make([]*moderationRule, 0, len(rows))
+	for _, row := range rows {
+		r, err := compileModerationRule(row)
+		if err != nil {
+			log.WithContext(ctx).WithField(keyModerationRule, row.AutoID).Errorf("invalid moderation rule: %v", err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	m.rules.Store(&rules)
+	return nil
+}
+
+// reloadLoop reloads rules periodically, so that changes of other instances take effect.
+func (m *moderator) reloadLoop(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := m.load(context.Background()); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+// evaluate returns the first rule matching the target, nil means no rule matches.
+// Hits of rules are logged and counted.
+func (m *moderator) evaluate(ctx context.Context, t *moderationTarget) *moderationRule {
+	rules := m.rules.Load()
+	if rules == nil {
+		return nil
+	}
+
+	for _, r := range *rules {
+		if !r.match(ctx, t) {
+			continue
+		}
+
+		fields := log.Fields{
+			keyModerationRule: r.AutoID,
+			"action":          r.Action,
+			constant.Channel:  t.Channel,
+			constant.UserID:   t.UserID,
+			constant.Link:     t.Simple,
+		}
+		log.WithContext(ctx).WithFields(fields).Info("moderation rule hit")
+		log.NewReport("moderation_rule_hit").Sets(fields).Done()
+
+		id := r.AutoID
+		async.Run(func() {
+			mr := query.ModerationRule
+			_, err := mr.WithContext(context.Background()).Where(mr.AutoID.Eq(id)).UpdateSimple(mr.Hits.Add(1), mr.LastHitAt.Value(time.Now()))
+			if err != nil {
+				log.WithField(keyModerationRule, id).Errorf("count moderation rule hits err: %v", err)
+			}
+		})
+		return r
+	}
+	return nil
+}
+
+var infoHashPattern = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
+
+// moderationRuleRequest is the request to create or replace a moderation rule.
+type moderationRuleRequest struct {
+	Name          string   `json:"name"`
+	Enabled       *bool    `json:"enabled"`
+	Priority      int32    `json:"priority"`
+	Action        string   `json:"action"`
+	Channel       string   `json:"channel"`
+	UserID        string   `json:"user_id"`
+	InfoHash      string   `json:"info_hash"` // info hash, or a link to hash
+	URLRegex      string   `json:"url_regex"`
+	FilenameRegex string   `json:"filename_regex"`
+	MinSize       int64    `json:"min_size"`
+	MaxSize       int64    `json:"max_size"`
+	Extensions    []string `json:"extensions"`
+}
+
+// rule validates the request and returns the rule to save.
+func (r *moderationRuleRequest) rule() (*model.ModerationRule, error) {
+	rule := &model.ModerationRule{
+		Name:          strings.TrimSpace(r.Name),
+		Enabled:       1,
+		Priority:      r.Priority,
+		Action:        r.Action,
+		Channel:       strings.TrimSpace(r.Channel),
+		UserID:        strings.TrimSpace(r.UserID),
+		URLRegex:      r.URLRegex,
+		FilenameRegex: r.FilenameRegex,
+		MinSize:       r.MinSize,
+		MaxSize:       r.MaxSize,
+		Extensions:    strings.Join(r.Extensions, ","),
+	}
+	if r.Enabled != nil && !*r.Enabled {
+		rule.Enabled = 0
+	}
+	if h := strings.TrimSpace(r.InfoHash); h != "" {
+		if infoHashPattern.MatchString(h) {
+			rule.InfoHash = strings.ToLower(h)
+		} else if _, hash, ok := validateLink(h); ok {
+			rule.InfoHash = hash
+		} else {
+			return nil, fmt.Errorf("invalid info_hash %q", h)
+		}
+	}
+
+	c, err := compileModerationRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	rule.Extensions = strings.Join(c.extensions, ",")
+	if len(rule.Name) > 64 || len(rule.Channel) > 32 || len(rule.UserID) > 16 ||
+		len(rule.URLRegex) > 512 || len(rule.FilenameRegex) > 512 || len(rule.Extensions) > 255 {
+		return nil, errors.New("fields are too long")
+	}
+	return rule, nil
+}
+
+func listModerationRules(c *gin.Context) {
+	t := query.ModerationRule
+	rules, err := t.WithContext(c.Request.Context()).Order(t.Priority.Desc(), t.AutoID).Find()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, Map{"list": rules})
+}
+
+func createModerationRule(c *gin.Context) {
+	var req moderationRuleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	rule, err := req.rule()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.ModerationRule
+	enabled := rule.Enabled
+	if err := t.WithContext(ctx).Create(rule); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	// zero values of columns with defaults are ignored on creation.
+	if enabled == 0 {
+		if _, err := t.WithContext(ctx).Where(t.AutoID.Eq(rule.AutoID)).Update(t.Enabled, 0); err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+		rule.Enabled = 0
+	}
+
+	reloadModerationRules(ctx)
+	c.JSON(http.StatusOK, rule)
+}
+
+func updateModerationRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "invalid id")))
+		return
+	}
+	var req moderationRuleRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	rule, err := req.rule()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.ModerationRule
+	rule.UpdatedAt = time.Now()
+	_, err = t.WithContext(ctx).Where(t.AutoID.Eq(id)).Select(
+		t.Name, t.Enabled, t.Priority, t.Action, t.Channel, t.UserID, t.InfoHash,
+		t.URLRegex, t.FilenameRegex, t.MinSize, t.MaxSize, t.Extensions, t.UpdatedAt,
+	).Updates(rule)
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	saved, err := t.WithContext(ctx).Where(t.AutoID.Eq(id)).Take()
+	if gormutil.IsNotFoundError(err) {
+		c.JSON(http.StatusNotFound, mdw.ErrResp(c, "moderation_rule_not_found", i18n.WithDataMap("id", c.Param("id"))))
+		return
+	}
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	reloadModerationRules(ctx)
+	c.JSON(http.StatusOK, saved)
+}
+
+func deleteModerationRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "invalid id")))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.ModerationRule
+	info, err := t.WithContext(ctx).Where(t.AutoID.Eq(id)).Delete()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	if info.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, mdw.ErrResp(c, "moderation_rule_not_found", i18n.WithDataMap("id", c.Param("id"))))
+		return
+	}
+
+	reloadModerationRules(ctx)
+	c.JSON(http.StatusOK, Map{"message": "ok"})
+}
+
+// reloadModerationRules reloads rules after changes, so that they take effect immediately on this instance.
+func reloadModerationRules(ctx context.Context) {
+	if err := moderation.load(ctx); err != nil {
+		log.WithContext(ctx).Error(err)
+	}
+}
+
+// legacyModerationRules converts the deprecated configs to moderation rules, which redirect visitors to the info page
+// like before. A rule is named after its config and channel, so that it is imported once only.
+func legacyModerationRules() ([]*model.ModerationRule, error) {
+	var rules []*model.ModerationRule
+	for _, channel := range viper.GetStringSlice("warning_channels") {
+		if channel = strings.ToLower(strings.TrimSpace(channel)); channel != "" {
+			rules = append(rules, &model.ModerationRule{Name: "warning_channels " + channel, Action: moderationRedirect, Channel: channel})
+		}
+	}
+
+	var forbidden []*struct {
+		ChannelID       string   `mapstructure:"channel_id"`
+		FilenameContain []string `mapstructure:"filename_contain"`
+	}
+	if err := viper.UnmarshalKey("forbidden_rules", &forbidden); err != nil {
+		return nil, fmt.Errorf("unmarshal forbidden_rules err: %w", err)
+	}
+	for _, r := range forbidden {
+		channel := strings.ToLower(strings.TrimSpace(r.ChannelID))
+		words := lo.FilterMap(r.FilenameContain, func(s string, _ int) (string, bool) { return regexp.QuoteMeta(s), s != "" })
+		if channel == "" || len(words) == 0 {
+			continue
+		}
+		rules = append(rules, &model.ModerationRule{
+			Name:          "forbidden_rules " + channel,
+			Action:        moderationRedirect,
+			Channel:       channel,
+			FilenameRegex: "(?i)" + strings.Join(words, "|"),
+		})
+	}
+	return rules, nil
+}
+
+// legacyModerationImport is recorded in keepshare_schema_migration once the legacy rules are imported,
+// so that the rules deleted by admins are not imported again.
+const legacyModerationImport = "import_legacy_moderation_rules"
+
+// importLegacyModerationRules saves the rules of the deprecated configs forbidden_rules and warning_channels once.
+// Rules with the same names are skipped, they were imported before the import was recorded.
+func importLegacyModerationRules(ctx context.Context) error {
+	rules, err := legacyModerationRules()
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	return query.Q.Transaction(func(tx *query.Query) error {
+		t := &tx.ModerationRule
+		ret := t.WithContext(ctx).UnderlyingDB().
+			Exec("INSERT IGNORE INTO `keepshare_schema_migration` (`version`) VALUES (?)", legacyModerationImport)
+		if ret.Error != nil {
+			return fmt.Errorf("record the import of legacy moderation rules err: %w", ret.Error)
+		}
+		if ret.RowsAffected == 0 {
+			log.WithContext(ctx).Warn("deprecated forbidden_rules and warning_channels were imported as moderation rules and are ignored, remove them from the config")
+			return nil
+		}
+
+		var imported []string
+		names := lo.Map(rules, func(r *model.ModerationRule, _ int) string { return r.Name })
+		if err := t.WithContext(ctx).Where(t.Name.In(names...)).Pluck(t.Name, &imported); err != nil {
+			return fmt.Errorf("query moderation rules err: %w", err)
+		}
+		rules = lo.Filter(rules, func(r *model.ModerationRule, _ int) bool { return !lo.Contains(imported, r.Name) })
+		if len(rules) == 0 {
+			return nil
+		}
+		if err := t.WithContext(ctx).Create(rules...); err != nil {
+			return fmt.Errorf("import moderation rules err: %w", err)
+		}
+		names = lo.Map(rules, func(r *model.ModerationRule, _ int) string { return r.Name })
+		log.WithContext(ctx).WithField("rules", names).Warn("deprecated forbidden_rules and warning_channels are imported as moderation rules, remove them from the config")
+		return nil
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileModerationRule(t *testing.T) {
+	tests := []struct {
+		rule model.ModerationRule
+		err  bool
+	}{
+		{rule: model.ModerationRule{Action: moderationBlock, Channel: "abcd1234"}},
+		{rule: model.ModerationRule{Action: moderationRedirect, Extensions: " .MP4, mkv"}},
+		{rule: model.ModerationRule{Action: "drop", Channel: "abcd1234"}, err: true},
+		{rule: model.ModerationRule{Action: moderationBlock}, err: true},
+		{rule: model.ModerationRule{Action: moderationBlock, URLRegex: "("}, err: true},
+		{rule: model.ModerationRule{Action: moderationBlock, FilenameRegex: "[a"}, err: true},
+		{rule: model.ModerationRule{Action: moderationBlock, MinSize: 10, MaxSize: 5}, err: true},
+		{rule: model.ModerationRule{Action: moderationBlock, MinSize: -1}, err: true},
+	}
+
+	for _, tt := range tests {
+		_, err := compileModerationRule(&tt.rule)
+		assert.Equal(t, tt.err, err != nil, "%+v: %v", tt.rule, err)
+	}
+}
+
+func TestModerationRuleMatch(t *testing.T) {
+	const magnet = "magnet:?xt=urn:btih:0123456789ABCDEF0123456789ABCDEF01234567"
+	simple, hash, ok := validateLink(magnet)
+	require.True(t, ok)
+
+	target := func() *moderationTarget {
+		return &moderationTarget{
+			Channel: "abcd1234",
+			UserID:  "user",
+			Link:    magnet,
+			Simple:  simple,
+			Hash:    hash,
+			Info:    &lk.Info{Name: "Some.Movie.2023.MKV", Size: 2 << 30},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		rule  model.ModerationRule
+		match bool
+	}{
+		{name: "channel", rule: model.ModerationRule{Channel: "abcd1234"}, match: true},
+		{name: "other channel", rule: model.ModerationRule{Channel: "00000000"}},
+		{name: "channel and user", rule: model.ModerationRule{Channel: "abcd1234", UserID: "other"}},
+		{name: "info hash", rule: model.ModerationRule{InfoHash: "0123456789abcdef0123456789abcdef01234567"}, match: true},
+		{name: "url regex", rule: model.ModerationRule{URLRegex: `^magnet:\?xt=urn:btih:0123`}, match: true},
+		{name: "filename regex", rule: model.ModerationRule{FilenameRegex: `(?i)movie`}, match: true},
+		{name: "filename regex mismatch", rule: model.ModerationRule{FilenameRegex: `^movie`}},
+		{name: "extensions", rule: model.ModerationRule{Extensions: "mp4,mkv"}, match: true},
+		{name: "extensions mismatch", rule: model.ModerationRule{Extensions: "avi"}},
+		{name: "min size", rule: model.ModerationRule{MinSize: 1 << 30}, match: true},
+		{name: "max size", rule: model.ModerationRule{MaxSize: 1 << 30}},
+		{name: "size range", rule: model.ModerationRule{MinSize: 1 << 30, MaxSize: 4 << 30, Channel: "abcd1234"}, match: true},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		tt.rule.Action = moderationBlock
+		r, err := compileModerationRule(&tt.rule)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.match, r.match(ctx, target()), tt.name)
+	}
+
+	// size rules never match links with unknown sizes.
+	r, err := compileModerationRule(&model.ModerationRule{Action: moderationBlock, MaxSize: 1 << 30})
+	require.NoError(t, err)
+	unknown := target()
+	unknown.Info = &lk.Info{Name: "a.mp4"}
+	assert.False(t, r.match(ctx, unknown))
+}
+
+func TestModerationRuleRequest(t *testing.T) {
+	enabled := false
+	rule, err := (&moderationRuleRequest{
+		Name:       " test ",
+		Enabled:    &enabled,
+		Action:     moderationRedirect,
+		InfoHash:   "magnet:?xt=urn:btih:0123456789ABCDEF0123456789ABCDEF01234567&dn=a",
+		Extensions: []string{".MP4", "", "mkv"},
+	}).rule()
+	require.NoError(t, err)
+	assert.Equal(t, "test", rule.Name)
+	assert.Equal(t, int32(0), rule.Enabled)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", rule.InfoHash)
+	assert.Equal(t, "mp4,mkv", rule.Extensions)
+
+	_, err = (&moderationRuleRequest{Action: moderationBlock, InfoHash: "not a link"}).rule()
+	assert.Error(t, err)
+	_, err = (&moderationRuleRequest{Action: moderationBlock}).rule()
+	assert.Error(t, err, "no matchers")
+}
+
+func TestLegacyModerationRules(t *testing.T) {
+	viper.Set("warning_channels", []string{"ABCD1234", " "})
+	viper.Set("forbidden_rules", []map[string]any{
+		{"channel_id": "efgh5678", "filename_contain": []string{"a.b", "", "Cd"}},
+		{"channel_id": "ijkl9012"},
+	})
+	t.Cleanup(func() {
+		viper.Set("warning_channels", nil)
+		viper.Set("forbidden_rules", nil)
+	})
+
+	rules, err := legacyModerationRules()
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, &model.ModerationRule{Name: "warning_channels abcd1234", Action: moderationRedirect, Channel: "abcd1234"}, rules[0])
+	assert.Equal(t, &model.ModerationRule{Name: "forbidden_rules efgh5678", Action: moderationRedirect, Channel: "efgh5678", FilenameRegex: `(?i)a\.b|Cd`}, rules[1])
+
+	r, err := compileModerationRule(rules[1])
+	require.NoError(t, err)
+	target := &moderationTarget{Channel: "efgh5678", Info: &lk.Info{Name: "xx.CD.mp4"}}
+	assert.True(t, r.match(context.Background(), target))
+	target.Info.Name = "axb.mp4"
+	assert.False(t, r.match(context.Background(), target))
+}
@@ -16,44 +16,69 @@ import (
 )
 
 var (
-	Q          = new(Query)
-	Blacklist  *blacklist
-	SharedLink *sharedLink
-	User       *user
+	Q               = new(Query)
+	Blacklist       *blacklist
+	ImportJob       *importJob
+	ModerationRule  *moderationRule
+	RedirectSetting *redirectSetting
+	SharedLink      *sharedLink
+	SharedLinkTag   *sharedLinkTag
+	Tag             *tag
+	User            *user
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
 	Blacklist = &Q.Blacklist
+	ImportJob = &Q.ImportJob
+	ModerationRule = &Q.ModerationRule
+	RedirectSetting = &Q.RedirectSetting
 	SharedLink = &Q.SharedLink
+	SharedLinkTag = &Q.SharedLinkTag
+	Tag = &Q.Tag
 	User = &Q.User
 }
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:         db,
-		Blacklist:  newBlacklist(db, opts...),
-		SharedLink: newSharedLink(db, opts...),
-		User:       newUser(db, opts...),
+		db:              db,
+		Blacklist:       newBlacklist(db, opts...),
+		ImportJob:       newImportJob(db, opts...),
+		ModerationRule:  newModerationRule(db, opts...),
+		RedirectSetting: newRedirectSetting(db, opts...),
+		SharedLink:      newSharedLink(db, opts...),
+		SharedLinkTag:   newSharedLinkTag(db, opts...),
+		Tag:             newTag(db, opts...),
+		User:            newUser(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	Blacklist  blacklist
-	SharedLink sharedLink
-	User       user
+	Blacklist       blacklist
+	ImportJob       importJob
+	ModerationRule  moderationRule
+	RedirectSetting redirectSetting
+	SharedLink      sharedLink
+	SharedLinkTag   sharedLinkTag
+	Tag             tag
+	User            user
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:         db,
-		Blacklist:  q.Blacklist.clone(db),
-		SharedLink: q.SharedLink.clone(db),
-		User:       q.User.clone(db),
+		db:              db,
+		Blacklist:       q.Blacklist.clone(db),
+		ImportJob:       q.ImportJob.clone(db),
+		ModerationRule:  q.ModerationRule.clone(db),
+		RedirectSetting: q.RedirectSetting.clone(db),
+		SharedLink:      q.SharedLink.clone(db),
+		SharedLinkTag:   q.SharedLinkTag.clone(db),
+		Tag:             q.Tag.clone(db),
+		User:            q.User.clone(db),
 	}
 }
 
@@ -67,24 +92,39 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:         db,
-		Blacklist:  q.Blacklist.replaceDB(db),
-		SharedLink: q.SharedLink.replaceDB(db),
-		User:       q.User.replaceDB(db),
+		db:              db,
+		Blacklist:       q.Blacklist.replaceDB(db),
+		ImportJob:       q.ImportJob.replaceDB(db),
+		ModerationRule:  q.ModerationRule.replaceDB(db),
+		RedirectSetting: q.RedirectSetting.replaceDB(db),
+		SharedLink:      q.SharedLink.replaceDB(db),
+		SharedLinkTag:   q.SharedLinkTag.replaceDB(db),
+		Tag:             q.Tag.replaceDB(db),
+		User:            q.User.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	Blacklist  IBlacklistDo
-	SharedLink ISharedLinkDo
-	User       IUserDo
+	Blacklist       IBlacklistDo
+	ImportJob       IImportJobDo
+	ModerationRule  IModerationRuleDo
+	RedirectSetting IRedirectSettingDo
+	SharedLink      ISharedLinkDo
+	SharedLinkTag   ISharedLinkTagDo
+	Tag             ITagDo
+	User            IUserDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		Blacklist:  q.Blacklist.WithContext(ctx),
-		SharedLink: q.SharedLink.WithContext(ctx),
-		User:       q.User.WithContext(ctx),
+		Blacklist:       q.Blacklist.WithContext(ctx),
+		ImportJob:       q.ImportJob.WithContext(ctx),
+		ModerationRule:  q.ModerationRule.WithContext(ctx),
+		RedirectSetting: q.RedirectSetting.WithContext(ctx),
+		SharedLink:      q.SharedLink.WithContext(ctx),
+		SharedLinkTag:   q.SharedLinkTag.WithContext(ctx),
+		Tag:             q.Tag.WithContext(ctx),
+		User:            q.User.WithContext(ctx),
 	}
 }
 
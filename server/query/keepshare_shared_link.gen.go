@@ -42,6 +42,11 @@ func newSharedLink(db *gorm.DB, opts ...gen.DOOption) sharedLink {
 	_sharedLink.LastStoredAt = field.NewTime(tableName, "last_stored_at")
 	_sharedLink.Revenue = field.NewInt64(tableName, "revenue")
 	_sharedLink.Title = field.NewString(tableName, "title")
+	_sharedLink.CustomTitle = field.NewString(tableName, "custom_title")
+	_sharedLink.Note = field.NewString(tableName, "note")
+	_sharedLink.Pinned = field.NewInt32(tableName, "pinned")
+	_sharedLink.ExpiresAt = field.NewTime(tableName, "expires_at")
+	_sharedLink.Version = field.NewInt64(tableName, "version")
 	_sharedLink.OriginalLinkHash = field.NewString(tableName, "original_link_hash")
 	_sharedLink.HostSharedLinkHash = field.NewString(tableName, "host_shared_link_hash")
 	_sharedLink.OriginalLink = field.NewString(tableName, "original_link")
@@ -72,6 +77,11 @@ type sharedLink struct {
 	LastStoredAt       field.Time
 	Revenue            field.Int64
 	Title              field.String
+	CustomTitle        field.String
+	Note               field.String
+	Pinned             field.Int32
+	ExpiresAt          field.Time
+	Version            field.Int64
 	OriginalLinkHash   field.String
 	HostSharedLinkHash field.String
 	OriginalLink       field.String
@@ -108,6 +118,11 @@ func (s *sharedLink) updateTableName(table string) *sharedLink {
 	s.LastStoredAt = field.NewTime(table, "last_stored_at")
 	s.Revenue = field.NewInt64(table, "revenue")
 	s.Title = field.NewString(table, "title")
+	s.CustomTitle = field.NewString(table, "custom_title")
+	s.Note = field.NewString(table, "note")
+	s.Pinned = field.NewInt32(table, "pinned")
+	s.ExpiresAt = field.NewTime(table, "expires_at")
+	s.Version = field.NewInt64(table, "version")
 	s.OriginalLinkHash = field.NewString(table, "original_link_hash")
 	s.HostSharedLinkHash = field.NewString(table, "host_shared_link_hash")
 	s.OriginalLink = field.NewString(table, "original_link")
@@ -129,7 +144,7 @@ func (s *sharedLink) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (s *sharedLink) fillFieldMap() {
-	s.fieldMap = make(map[string]field.Expr, 20)
+	s.fieldMap = make(map[string]field.Expr, 25)
 	s.fieldMap["auto_id"] = s.AutoID
 	s.fieldMap["user_id"] = s.UserID
 	s.fieldMap["state"] = s.State
@@ -145,6 +160,11 @@ func (s *sharedLink) fillFieldMap() {
 	s.fieldMap["last_stored_at"] = s.LastStoredAt
 	s.fieldMap["revenue"] = s.Revenue
 	s.fieldMap["title"] = s.Title
+	s.fieldMap["custom_title"] = s.CustomTitle
+	s.fieldMap["note"] = s.Note
+	s.fieldMap["pinned"] = s.Pinned
+	s.fieldMap["expires_at"] = s.ExpiresAt
+	s.fieldMap["version"] = s.Version
 	s.fieldMap["original_link_hash"] = s.OriginalLinkHash
 	s.fieldMap["host_shared_link_hash"] = s.HostSharedLinkHash
 	s.fieldMap["original_link"] = s.OriginalLink
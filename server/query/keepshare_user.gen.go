@@ -33,6 +33,11 @@ func newUser(db *gorm.DB, opts ...gen.DOOption) user {
 	_user.PasswordHash = field.NewString(tableName, "password_hash")
 	_user.Channel = field.NewString(tableName, "channel")
 	_user.EmailVerified = field.NewInt32(tableName, "email_verified")
+	_user.HostPolicy = field.NewString(tableName, "host_policy")
+	_user.SignedMode = field.NewInt32(tableName, "signed_mode")
+	_user.LinkSecret = field.NewString(tableName, "link_secret")
+	_user.RateLimits = field.NewString(tableName, "rate_limits")
+	_user.Timezone = field.NewString(tableName, "timezone")
 	_user.CreatedAt = field.NewTime(tableName, "created_at")
 	_user.UpdatedAt = field.NewTime(tableName, "updated_at")
 
@@ -51,6 +56,11 @@ type user struct {
 	PasswordHash  field.String
 	Channel       field.String
 	EmailVerified field.Int32
+	HostPolicy    field.String
+	SignedMode    field.Int32
+	LinkSecret    field.String
+	RateLimits    field.String
+	Timezone      field.String
 	CreatedAt     field.Time
 	UpdatedAt     field.Time
 
@@ -75,6 +85,11 @@ func (u *user) updateTableName(table string) *user {
 	u.PasswordHash = field.NewString(table, "password_hash")
 	u.Channel = field.NewString(table, "channel")
 	u.EmailVerified = field.NewInt32(table, "email_verified")
+	u.HostPolicy = field.NewString(table, "host_policy")
+	u.SignedMode = field.NewInt32(table, "signed_mode")
+	u.LinkSecret = field.NewString(table, "link_secret")
+	u.RateLimits = field.NewString(table, "rate_limits")
+	u.Timezone = field.NewString(table, "timezone")
 	u.CreatedAt = field.NewTime(table, "created_at")
 	u.UpdatedAt = field.NewTime(table, "updated_at")
 
@@ -93,13 +108,18 @@ func (u *user) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (u *user) fillFieldMap() {
-	u.fieldMap = make(map[string]field.Expr, 8)
+	u.fieldMap = make(map[string]field.Expr, 13)
 	u.fieldMap["id"] = u.ID
 	u.fieldMap["name"] = u.Name
 	u.fieldMap["email"] = u.Email
 	u.fieldMap["password_hash"] = u.PasswordHash
 	u.fieldMap["channel"] = u.Channel
 	u.fieldMap["email_verified"] = u.EmailVerified
+	u.fieldMap["host_policy"] = u.HostPolicy
+	u.fieldMap["signed_mode"] = u.SignedMode
+	u.fieldMap["link_secret"] = u.LinkSecret
+	u.fieldMap["rate_limits"] = u.RateLimits
+	u.fieldMap["timezone"] = u.Timezone
 	u.fieldMap["created_at"] = u.CreatedAt
 	u.fieldMap["updated_at"] = u.UpdatedAt
 }
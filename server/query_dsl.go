@@ -0,0 +1,453 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+)
+
+// The search DSL of shared links, e.g.
+//
+//	title:"movie" and (size >= 1.5GB or visitor > 10) and not state in ("ERROR", "BLOCKED")
+//	created_at > -7d; last_visited_at between ["2023-01-01", "2023-02-01 12:00"]
+//
+// Conditions separated by spaces, `;`, `and` or `&&` are all required, `or` and `||` bind looser than `and`.
+// `not` and `!` negate conditions or groups, and `not` before `in`, `between` and `match` negates the operator.
+// Sizes accept the units B, KB, MB, GB and TB, times are absolute in the time zone of the user or relative to now
+// with the units s, m, h, d and w, e.g. -12h.
+
+var dslLexer = lexer.MustSimple([]lexer.SimpleRule{
+	{Name: "Keyword", Pattern: `(?i)\b(?:and|or|not|in|between|match|true|false)\b`},
+	{Name: "Ident", Pattern: `[a-zA-Z_][a-zA-Z0-9_]*`},
+	{Name: "String", Pattern: `"(?:\\.|[^"\\])*"`},
+	{Name: "Number", Pattern: `[-+]?(?:\d+\.?\d*|\.\d+)[a-zA-Z]*`},
+	{Name: "Operator", Pattern: `!=|<=|>=|&&|\|\||[=<>:!]`},
+	{Name: "Punct", Pattern: `[()\[\],;]`},
+	{Name: "Whitespace", Pattern: `\s+`},
+})
+
+// dslExpr is true if any of the groups is true.
+type dslExpr struct {
+	And []*dslAnd `parser:"@@ ( ('or' | '||') @@ )*"`
+}
+
+// dslAnd is true if all the terms are true.
+type dslAnd struct {
+	Terms []*dslTerm `parser:"@@ ( ('and' | '&&')? @@ )*"`
+}
+
+type dslTerm struct {
+	Pos   lexer.Position
+	Not   bool           `parser:"@('not' | '!')?"`
+	Group *dslExpr       `parser:"( '(' @@ ')'"`
+	Cmp   *dslComparison `parser:"| @@ ) ';'*"`
+}
+
+type dslComparison struct {
+	Pos   lexer.Position
+	Key   string    `parser:"@Ident"`
+	Not   bool      `parser:"@'not'?"`
+	Op    string    `parser:"@('=' | '!=' | '<=' | '>=' | '<' | '>' | ':' | 'match' | 'between' | 'in')"`
+	Value *dslValue `parser:"@@"`
+}
+
+type dslValue struct {
+	Scalar *dslScalar   `parser:"  @@"`
+	List   []*dslScalar `parser:"| '[' @@ ( ',' @@ )* ']' | '(' @@ ( ',' @@ )* ')'"`
+}
+
+type dslScalar struct {
+	Pos    lexer.Position
+	String *string `parser:"  @String"`
+	Number *string `parser:"| @Number"`
+	Bool   *string `parser:"| @('true' | 'false')"`
+}
+
+// keepShare query dsl parser
+var parser = participle.MustBuild[dslExpr](
+	participle.Lexer(dslLexer),
+	participle.Elide("Whitespace"),
+	participle.Unquote("String"),
+	participle.CaseInsensitive("Keyword"),
+)
+
+// dslError is an error of the search DSL at the position.
+type dslError struct {
+	Pos lexer.Position
+	Msg string
+}
+
+func (e *dslError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+func dslErrorf(pos lexer.Position, format string, args ...any) error {
+	return &dslError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// respDSLError responds the error of the search DSL with its position.
+func respDSLError(c *gin.Context, err error) {
+	var e *dslError
+	if !errors.As(err, &e) {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	resp := mdw.ErrResp(c, "invalid_search", i18n.WithDataMap(
+		"error", e.Msg,
+		"line", strconv.Itoa(e.Pos.Line),
+		"column", strconv.Itoa(e.Pos.Column),
+	))
+	resp["position"] = Map{"offset": e.Pos.Offset, "line": e.Pos.Line, "column": e.Pos.Column}
+	c.JSON(http.StatusBadRequest, resp)
+}
+
+// parseQueryDSL parses the keepShare dsl string to the syntax tree, errors are *dslError.
+func parseQueryDSL(queryString string) (*dslExpr, error) {
+	ret, err := parser.ParseString("", queryString)
+	if err != nil {
+		var perr participle.Error
+		if errors.As(err, &perr) {
+			return nil, &dslError{Pos: perr.Position(), Msg: perr.Message()}
+		}
+		return nil, err
+	}
+	return ret, nil
+}
+
+// compileQueryDSL compiles the keepShare dsl string to a condition of shared links of the user,
+// absolute times are in the location. Errors are *dslError.
+func compileQueryDSL(queryString string, userID string, loc *time.Location) (gen.Condition, error) {
+	e, err := parseQueryDSL(queryString)
+	if err != nil {
+		return nil, err
+	}
+	return (&dslCompiler{userID: userID, loc: loc, now: time.Now()}).expr(e)
+}
+
+type dslCompiler struct {
+	// userID is the owner of tags.
+	userID string
+	loc    *time.Location
+	now    time.Time
+}
+
+func (c *dslCompiler) expr(e *dslExpr) (field.Expr, error) {
+	ors := make([]field.Expr, 0, len(e.And))
+	for _, a := range e.And {
+		ands := make([]field.Expr, 0, len(a.Terms))
+		for _, t := range a.Terms {
+			x, err := c.term(t)
+			if err != nil {
+				return nil, err
+			}
+			ands = append(ands, x)
+		}
+		if len(ands) == 1 {
+			ors = append(ors, ands[0])
+		} else {
+			ors = append(ors, field.And(ands...))
+		}
+	}
+	if len(ors) == 1 {
+		return ors[0], nil
+	}
+	return field.Or(ors...), nil
+}
+
+func (c *dslCompiler) term(t *dslTerm) (x field.Expr, err error) {
+	if t.Group != nil {
+		x, err = c.expr(t.Group)
+	} else {
+		x, err = c.comparison(t.Cmp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Not {
+		return field.Not(x), nil
+	}
+	return x, nil
+}
+
+const (
+	dslMatch   = "match"
+	dslBetween = "between"
+	dslIn      = "in"
+
+	// dslTag is the key of tag names, which are matched exactly, e.g. `tag:"movies"` or `tag in ("a", "b")`.
+	dslTag = "tag"
+)
+
+func (c *dslCompiler) comparison(cmp *dslComparison) (field.Expr, error) {
+	op := strings.ToLower(cmp.Op)
+	if op == string(OpMatch) {
+		op = dslMatch
+	}
+
+	key := SupportQueryKey(cmp.Key)
+	f, ok := types[key]
+	if !ok && key != dslTag {
+		return nil, dslErrorf(cmp.Pos, "unknown key %q, supported keys: %s", cmp.Key, strings.Join(dslKeys(), ", "))
+	}
+	if cmp.Not && op != dslMatch && op != dslBetween && op != dslIn {
+		return nil, dslErrorf(cmp.Pos, "not is only allowed before in, between and match")
+	}
+	if _, text := f.(field.String); op == dslMatch && !text && key != dslTag {
+		return nil, dslErrorf(cmp.Pos, "%s can not be matched, match is for text only", cmp.Key)
+	}
+
+	values := []*dslScalar{cmp.Value.Scalar}
+	switch op {
+	case dslBetween:
+		if len(cmp.Value.List) != 2 {
+			return nil, dslErrorf(cmp.Pos, "between needs a list of 2 values, e.g. [1, 2]")
+		}
+		values = cmp.Value.List
+	case dslIn:
+		if cmp.Value.List == nil {
+			return nil, dslErrorf(cmp.Pos, "in needs a list of values, e.g. (1, 2)")
+		}
+		values = cmp.Value.List
+	default:
+		if cmp.Value.Scalar == nil {
+			return nil, dslErrorf(cmp.Pos, "%s needs a single value", cmp.Op)
+		}
+	}
+
+	if key == dslTag {
+		return c.tag(cmp, op, values)
+	}
+
+	switch f := f.(type) {
+	case field.String:
+		v, err := dslValues(values, c.textValue)
+		if err != nil {
+			return nil, err
+		}
+		if op == dslMatch {
+			if cmp.Not {
+				return f.NotLike("%" + escapeLike(v[0]) + "%"), nil
+			}
+			return f.Like("%" + escapeLike(v[0]) + "%"), nil
+		}
+		return dslCompare[string](f, op, cmp.Not, v), nil
+	case field.Int32:
+		v, err := dslValues(values, func(s *dslScalar) (int32, error) {
+			n, err := c.intValue(key, s)
+			if err == nil && (n > math.MaxInt32 || n < math.MinInt32) {
+				err = dslErrorf(s.Pos, "%d is out of range", n)
+			}
+			return int32(n), err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return dslCompare[int32](f, op, cmp.Not, v), nil
+	case field.Int64:
+		v, err := dslValues(values, func(s *dslScalar) (int64, error) { return c.intValue(key, s) })
+		if err != nil {
+			return nil, err
+		}
+		return dslCompare[int64](f, op, cmp.Not, v), nil
+	case field.Time:
+		v, err := dslValues(values, c.timeValue)
+		if err != nil {
+			return nil, err
+		}
+		return dslCompare[time.Time](f, op, cmp.Not, v), nil
+	}
+	return nil, dslErrorf(cmp.Pos, "unknown key %q", cmp.Key)
+}
+
+// tag returns the condition of shared links with any of the tags, or none of them for `!=` and `not`.
+func (c *dslCompiler) tag(cmp *dslComparison, op string, values []*dslScalar) (field.Expr, error) {
+	switch op {
+	case dslMatch, dslIn, string(OpEquals), string(OpNotEquals):
+	default:
+		return nil, dslErrorf(cmp.Pos, "tag can not be compared by %s", cmp.Op)
+	}
+	names, err := dslValues(values, c.textValue)
+	if err != nil {
+		return nil, err
+	}
+	x := taggedWith(c.userID, names...)
+	if cmp.Not || op == string(OpNotEquals) {
+		return field.Not(x), nil
+	}
+	return x, nil
+}
+
+// dslField is implemented by the fields of gen.
+type dslField[T any] interface {
+	Eq(T) field.Expr
+	Neq(T) field.Expr
+	Gt(T) field.Expr
+	Gte(T) field.Expr
+	Lt(T) field.Expr
+	Lte(T) field.Expr
+	Between(T, T) field.Expr
+	NotBetween(T, T) field.Expr
+	In(...T) field.Expr
+	NotIn(...T) field.Expr
+}
+
+// dslCompare returns the comparison, the number of values has been checked by the operator.
+func dslCompare[T any](f dslField[T], op string, not bool, values []T) field.Expr {
+	switch op {
+	case dslBetween:
+		if not {
+			return f.NotBetween(values[0], values[1])
+		}
+		return f.Between(values[0], values[1])
+	case dslIn:
+		if not {
+			return f.NotIn(values...)
+		}
+		return f.In(values...)
+	case string(OpNotEquals):
+		return f.Neq(values[0])
+	case string(OpGreaterThan):
+		return f.Gt(values[0])
+	case string(OpGreaterOrEqual):
+		return f.Gte(values[0])
+	case string(OpLessThan):
+		return f.Lt(values[0])
+	case string(OpLessOrEqual):
+		return f.Lte(values[0])
+	}
+	return f.Eq(values[0])
+}
+
+func dslValues[T any](values []*dslScalar, convert func(*dslScalar) (T, error)) ([]T, error) {
+	ret := make([]T, 0, len(values))
+	for _, v := range values {
+		t, err := convert(v)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
+
+func (c *dslCompiler) textValue(s *dslScalar) (string, error) {
+	switch {
+	case s.String != nil:
+		return *s.String, nil
+	case s.Number != nil:
+		return *s.Number, nil
+	}
+	return "", dslErrorf(s.Pos, "expected text, got %s", *s.Bool)
+}
+
+var (
+	dslNumberReg = regexp.MustCompile(`^([-+]?(?:\d+\.?\d*|\.\d+))([a-zA-Z]*)$`)
+
+	// sizeUnits are the units of sizes, per GB equal 1024 * 1024 * 1024 bytes.
+	sizeUnits = map[string]float64{"": 1, "b": 1, "kb": 1 << 10, "mb": 1 << 20, "gb": 1 << 30, "tb": 1 << 40}
+
+	// relativeTimeUnits are the units of times relative to now.
+	relativeTimeUnits = map[string]time.Duration{
+		"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+	}
+
+	// timeLayouts are the layouts of absolute times, the time zone of the user is used if there is no zone in the layout.
+	timeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", time.RFC3339}
+)
+
+// splitNumber splits the number and the lower-case unit of values like 1.5GB.
+func splitNumber(s *dslScalar) (float64, string, error) {
+	text := ""
+	switch {
+	case s.Number != nil:
+		text = *s.Number
+	case s.String != nil:
+		text = strings.TrimSpace(*s.String)
+	default:
+		return 0, "", dslErrorf(s.Pos, "expected a number, got %s", *s.Bool)
+	}
+	m := dslNumberReg.FindStringSubmatch(text)
+	if m == nil {
+		return 0, "", dslErrorf(s.Pos, "expected a number, got %q", text)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, "", dslErrorf(s.Pos, "invalid number %q", m[1])
+	}
+	return n, strings.ToLower(m[2]), nil
+}
+
+func (c *dslCompiler) intValue(key SupportQueryKey, s *dslScalar) (int64, error) {
+	n, unit, err := splitNumber(s)
+	if err != nil {
+		return 0, err
+	}
+	if key != "size" {
+		if unit != "" {
+			return 0, dslErrorf(s.Pos, "%s has no units", key)
+		}
+		return int64(n), nil
+	}
+	multiple, ok := sizeUnits[unit]
+	if !ok {
+		return 0, dslErrorf(s.Pos, "unknown size unit %q, supported units: B, KB, MB, GB, TB", unit)
+	}
+	return int64(n * multiple), nil
+}
+
+func (c *dslCompiler) timeValue(s *dslScalar) (time.Time, error) {
+	if s.String != nil {
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(*s.String), c.loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, dslErrorf(s.Pos, "invalid time %q, e.g. \"2006-01-02 15:04:05\" or -7d", *s.String)
+	}
+
+	n, unit, err := splitNumber(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	d, ok := relativeTimeUnits[unit]
+	if !ok || !strings.ContainsAny((*s.Number)[:1], "-+") {
+		return time.Time{}, dslErrorf(s.Pos, "invalid relative time %q, e.g. -7d, -12h, +30m", *s.Number)
+	}
+	return c.now.Add(time.Duration(n * float64(d))), nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike escapes wildcards of LIKE patterns.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// dslKeys returns the keys of the search DSL.
+func dslKeys() []string {
+	keys := make([]string, 0, len(types)+1)
+	for k := range types {
+		keys = append(keys, string(k))
+	}
+	keys = append(keys, dslTag)
+	slices.Sort(keys)
+	return keys
+}
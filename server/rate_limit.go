@@ -0,0 +1,224 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/ratelimit"
+	"github.com/KeepShareOrg/keepshare/pkg/util"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+)
+
+// tokenLimiter takes tokens from buckets, it is implemented by ratelimit.Limiter.
+type tokenLimiter interface {
+	Allow(ctx context.Context, buckets ...ratelimit.Bucket) (*ratelimit.Result, error)
+}
+
+// limiter limits new host tasks of auto sharing links, it is nil if redis is not initialized.
+var limiter tokenLimiter
+
+const (
+	keyRateLimit  = "rate_limit"
+	keyRetryAfter = "retry_after"
+	// keyJSONMode is set in the gin context if the client wants JSON responses only.
+	keyJSONMode = "json_mode"
+)
+
+// rateLimits are the limits of auto sharing links of a user, empty fields are the defaults of configs.
+// The limit of a client IP is shared by all channels, so it is server-wide and can not be overridden.
+type rateLimits struct {
+	Channel   string `json:"channel,omitempty"`
+	ChannelIP string `json:"channel_ip,omitempty"`
+}
+
+// parse parses the limits of the user, the defaults of configs are used for empty fields.
+func (r *rateLimits) parse() (channel, channelIP ratelimit.Limit, err error) {
+	if channel, err = ratelimit.ParseLimit(util.FirstNotEmpty(r.Channel, config.RateLimitChannel())); err != nil {
+		return
+	}
+	channelIP, err = ratelimit.ParseLimit(util.FirstNotEmpty(r.ChannelIP, config.RateLimitChannelIP()))
+	return
+}
+
+// ipRateLimit parses the server-wide limit of a client IP.
+func ipRateLimit() (ratelimit.Limit, error) {
+	return ratelimit.ParseLimit(config.RateLimitIP())
+}
+
+func userRateLimits(user *model.User) *rateLimits {
+	r := &rateLimits{}
+	if user.RateLimits != "" {
+		if err := json.Unmarshal([]byte(user.RateLimits), r); err != nil {
+			log.WithField("user_id", user.ID).Errorf("unmarshal rate limits err: %v", err)
+		}
+	}
+	return r
+}
+
+// rateLimitedError is returned if a bucket is out of tokens.
+type rateLimitedError struct {
+	bucket     string
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited by %s, retry after %s", e.bucket, e.retryAfter)
+}
+
+// takeTokens takes a token from every bucket, the kinds of buckets are the prefixes of keys before the colon.
+// Redis errors are logged only and the visit is allowed.
+func takeTokens(ctx context.Context, buckets ...ratelimit.Bucket) *rateLimitedError {
+	if limiter == nil {
+		return nil
+	}
+	r, err := limiter.Allow(ctx, buckets...)
+	if err != nil {
+		log.WithContext(ctx).Errorf("rate limit err: %v", err)
+		return nil
+	}
+	if r.Allowed {
+		return nil
+	}
+	kind, _, _ := strings.Cut(r.Denied, ":")
+	return &rateLimitedError{bucket: kind, retryAfter: r.RetryAfter}
+}
+
+// limitAutoSharing takes tokens of the channel, the client IP and both of them,
+// it is called only if an auto sharing link creates or re-creates a host task.
+func limitAutoSharing(ctx context.Context, user *model.User, ip string) *rateLimitedError {
+	channel, channelIP, err := userRateLimits(user).parse()
+	if err != nil {
+		log.WithContext(ctx).WithField("user_id", user.ID).Errorf("parse rate limits err: %v", err)
+		return nil
+	}
+	ipLimit, err := ipRateLimit()
+	if err != nil {
+		log.WithContext(ctx).Errorf("parse rate limit of client IPs err: %v", err)
+		return nil
+	}
+	return takeTokens(ctx,
+		ratelimit.Bucket{Key: "channel:" + user.Channel, Limit: channel},
+		ratelimit.Bucket{Key: "ip:" + ip, Limit: ipLimit},
+		ratelimit.Bucket{Key: "channel_ip:" + user.Channel + ":" + ip, Limit: channelIP},
+	)
+}
+
+// limitHostTasks takes a token of the global limit of new host tasks.
+func limitHostTasks(ctx context.Context) *rateLimitedError {
+	l, err := ratelimit.ParseLimit(config.RateLimitHostTasks())
+	if err != nil {
+		log.WithContext(ctx).Errorf("parse rate limit of host tasks err: %v", err)
+		return nil
+	}
+	return takeTokens(ctx, ratelimit.Bucket{Key: "host_tasks", Limit: l})
+}
+
+var rateLimitedPage = template.Must(template.New("rate_limited").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="{{.RetryAfter}}">
+<title>KeepShare</title>
+</head>
+<body style="font-family: sans-serif; text-align: center; padding-top: 20vh;">
+<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+// respondRateLimited records the limited bucket in the report and responds, see respRateLimited.
+func respondRateLimited(c *gin.Context, report *log.Report, e *rateLimitedError) {
+	report.Sets(Map{
+		keyRateLimit:    e.bucket,
+		keyRetryAfter:   e.retryAfter.Milliseconds(),
+		keyRedirectType: "rate_limited",
+		constant.Error:  e.Error(),
+	})
+	respRateLimited(c, e)
+}
+
+// respRateLimited responds 429 with the Retry-After header, browsers get a page which reloads after the duration
+// unless JSON mode is set.
+func respRateLimited(c *gin.Context, e *rateLimitedError) {
+	seconds := strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds())))
+	c.Header("Retry-After", seconds)
+	resp := mdw.ErrResp(c, "too_many_requests", i18n.WithDataMap(keyRetryAfter, seconds))
+
+	if c.GetBool(keyJSONMode) || !strings.Contains(c.GetHeader("Accept"), "text/html") {
+		c.JSON(http.StatusTooManyRequests, resp)
+		return
+	}
+	c.Status(http.StatusTooManyRequests)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = rateLimitedPage.Execute(c.Writer, map[string]any{"RetryAfter": seconds, "Message": resp[constant.Message]})
+}
+
+func getRateLimits(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	channel, channelIP, _ := userRateLimits(user).parse()
+	ip, _ := ipRateLimit()
+	c.JSON(http.StatusOK, Map{
+		"limits": userRateLimits(user),
+		"effective": Map{
+			"channel":    channel.String(),
+			"ip":         ip.String(),
+			"channel_ip": channelIP.String(),
+		},
+	})
+}
+
+func setRateLimits(c *gin.Context) {
+	var req rateLimits
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	if _, _, err := req.parse(); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	var limits string
+	if req != (rateLimits{}) {
+		b, _ := json.Marshal(req)
+		limits = string(b)
+	}
+	if len(limits) > 255 {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "limits are too long")))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.User
+	if _, err := t.WithContext(ctx).Where(t.ID.Eq(c.GetString(constant.UserID))).Update(t.RateLimits, limits); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"limits": req})
+}
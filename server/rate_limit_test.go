@@ -0,0 +1,114 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/locale"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/ratelimit"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUserRateLimits(t *testing.T) {
+	viper.Set("rate_limit_channel", "100/1m")
+	viper.Set("rate_limit_ip", "10/1m")
+	viper.Set("rate_limit_channel_ip", "")
+	defer func() {
+		for _, k := range []string{"rate_limit_channel", "rate_limit_ip", "rate_limit_channel_ip"} {
+			viper.Set(k, nil)
+		}
+	}()
+
+	channel, channelIP, err := userRateLimits(&model.User{RateLimits: `{"ip":"0","channel_ip":"5/1s"}`}).parse()
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Burst: 100, Period: time.Minute}, channel, "default of configs")
+	assert.Equal(t, ratelimit.Limit{Burst: 5, Period: time.Second}, channelIP, "overridden by the user")
+
+	ip, err := ipRateLimit()
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Limit{Burst: 10, Period: time.Minute}, ip, "not overridden by the user")
+
+	_, _, err = (&rateLimits{Channel: "abc"}).parse()
+	assert.Error(t, err)
+}
+
+func TestRespRateLimited(t *testing.T) {
+	require.NoError(t, i18n.Load(locale.FS))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		respondRateLimited(c, log.NewReport("test"), &rateLimitedError{bucket: "ip", retryAfter: 1500 * time.Millisecond})
+	})
+
+	for accept, contentType := range map[string]string{
+		"application/json":                          "application/json",
+		"text/html,application/xhtml+xml,*/*;q=0.8": "text/html",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Header().Get("Content-Type"), contentType)
+		assert.Contains(t, w.Body.String(), "retry after 2 seconds")
+	}
+}
+
+// denyLimiter denies all buckets.
+type denyLimiter struct{}
+
+func (denyLimiter) Allow(_ context.Context, buckets ...ratelimit.Bucket) (*ratelimit.Result, error) {
+	return &ratelimit.Result{Denied: buckets[0].Key, RetryAfter: time.Second}, nil
+}
+
+func TestExistingSharedLinksNotLimited(t *testing.T) {
+	db := useDryRunSharedLink(t)
+	hoststest.Register(t, "limitfake", hoststest.NewFake())
+	prev := limiter
+	limiter = denyLimiter{}
+	t.Cleanup(func() { limiter = prev })
+
+	user := &model.User{ID: "u1", Channel: "abcd1234"}
+	candidates := []*hosts.HostWithProperties{hosts.Get("limitfake")}
+	existing := "magnet:?xt=urn:btih:3e5f0ef1240ff8906281955666e73d7f8a14e42b"
+	err := db.Callback().Query().After("gorm:query").Register("test:rows", func(tx *gorm.DB) {
+		if row, ok := tx.Statement.Dest.(*model.SharedLink); ok && slices.Contains(tx.Statement.Vars, any(lk.Hash(existing))) {
+			*row = model.SharedLink{AutoID: 1, UserID: user.ID, Host: "limitfake", State: share.StatusOK.String(),
+				OriginalLink: existing, OriginalLinkHash: lk.Hash(existing), HostSharedLink: "", LastVisitedAt: time.Now()}
+			tx.RowsAffected = 1
+		}
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		sh, state, err := createShareLinkIfNotExist(ctx, user, candidates, existing, share.AutoShare, "1.2.3.4")
+		require.NoError(t, err, "visits to existing OK links are not limited")
+		assert.Equal(t, share.StatusOK, state)
+		assert.Equal(t, int64(1), sh.AutoID)
+	}
+
+	_, _, err = createShareLinkIfNotExist(ctx, user, candidates, "https://example.com/a.mp4", share.AutoShare, "1.2.3.4")
+	var limited *rateLimitedError
+	assert.ErrorAs(t, err, &limited, "new host tasks are limited")
+}
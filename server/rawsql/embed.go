@@ -10,3 +10,10 @@ import "embed"
 //
 //go:embed *.sql
 var FS embed.FS
+
+// Migrations holds the statements changing existing tables, such as ALTER TABLE ... ADD COLUMN.
+// Each file changes a table, the files are applied in the order of names and recorded in keepshare_schema_migration.
+// New columns and indexes must also be added to the CREATE TABLE statements in FS.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
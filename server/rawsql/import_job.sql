@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS `keepshare_import_job`
+(
+    `id`         bigint       NOT NULL AUTO_INCREMENT,
+    `user_id`    varchar(16)  NOT NULL,
+    `host`       varchar(20)  NOT NULL,
+    `filename`   varchar(255) NOT NULL DEFAULT '',
+    `total`      int          NOT NULL DEFAULT 0, # non-empty lines of the upload
+    `accepted`   int          NOT NULL DEFAULT 0, # links queued to create on the host
+    `duplicate`  int          NOT NULL DEFAULT 0, # links repeated in the upload or already shared on the host
+    `invalid`    int          NOT NULL DEFAULT 0,
+    `moderated`  int          NOT NULL DEFAULT 0, # accepted links blocked or redirected by moderation rules
+    `ok`         int          NOT NULL DEFAULT 0, # accepted links created on the host
+    `error`      int          NOT NULL DEFAULT 0, # accepted links failed to create on the host
+    `created_at` datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `user_id` (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
@@ -0,0 +1,2 @@
+ALTER TABLE `keepshare_user`
+    ADD COLUMN `host_policy` varchar(64) NOT NULL DEFAULT '' AFTER `email_verified`;
@@ -0,0 +1,3 @@
+ALTER TABLE `keepshare_user`
+    ADD COLUMN `signed_mode` int NOT NULL DEFAULT 0 AFTER `host_policy`,
+    ADD COLUMN `link_secret` varchar(64) NOT NULL DEFAULT '' AFTER `signed_mode`;
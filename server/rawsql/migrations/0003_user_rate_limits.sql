@@ -0,0 +1,2 @@
+ALTER TABLE `keepshare_user`
+    ADD COLUMN `rate_limits` varchar(255) NOT NULL DEFAULT '' AFTER `link_secret`;
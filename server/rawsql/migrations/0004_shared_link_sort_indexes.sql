@@ -0,0 +1,5 @@
+ALTER TABLE `keepshare_shared_link`
+    ADD INDEX `user_id.visitor` (`user_id`, `visitor`),
+    ADD INDEX `user_id.stored` (`user_id`, `stored`),
+    ADD INDEX `user_id.size` (`user_id`, `size`),
+    ADD INDEX `user_id.last_visited_at` (`user_id`, `last_visited_at`);
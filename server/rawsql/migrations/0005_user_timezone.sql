@@ -0,0 +1,2 @@
+ALTER TABLE `keepshare_user`
+    ADD COLUMN `timezone` varchar(64) NOT NULL DEFAULT '' AFTER `rate_limits`;
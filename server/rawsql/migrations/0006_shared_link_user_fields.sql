@@ -0,0 +1,5 @@
+ALTER TABLE `keepshare_shared_link`
+    ADD COLUMN `custom_title` varchar(256) NOT NULL DEFAULT '' AFTER `title`,
+    ADD COLUMN `note` varchar(1024) NOT NULL DEFAULT '' AFTER `custom_title`,
+    ADD COLUMN `pinned` int NOT NULL DEFAULT 0 AFTER `note`,
+    ADD COLUMN `expires_at` datetime NOT NULL DEFAULT '2000-01-01 00:00:00' AFTER `pinned`;
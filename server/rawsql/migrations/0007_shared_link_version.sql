@@ -0,0 +1,2 @@
+ALTER TABLE `keepshare_shared_link`
+    ADD COLUMN `version` bigint NOT NULL DEFAULT 0 AFTER `expires_at`;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS `keepshare_moderation_rule`
+(
+    `auto_id`        bigint       NOT NULL AUTO_INCREMENT,
+    `name`           varchar(64)  NOT NULL DEFAULT '',
+    `enabled`        int          NOT NULL DEFAULT 1, # 0: disabled, 1: enabled
+    `priority`       int          NOT NULL DEFAULT 0, # rules with higher priorities are evaluated first
+    `action`         varchar(16)  NOT NULL, # block, redirect, allow
+    `channel`        varchar(32)  NOT NULL DEFAULT '',
+    `user_id`        varchar(16)  NOT NULL DEFAULT '',
+    `info_hash`      char(40)     NOT NULL DEFAULT '', # the hash of original links
+    `url_regex`      varchar(512) NOT NULL DEFAULT '',
+    `filename_regex` varchar(512) NOT NULL DEFAULT '',
+    `min_size`       bigint       NOT NULL DEFAULT 0,
+    `max_size`       bigint       NOT NULL DEFAULT 0, # 0 means unlimited
+    `extensions`     varchar(255) NOT NULL DEFAULT '', # file extensions separated by comma, e.g. mp4,mkv
+    `hits`           bigint       NOT NULL DEFAULT 0,
+    `last_hit_at`    datetime     NOT NULL DEFAULT '1970-01-01 00:00:00',
+    `created_at`     datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at`     datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`auto_id`),
+    KEY `updated_at` (`updated_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
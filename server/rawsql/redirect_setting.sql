@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS `keepshare_redirect_setting`
+(
+    `user_id`          varchar(16)   NOT NULL,
+    `append_params`    varchar(255)  NOT NULL DEFAULT '', # url encoded query parameters appended to host shared links, e.g. act=play
+    `interstitial`     int           NOT NULL DEFAULT 0, # 0: redirect directly, 1: show a page before redirecting to host shared links
+    `status_page_url`  varchar(1024) NOT NULL DEFAULT '', # custom status page of shared links which are not ready, default to the console
+    `sensitive_action` varchar(16)   NOT NULL DEFAULT 'status', # status, original, not_found
+    `blocked_action`   varchar(16)   NOT NULL DEFAULT 'status', # status, original, not_found
+    `created_at`       datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at`       datetime      NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`user_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS `keepshare_schema_migration`
+(
+    `version`    varchar(128) NOT NULL, # file name of the migration without .sql, e.g. 0001_user_host_policy, or a one-time data step, e.g. import_legacy_moderation_rules
+    `applied_at` datetime     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`version`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
@@ -15,6 +15,11 @@ CREATE TABLE IF NOT EXISTS `keepshare_shared_link`
     `last_stored_at`        datetime     NOT NULL DEFAULT '2000-01-01 00:00:00',
     `revenue`               bigint       NOT NULL DEFAULT 0,
     `title`                 varchar(256) NOT NULL DEFAULT '',
+    `custom_title`          varchar(256) NOT NULL DEFAULT '', # set by the user, it takes precedence over the title of the host
+    `note`                  varchar(1024) NOT NULL DEFAULT '',
+    `pinned`                int          NOT NULL DEFAULT 0,
+    `expires_at`            datetime     NOT NULL DEFAULT '2000-01-01 00:00:00', # auto sharing links are unavailable after it, the default means never
+    `version`               bigint       NOT NULL DEFAULT 0, # increased by the edits of the user only, see patchSharedLink
     `original_link_hash`    char(40)     NOT NULL,
     `host_shared_link_hash` char(40)     NOT NULL,
     `original_link`         text         NOT NULL,
@@ -23,6 +28,10 @@ CREATE TABLE IF NOT EXISTS `keepshare_shared_link`
     PRIMARY KEY (`auto_id`),
     UNIQUE KEY `original_link_hash.user_id.host` (`original_link_hash`, `user_id`, `host`),
     KEY `user_id.created_at` (`user_id`, `created_at`),
+    KEY `user_id.visitor` (`user_id`, `visitor`),
+    KEY `user_id.stored` (`user_id`, `stored`),
+    KEY `user_id.size` (`user_id`, `size`),
+    KEY `user_id.last_visited_at` (`user_id`, `last_visited_at`),
     KEY `host_shared_link_hash.user_id` (`host_shared_link_hash`, `user_id`),
     KEY `state.created_at` (`state`, `created_at`),
     KEY `state.updated_at` (`state`, `updated_at`)
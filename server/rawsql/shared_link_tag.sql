@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS `keepshare_shared_link_tag`
+(
+    `tag_id`         bigint   NOT NULL,
+    `shared_link_id` bigint   NOT NULL, # auto_id of keepshare_shared_link
+    `created_at`     datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`tag_id`, `shared_link_id`),
+    KEY `shared_link_id` (`shared_link_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `keepshare_tag`
+(
+    `id`         bigint      NOT NULL AUTO_INCREMENT,
+    `user_id`    varchar(16) NOT NULL,
+    `name`       varchar(64) NOT NULL,
+    `color`      varchar(16) NOT NULL DEFAULT '', # css color of the tag in the console, e.g. #ff0000
+    `created_at` datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `user_id.name` (`user_id`, `name`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_0900_bin;
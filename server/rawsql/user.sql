@@ -6,6 +6,11 @@ CREATE TABLE IF NOT EXISTS `keepshare_user`
     `password_hash`  char(64)    NOT NULL,
     `channel`        varchar(32) NOT NULL,
     `email_verified` int         NOT NULL DEFAULT 0, # 0: not verified, 1: verified
+    `host_policy`    varchar(64) NOT NULL DEFAULT '', # ordered hosts separated by comma, e.g. pikpak,webdav
+    `signed_mode`    int         NOT NULL DEFAULT 0, # 0: off, 1: on, only signed auto sharing links can create shared links
+    `link_secret`    varchar(64) NOT NULL DEFAULT '', # secret to sign auto sharing links
+    `rate_limits`    varchar(255) NOT NULL DEFAULT '', # json of rate limits of auto sharing links, e.g. {"channel":"60/1m"}
+    `timezone`       varchar(64) NOT NULL DEFAULT '', # IANA time zone of search times, e.g. Asia/Shanghai, empty for the server time zone
     `created_at`     datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP,
     `updated_at`     datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`),
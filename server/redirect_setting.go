@@ -0,0 +1,273 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// Actions for visits to shared links which are SENSITIVE or BLOCKED.
+const (
+	// unavailableStatus redirects to the status page.
+	unavailableStatus = "status"
+	// unavailableOriginal redirects to the original link.
+	unavailableOriginal = "original"
+	// unavailableNotFound responds 404 without redirecting.
+	unavailableNotFound = "not_found"
+)
+
+const (
+	// defaultAppendParams plays videos automatically on PikPak.
+	defaultAppendParams = "act=play"
+	// interstitialSeconds is the delay before redirecting from the interstitial page.
+	interstitialSeconds = 5
+)
+
+// defaultRedirectSetting returns the setting of users who have not set theirs.
+func defaultRedirectSetting(userID string) *model.RedirectSetting {
+	return &model.RedirectSetting{
+		UserID:          userID,
+		AppendParams:    defaultAppendParams,
+		SensitiveAction: unavailableStatus,
+		BlockedAction:   unavailableStatus,
+	}
+}
+
+// userRedirectSetting returns the redirect setting of the user, errors are logged and the default setting is returned.
+func userRedirectSetting(ctx context.Context, userID string) *model.RedirectSetting {
+	t := query.RedirectSetting
+	s, err := t.WithContext(ctx).Where(t.UserID.Eq(userID)).Take()
+	if err != nil {
+		if !gormutil.IsNotFoundError(err) {
+			log.WithContext(ctx).WithField(constant.UserID, userID).Errorf("query redirect setting err: %v", err)
+		}
+		return defaultRedirectSetting(userID)
+	}
+	return s
+}
+
+// validateRedirectSetting checks the setting, the parameters to append must be supported by at least one of the hosts,
+// see hosts.Capabilities.RedirectParams.
+func validateRedirectSetting(s *model.RedirectSetting, candidates []*hosts.HostWithProperties) error {
+	if len(s.AppendParams) > 255 {
+		return fmt.Errorf("append_params is too long")
+	}
+	params, err := url.ParseQuery(s.AppendParams)
+	if err != nil {
+		return fmt.Errorf("invalid append_params: %w", err)
+	}
+	for name := range params {
+		supported := slices.ContainsFunc(candidates, func(h *hosts.HostWithProperties) bool {
+			return slices.Contains(h.Capabilities().RedirectParams, name)
+		})
+		if !supported {
+			return fmt.Errorf("parameter %q is not supported by hosts %s", name, hostNames(candidates))
+		}
+	}
+
+	if s.StatusPageURL != "" {
+		u, err := url.Parse(s.StatusPageURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Fragment != "" {
+			return fmt.Errorf("invalid status_page_url %q", s.StatusPageURL)
+		}
+		if len(s.StatusPageURL) > 1024 {
+			return fmt.Errorf("status_page_url is too long")
+		}
+	}
+
+	for _, action := range []string{s.SensitiveAction, s.BlockedAction} {
+		switch action {
+		case unavailableStatus, unavailableOriginal, unavailableNotFound:
+		default:
+			return fmt.Errorf("invalid action %q", action)
+		}
+	}
+	if s.Interstitial != 0 && s.Interstitial != 1 {
+		return fmt.Errorf("invalid interstitial %d", s.Interstitial)
+	}
+	return nil
+}
+
+func hostNames(candidates []*hosts.HostWithProperties) string {
+	names := make([]string, 0, len(candidates))
+	for _, h := range candidates {
+		names = append(names, h.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// hostLinkWithParams appends the parameters of the setting which are supported by the host to the host shared link.
+func hostLinkWithParams(s *model.RedirectSetting, hostName, link string) string {
+	host := hosts.Get(hostName)
+	if host == nil || host.Host == nil || s.AppendParams == "" || link == "" {
+		return link
+	}
+	params, _ := url.ParseQuery(s.AppendParams)
+	supported := host.Capabilities().RedirectParams
+	for name := range params {
+		if !slices.Contains(supported, name) {
+			delete(params, name)
+		}
+	}
+	if len(params) == 0 {
+		return link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	q := u.Query()
+	for name, values := range params {
+		q[name] = values
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// statusPageURL returns the page showing the state of the shared link, the console page is used by default.
+// The custom page of the setting gets the state too.
+func statusPageURL(s *model.RedirectSetting, sh *model.SharedLink, requestID string) string {
+	if s.StatusPageURL == "" {
+		return fmt.Sprintf("https://%s/console/shared/status?id=%d&request_id=%s", config.RootDomain(), sh.AutoID, requestID)
+	}
+	sep := "?"
+	if strings.Contains(s.StatusPageURL, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%sid=%d&request_id=%s&state=%s", s.StatusPageURL, sep, sh.AutoID, requestID, url.QueryEscape(sh.State))
+}
+
+// unavailableAction returns the action of the setting for the state, it is empty for other states.
+func unavailableAction(s *model.RedirectSetting, state share.State) string {
+	switch state {
+	case share.StatusSensitive:
+		return s.SensitiveAction
+	case share.StatusBlocked:
+		return s.BlockedAction
+	}
+	return ""
+}
+
+var interstitialPage = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="{{.Seconds}};url={{.URL}}">
+<title>{{if .Title}}{{.Title}} - {{end}}KeepShare</title>
+</head>
+<body style="font-family: sans-serif; text-align: center; padding-top: 20vh;">
+{{if .Title}}<h3>{{.Title}}</h3>{{end}}
+<p><a href="{{.URL}}">{{.URL}}</a></p>
+</body>
+</html>
+`))
+
+// respInterstitial responds a page which redirects to the url after a few seconds.
+func respInterstitial(c *gin.Context, title, u string) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	_ = interstitialPage.Execute(c.Writer, map[string]any{"Seconds": interstitialSeconds, "URL": u, "Title": title})
+}
+
+func getRedirectSetting(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	supported := Map{}
+	for _, h := range hostPolicy(user) {
+		supported[h.Name()] = h.Capabilities().RedirectParams
+	}
+	c.JSON(http.StatusOK, Map{
+		"setting":          userRedirectSetting(ctx, user.ID),
+		"supported_params": supported,
+	})
+}
+
+func setRedirectSetting(c *gin.Context) {
+	var req struct {
+		AppendParams    string `json:"append_params"`
+		Interstitial    bool   `json:"interstitial"`
+		StatusPageURL   string `json:"status_page_url"`
+		SensitiveAction string `json:"sensitive_action"`
+		BlockedAction   string `json:"blocked_action"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	now := time.Now()
+	s := &model.RedirectSetting{
+		UserID:          user.ID,
+		AppendParams:    strings.TrimPrefix(strings.TrimSpace(req.AppendParams), "?"),
+		StatusPageURL:   strings.TrimSpace(req.StatusPageURL),
+		SensitiveAction: req.SensitiveAction,
+		BlockedAction:   req.BlockedAction,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if req.Interstitial {
+		s.Interstitial = 1
+	}
+	if s.SensitiveAction == "" {
+		s.SensitiveAction = unavailableStatus
+	}
+	if s.BlockedAction == "" {
+		s.BlockedAction = unavailableStatus
+	}
+	if err := validateRedirectSetting(s, hostPolicy(user)); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	t := query.RedirectSetting
+	err = t.WithContext(ctx).Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{
+		t.AppendParams.ColumnName().String(),
+		t.Interstitial.ColumnName().String(),
+		t.StatusPageURL.ColumnName().String(),
+		t.SensitiveAction.ColumnName().String(),
+		t.BlockedAction.ColumnName().String(),
+		t.UpdatedAt.ColumnName().String(),
+	})}).Create(s)
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"setting": s})
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectSetting(t *testing.T) {
+	play := hoststest.NewFake()
+	play.Caps.RedirectParams = []string{"act", "t"}
+	candidates := []*hosts.HostWithProperties{
+		hoststest.Register(t, "redirectplain", hoststest.NewFake()),
+		hoststest.Register(t, "redirectplay", play),
+	}
+	valid := func(s *model.RedirectSetting) *model.RedirectSetting {
+		s.SensitiveAction = lo.Ternary(s.SensitiveAction == "", unavailableStatus, s.SensitiveAction)
+		s.BlockedAction = lo.Ternary(s.BlockedAction == "", unavailableStatus, s.BlockedAction)
+		return s
+	}
+	assert.NoError(t, validateRedirectSetting(valid(&model.RedirectSetting{AppendParams: "act=play&t=10"}), candidates))
+	assert.NoError(t, validateRedirectSetting(valid(&model.RedirectSetting{StatusPageURL: "https://example.com/status?from=ks", BlockedAction: unavailableNotFound}), candidates))
+	assert.Error(t, validateRedirectSetting(valid(&model.RedirectSetting{AppendParams: "act=play"}), candidates[:1]), "not supported by the host")
+	assert.Error(t, validateRedirectSetting(valid(&model.RedirectSetting{AppendParams: "a=%zz"}), candidates))
+	assert.Error(t, validateRedirectSetting(valid(&model.RedirectSetting{StatusPageURL: "javascript:alert(1)"}), candidates))
+	assert.Error(t, validateRedirectSetting(valid(&model.RedirectSetting{SensitiveAction: "drop"}), candidates))
+
+	s := defaultRedirectSetting("user")
+	assert.Equal(t, "https://a.com/s/1?act=play", hostLinkWithParams(s, "redirectplay", "https://a.com/s/1"))
+	assert.Equal(t, "https://a.com/s/1", hostLinkWithParams(s, "redirectplain", "https://a.com/s/1"), "unsupported params are skipped")
+	assert.Equal(t, "https://a.com/s/1?act=play&p=1", hostLinkWithParams(s, "redirectplay", "https://a.com/s/1?p=1"))
+	s.AppendParams = ""
+	assert.Equal(t, "https://a.com/s/1", hostLinkWithParams(s, "redirectplay", "https://a.com/s/1"))
+
+	viper.Set("root_domain", "keepshare.test")
+	defer viper.Set("root_domain", nil)
+	sh := &model.SharedLink{AutoID: 7, State: share.StatusSensitive.String()}
+	assert.Equal(t, "https://keepshare.test/console/shared/status?id=7&request_id=r", statusPageURL(s, sh, "r"))
+	s.StatusPageURL = "https://example.com/status?from=ks"
+	assert.Equal(t, "https://example.com/status?from=ks&id=7&request_id=r&state=SENSITIVE", statusPageURL(s, sh, "r"))
+
+	s.SensitiveAction = unavailableOriginal
+	assert.Equal(t, unavailableOriginal, unavailableAction(s, share.StatusSensitive))
+	assert.Equal(t, unavailableStatus, unavailableAction(s, share.StatusBlocked))
+	assert.Equal(t, "", unavailableAction(s, share.StatusCreated))
+}
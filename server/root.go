@@ -6,6 +6,7 @@ package server
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"github.com/KeepShareOrg/keepshare/server/constant"
 	"io/fs"
@@ -26,6 +27,7 @@ import (
 	"github.com/KeepShareOrg/keepshare/pkg/i18n"
 	"github.com/KeepShareOrg/keepshare/pkg/log"
 	q "github.com/KeepShareOrg/keepshare/pkg/queue"
+	"github.com/KeepShareOrg/keepshare/pkg/ratelimit"
 	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
 	"github.com/KeepShareOrg/keepshare/server/query"
 	"github.com/KeepShareOrg/keepshare/static"
@@ -60,9 +62,17 @@ func Start() error {
 		constant.AsyncQueueSyncWorkerInfo:   3,
 		constant.AsyncQueueRefreshToken:     3,
 		constant.AsyncQueueStatisticTask:    1,
+		hosts.EventQueue:                    3,
+		constant.AsyncQueueImportJob:        1,
 	})
 	queue = queueIns.Client()
+	limiter = ratelimit.New(config.Redis(), "rate_limit:")
+	events = hosts.NewEventBus(queue)
+	eventsRedis = config.Redis()
+	go sharedLinkStreams.run(context.Background(), eventsRedis)
+	listenFileEvents(events)
 	queue.RegisterHandler(statisticTask, asynq.HandlerFunc(handleGetStatistics))
+	queue.RegisterHandler(importTask, asynq.HandlerFunc(handleImportChunk))
 
 	// load locales
 	if err := i18n.Load(locale.FS); err != nil {
@@ -70,17 +80,45 @@ func Start() error {
 	}
 	log.Info("loaded languages:", i18n.Languages())
 
+	middlewares, err := hostMiddlewares()
+	if err != nil {
+		return err
+	}
+	hosts.Use(middlewares...)
+
+	if linkInfo, err = linkInfoProvider(); err != nil {
+		return err
+	}
+	if err := importLegacyModerationRules(context.Background()); err != nil {
+		log.Error(err)
+	}
+	if err := moderation.load(context.Background()); err != nil {
+		log.Error(err)
+	}
+	if interval := config.ModerationReloadInterval(); interval > 0 {
+		go moderation.reloadLoop(interval)
+	}
 	hosts.Start(&hosts.Dependencies{
-		Mysql:  config.MySQL(),
-		Redis:  config.Redis(),
-		Mailer: config.Mailer(),
-		Queue:  queue,
+		Mysql:    config.MySQL(),
+		Redis:    config.Redis(),
+		Mailer:   config.Mailer(),
+		Queue:    queue,
+		Events:   events,
+		LinkInfo: linkInfo,
 	})
 
+	asyncTaskRunner := NewAsyncTaskRunner()
+	if err := asyncTaskRunner.ListenCompleteFiles(); err != nil {
+		return fmt.Errorf("listen complete files err: %w", err)
+	}
+
 	queueIns.Run() // Run after hosts start.
 
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		return fmt.Errorf("set trusted proxies err: %w", err)
+	}
 	router.Use(
 		gin.Recovery(),
 		mdw.CORS(),
@@ -95,6 +133,7 @@ func Start() error {
 	sessionRouter(router)
 	apiRouter(router)
 	consoleRouter(router)
+	hostsRouter(router)
 
 	router.NoRoute(autoRouter)
 
@@ -103,12 +142,21 @@ func Start() error {
 		Handler: router,
 	}
 
-	asyncTaskRunner := NewAsyncTaskRunner()
 	//go asyncTaskRunner.Run()
-	go asyncTaskRunner.ListenCompleteFiles()
 	return serveGraceful(srv)
 }
 
+// trustedProxies returns the configured proxies, nil if no proxy is trusted.
+func trustedProxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(config.TrustedProxies(), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
 func sessionRouter(router *gin.Engine) {
 	g := router.Group("/session")
 	g.Use(mdw.ContextWithAcceptLanguage)
@@ -135,10 +183,24 @@ func apiRouter(router *gin.Engine) {
 	g.POST("/donation", donationRedeemCode)
 
 	g.GET("/shared_link", querySharedLinkInfo) // front-end query shared link status, authentication is not required
+	g.GET("/shared_link/events", sharedLinkEvents)
+	g.GET("/resolve", resolveAutoSharingLink) // JSON outcome of auto sharing links, authentication is not required
 	g.GET("/shared_links", mdw.Auth, listSharedLinks)
+	g.GET("/shared_links/export", mdw.Auth, exportSharedLinks)
 	g.POST("/shared_links", mdw.Auth, createSharedLinks)
+	g.POST("/shared_links/torrent", mdw.Auth, createSharedLinksFromTorrents)
 	g.POST("/query_shared_links", mdw.Auth, batchQuerySharedLinksInfo)
 	g.DELETE("/shared_links", mdw.Auth, deleteSharedLinks)
+	g.PATCH("/shared_links/:id", mdw.Auth, patchSharedLink)
+	g.POST("/shared_links/tag", mdw.Auth, bulkTagSharedLinks(false))
+	g.POST("/shared_links/untag", mdw.Auth, bulkTagSharedLinks(true))
+	g.POST("/import_jobs", mdw.Auth, createImportJob)
+	g.GET("/import_jobs/:id", mdw.Auth, getImportJob)
+
+	g.GET("/tags", mdw.Auth, listTags)
+	g.POST("/tags", mdw.Auth, createTag)
+	g.PUT("/tags/:id", mdw.Auth, updateTag)
+	g.DELETE("/tags/:id", mdw.Auth, deleteTag)
 
 	g.POST("/storage/statistics", mdw.Auth, storageStatistics)
 	g.POST("/storage/release", mdw.Auth, storageRelease)
@@ -147,7 +209,30 @@ func apiRouter(router *gin.Engine) {
 	g.POST("/blacklist", mdw.Auth, addToBlackList)
 	g.DELETE("/blacklist", mdw.Auth, removeFromBlackList)
 
+	g.GET("/hosts", listHosts)
 	g.GET("/host/info", mdw.Auth, getHostInfo)
+	g.GET("/host/policy", mdw.Auth, getHostPolicy)
+	g.PUT("/host/policy", mdw.Auth, setHostPolicy)
+
+	g.GET("/signed_mode", mdw.Auth, getSignedMode)
+	g.PUT("/signed_mode", mdw.Auth, setSignedMode)
+	g.POST("/sign_links", mdw.Auth, signLinks)
+
+	g.GET("/rate_limits", mdw.Auth, getRateLimits)
+	g.PUT("/rate_limits", mdw.Auth, setRateLimits)
+
+	g.GET("/timezone", mdw.Auth, getTimezone)
+	g.PUT("/timezone", mdw.Auth, setTimezone)
+
+	g.GET("/redirect_setting", mdw.Auth, getRedirectSetting)
+	g.PUT("/redirect_setting", mdw.Auth, setRedirectSetting)
+
+	g.GET("/admin/moderation_rules", mdw.Auth, mdw.Admin, listModerationRules)
+	g.POST("/admin/moderation_rules", mdw.Auth, mdw.Admin, createModerationRule)
+	g.PUT("/admin/moderation_rules/:id", mdw.Auth, mdw.Admin, updateModerationRule)
+	g.DELETE("/admin/moderation_rules/:id", mdw.Auth, mdw.Admin, deleteModerationRule)
+	// expvar of the process, including the metrics of hosts.
+	g.GET("/admin/metrics", mdw.Auth, mdw.Admin, gin.WrapH(expvar.Handler()))
 
 	g.PATCH("/host/password", mdw.Auth, changeHostPassword)
 	g.GET("/host/password/task", mdw.Auth, getChangePasswordTaskInfo)
@@ -165,7 +250,6 @@ func consoleRouter(router *gin.Engine) {
 	// frontend pages.
 	if err != nil {
 		panic(err)
-		return
 	}
 	h := http.FileServer(http.FS(sub))
 
@@ -194,6 +278,18 @@ func consoleRouter(router *gin.Engine) {
 	})
 }
 
+// hostsRouter routes `/hosts/<name>/*` to the hosts serving their own pages.
+func hostsRouter(router *gin.Engine) {
+	for _, host := range hosts.GetAll() {
+		h, ok := hosts.As[http.Handler](host.Host)
+		if !ok {
+			continue
+		}
+		prefix := "/hosts/" + strings.ToLower(host.Name())
+		router.Any(prefix+"/*path", gin.WrapH(http.StripPrefix(prefix, h)))
+	}
+}
+
 var mockViteContentType = regexp.MustCompile(`(?i)\.(json|png)`)
 
 func consoleProxy() func(c *gin.Context) {
@@ -218,7 +314,7 @@ func consoleProxy() func(c *gin.Context) {
 	}
 }
 
-var autoSharingPath = regexp.MustCompile(`(?i)^/[a-z0-9]{8}/(magnet|http|https|ftp|ed2k):`)
+var autoSharingPath = regexp.MustCompile(`(?i)^/[a-z0-9]{8}/(magnet|http|https|ftp|ed2k|thunder):`)
 
 func autoRouter(c *gin.Context) {
 	switch {
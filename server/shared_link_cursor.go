@@ -0,0 +1,224 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+)
+
+// sharedLinkSortKeys are the keys which shared links can be sorted by, auto_id breaks ties.
+// Each of them is indexed with user_id, see rawsql/shared_link.sql.
+var sharedLinkSortKeys = []SupportQueryKey{"created_at", "visitor", "stored", "size", "last_visited_at"}
+
+// sharedLinkSort is the order of shared links, they are sorted by auto_id only if the key is empty.
+type sharedLinkSort struct {
+	Key  SupportQueryKey
+	Desc bool
+}
+
+// parseSharedLinkSort parses the sort parameter, `size` and `size:asc` are ascending, `-size` and `size:desc` are descending.
+// Shared links are sorted by auto_id descending if it is empty, which is the order of creation.
+func parseSharedLinkSort(s string) (*sharedLinkSort, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return &sharedLinkSort{Desc: true}, nil
+	}
+
+	ret := &sharedLinkSort{}
+	if strings.HasPrefix(s, "-") {
+		ret.Desc = true
+		s = s[1:]
+	} else if key, dir, ok := strings.Cut(s, ":"); ok {
+		switch strings.ToLower(dir) {
+		case "asc":
+		case "desc":
+			ret.Desc = true
+		default:
+			return nil, fmt.Errorf("invalid sort direction %q", dir)
+		}
+		s = key
+	}
+
+	ret.Key = SupportQueryKey(s)
+	if !slices.Contains(sharedLinkSortKeys, ret.Key) {
+		return nil, fmt.Errorf("unsupported sort key %q", s)
+	}
+	return ret, nil
+}
+
+// String returns the sort parameter, it is empty for the default order.
+func (s *sharedLinkSort) String() string {
+	if s.Key == "" {
+		return ""
+	}
+	if s.Desc {
+		return string(s.Key) + ":desc"
+	}
+	return string(s.Key) + ":asc"
+}
+
+// orders returns the ORDER BY expressions.
+func (s *sharedLinkSort) orders() []field.Expr {
+	t := query.SharedLink
+	var columns []field.OrderExpr
+	switch s.Key {
+	case "created_at":
+		columns = append(columns, t.CreatedAt)
+	case "visitor":
+		columns = append(columns, t.Visitor)
+	case "stored":
+		columns = append(columns, t.Stored)
+	case "size":
+		columns = append(columns, t.Size)
+	case "last_visited_at":
+		columns = append(columns, t.LastVisitedAt)
+	}
+	columns = append(columns, t.AutoID)
+
+	orders := make([]field.Expr, 0, len(columns))
+	for _, c := range columns {
+		if s.Desc {
+			orders = append(orders, c.Desc())
+		} else {
+			orders = append(orders, c)
+		}
+	}
+	return orders
+}
+
+// after returns the condition of shared links after the cursor in the order:
+// `key < value OR (key = value AND auto_id < id)` for descending orders, and the opposite for ascending.
+func (s *sharedLinkSort) after(c *sharedLinkCursor) gen.Condition {
+	t := query.SharedLink
+	id := t.AutoID.Gt(c.ID)
+	if s.Desc {
+		id = t.AutoID.Lt(c.ID)
+	}
+
+	var beyond, equal field.Expr
+	switch s.Key {
+	case "":
+		return id
+	case "created_at", "last_visited_at":
+		column, v := t.CreatedAt, time.Unix(0, c.Value)
+		if s.Key == "last_visited_at" {
+			column = t.LastVisitedAt
+		}
+		beyond, equal = column.Gt(v), column.Eq(v)
+		if s.Desc {
+			beyond = column.Lt(v)
+		}
+	case "visitor", "stored":
+		column, v := t.Visitor, int32(c.Value)
+		if s.Key == "stored" {
+			column = t.Stored
+		}
+		beyond, equal = column.Gt(v), column.Eq(v)
+		if s.Desc {
+			beyond = column.Lt(v)
+		}
+	case "size":
+		beyond, equal = t.Size.Gt(c.Value), t.Size.Eq(c.Value)
+		if s.Desc {
+			beyond = t.Size.Lt(c.Value)
+		}
+	}
+	return field.Or(beyond, field.And(equal, id))
+}
+
+// sharedLinkCursor is the position after the last shared link of a page, it is opaque to clients.
+type sharedLinkCursor struct {
+	// Sort is the order of the page, the cursor is invalid in other orders.
+	Sort string `json:"s,omitempty"`
+	// Value is the value of the sort key, times are in unix nanoseconds.
+	Value int64 `json:"v,omitempty"`
+	ID    int64 `json:"id"`
+}
+
+// newSharedLinkCursor returns the cursor after the shared link in the order.
+func newSharedLinkCursor(s *sharedLinkSort, last *model.SharedLink) *sharedLinkCursor {
+	c := &sharedLinkCursor{Sort: s.String(), ID: last.AutoID}
+	switch s.Key {
+	case "created_at":
+		c.Value = last.CreatedAt.UnixNano()
+	case "visitor":
+		c.Value = int64(last.Visitor)
+	case "stored":
+		c.Value = int64(last.Stored)
+	case "size":
+		c.Value = last.Size
+	case "last_visited_at":
+		c.Value = last.LastVisitedAt.UnixNano()
+	}
+	return c
+}
+
+func (c *sharedLinkCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// parseSharedLinkCursor parses the cursor returned by listSharedLinks.
+func parseSharedLinkCursor(s string) (*sharedLinkCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c sharedLinkCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// sharedLinkPage is the page of shared links to query, the cursor takes precedence over the page index.
+type sharedLinkPage struct {
+	Index  int
+	Limit  int
+	Sort   *sharedLinkSort
+	Cursor *sharedLinkCursor
+}
+
+// maxSharedLinkPageLimit is the maximum number of shared links of a page.
+const maxSharedLinkPageLimit = 1000
+
+// newSharedLinkPage returns the page of the parameters, the order of the cursor is used if sortParam is empty.
+// The limit is clamped to maxSharedLinkPageLimit.
+func newSharedLinkPage(index, limit int, sortParam, cursorParam string) (*sharedLinkPage, error) {
+	sort, err := parseSharedLinkSort(sortParam)
+	if err != nil {
+		return nil, err
+	}
+	if limit > maxSharedLinkPageLimit {
+		limit = maxSharedLinkPageLimit
+	}
+	page := &sharedLinkPage{Index: index, Limit: limit, Sort: sort}
+	if cursorParam == "" {
+		return page, nil
+	}
+
+	if page.Cursor, err = parseSharedLinkCursor(cursorParam); err != nil {
+		return nil, err
+	}
+	if sortParam == "" {
+		if page.Sort, err = parseSharedLinkSort(page.Cursor.Sort); err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+	} else if page.Cursor.Sort != sort.String() {
+		return nil, fmt.Errorf("the cursor is not in the order %q", sortParam)
+	}
+	return page, nil
+}
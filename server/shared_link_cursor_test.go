@@ -0,0 +1,121 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// useDryRunSharedLink makes query.SharedLink and the tables of tags build statements without executing them,
+// the dry-run DB is returned to register callbacks.
+func useDryRunSharedLink(t *testing.T) *gorm.DB {
+	cfg := &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true}
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), cfg)
+	require.NoError(t, err)
+
+	q := query.Use(db)
+	prev, prevTag, prevLinkTag := query.SharedLink, query.Tag, query.SharedLinkTag
+	query.SharedLink, query.Tag, query.SharedLinkTag = &q.SharedLink, &q.Tag, &q.SharedLinkTag
+	t.Cleanup(func() { query.SharedLink, query.Tag, query.SharedLinkTag = prev, prevTag, prevLinkTag })
+	return db
+}
+
+func TestParseSharedLinkSort(t *testing.T) {
+	useDryRunSharedLink(t)
+
+	tests := []struct {
+		param string
+		want  *sharedLinkSort
+		str   string
+	}{
+		{param: "", want: &sharedLinkSort{Desc: true}, str: ""},
+		{param: "size", want: &sharedLinkSort{Key: "size"}, str: "size:asc"},
+		{param: "-visitor", want: &sharedLinkSort{Key: "visitor", Desc: true}, str: "visitor:desc"},
+		{param: "last_visited_at:DESC", want: &sharedLinkSort{Key: "last_visited_at", Desc: true}, str: "last_visited_at:desc"},
+		{param: "created_at:asc", want: &sharedLinkSort{Key: "created_at"}, str: "created_at:asc"},
+		{param: "title"},
+		{param: "auto_id"},
+		{param: "size:up"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSharedLinkSort(tt.param)
+		if tt.want == nil {
+			assert.Error(t, err, tt.param)
+			continue
+		}
+		require.NoError(t, err, tt.param)
+		assert.Equal(t, tt.want, got, tt.param)
+		assert.Equal(t, tt.str, got.String(), tt.param)
+		assert.Len(t, got.orders(), lo.Ternary(got.Key == "", 1, 2), "auto_id is always ordered")
+	}
+}
+
+func TestSharedLinkCursor(t *testing.T) {
+	now := time.Now()
+	last := &model.SharedLink{AutoID: 42, Size: 1 << 30, Visitor: 7, Stored: 3, CreatedAt: now, LastVisitedAt: now.Add(-time.Hour)}
+
+	for _, param := range []string{"", "size", "-visitor", "stored", "created_at", "-last_visited_at"} {
+		sort, err := parseSharedLinkSort(param)
+		require.NoError(t, err)
+
+		cursor := newSharedLinkCursor(sort, last).String()
+		page, err := newSharedLinkPage(1, 10, param, cursor)
+		require.NoError(t, err, param)
+		assert.Equal(t, sort, page.Sort, param)
+		assert.Equal(t, int64(42), page.Cursor.ID, param)
+
+		// the order of the cursor is used without the sort parameter.
+		page, err = newSharedLinkPage(1, 10, "", cursor)
+		require.NoError(t, err, param)
+		assert.Equal(t, sort, page.Sort, param)
+	}
+
+	sizeCursor := newSharedLinkCursor(&sharedLinkSort{Key: "size"}, last)
+	assert.Equal(t, int64(1<<30), sizeCursor.Value)
+	timeCursor := newSharedLinkCursor(&sharedLinkSort{Key: "created_at", Desc: true}, last)
+	assert.Equal(t, now.UnixNano(), timeCursor.Value)
+
+	page, err := newSharedLinkPage(1, math.MaxInt, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, maxSharedLinkPageLimit, page.Limit, "the limit is clamped")
+
+	_, err = newSharedLinkPage(1, 10, "-size", sizeCursor.String())
+	assert.Error(t, err, "the cursor is in another order")
+	for _, invalid := range []string{"!", "e30", "bm90IGpzb24"} {
+		_, err = newSharedLinkPage(1, 10, "", invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSharedLinkSortAfter(t *testing.T) {
+	useDryRunSharedLink(t)
+
+	sql := func(param string, c *sharedLinkCursor) string {
+		sort, err := parseSharedLinkSort(param)
+		require.NoError(t, err)
+		stmt := query.SharedLink.WithContext(context.Background()).Where(sort.after(c)).Order(sort.orders()...).UnderlyingDB().Find(&[]*model.SharedLink{}).Statement
+		return stmt.SQL.String()
+	}
+
+	assert.Contains(t, sql("", &sharedLinkCursor{ID: 42}), "WHERE `keepshare_shared_link`.`auto_id` < ? ORDER BY `keepshare_shared_link`.`auto_id` DESC")
+	assert.Contains(t, sql("-size", &sharedLinkCursor{Sort: "size:desc", Value: 10, ID: 42}),
+		"WHERE (`keepshare_shared_link`.`size` < ? OR (`keepshare_shared_link`.`size` = ? AND `keepshare_shared_link`.`auto_id` < ?)) "+
+			"ORDER BY `keepshare_shared_link`.`size` DESC,`keepshare_shared_link`.`auto_id` DESC")
+	assert.Contains(t, sql("visitor", &sharedLinkCursor{Sort: "visitor:asc", Value: 10, ID: 42}),
+		"WHERE (`keepshare_shared_link`.`visitor` > ? OR (`keepshare_shared_link`.`visitor` = ? AND `keepshare_shared_link`.`auto_id` > ?)) "+
+			"ORDER BY `keepshare_shared_link`.`visitor`,`keepshare_shared_link`.`auto_id`")
+}
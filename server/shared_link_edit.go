@@ -0,0 +1,253 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxCustomTitleLength = 256
+	maxNoteLength        = 1024
+)
+
+// noExpiry is the default of expires_at, shared links expire only if expires_at is after it.
+var noExpiry = time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+
+var errSharedLinkExpired = errors.New("shared link expired")
+
+// userOwnedSharedLinkFields returns the fields of shared links edited by users only, see patchSharedLink.
+// Background updaters omit them, so that the edits of users are never overwritten,
+// and the version is not increased by them.
+func userOwnedSharedLinkFields() []field.Expr {
+	t := query.SharedLink
+	return []field.Expr{t.CustomTitle, t.Note, t.Pinned, t.ExpiresAt, t.Version}
+}
+
+// updateSharedLinks updates shared links with the non-zero fields of the update except user-owned ones,
+// it is used by background updaters such as hosts and statistics.
+func updateSharedLinks(ctx context.Context, update *model.SharedLink, conditions ...gen.Condition) (gen.ResultInfo, error) {
+	t := query.SharedLink
+	return t.WithContext(ctx).Omit(userOwnedSharedLinkFields()...).Where(conditions...).Updates(update)
+}
+
+// sharedLinkExpired reports whether the expiry set by the user has passed.
+func sharedLinkExpired(s *model.SharedLink, now time.Time) bool {
+	return s.ExpiresAt.After(noExpiry) && !now.Before(s.ExpiresAt)
+}
+
+// sharedLinkTitle returns the title set by the user, or the title of the host.
+func sharedLinkTitle(s *model.SharedLink) string {
+	if s.CustomTitle != "" {
+		return s.CustomTitle
+	}
+	return s.Title
+}
+
+// parseExpiry parses the expiry of shared links in the layouts of the search DSL, the empty string means never.
+func parseExpiry(s string, loc *time.Location) (time.Time, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return noExpiry, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			if !t.After(noExpiry) {
+				return time.Time{}, fmt.Errorf("the expiry %q is too early", s)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q, e.g. \"2006-01-02 15:04:05\"", s)
+}
+
+// patchSharedLinkRequest has the user-owned fields of shared links, nil fields are unchanged.
+type patchSharedLinkRequest struct {
+	// Version is the version of the shared link read by the client,
+	// the shared link is not updated if it has been edited since then.
+	Version *int64    `json:"version"`
+	Title   *string   `json:"title"`
+	Note    *string   `json:"note"`
+	Tags    *[]string `json:"tags"`
+	Pinned  *bool     `json:"pinned"`
+	// ExpiresAt is in the layouts of the search DSL and the time zone of the user, the empty string removes the expiry.
+	ExpiresAt *string `json:"expires_at"`
+}
+
+// assignments checks the request and returns the assignments of the fields to update.
+func (r *patchSharedLinkRequest) assignments(loc *time.Location) ([]field.AssignExpr, error) {
+	if r.Version == nil {
+		return nil, errors.New("version is required")
+	}
+
+	t := query.SharedLink
+	var ret []field.AssignExpr
+	if r.Title != nil {
+		title := strings.TrimSpace(*r.Title)
+		if utf8.RuneCountInString(title) > maxCustomTitleLength {
+			return nil, fmt.Errorf("the title is longer than %d characters", maxCustomTitleLength)
+		}
+		ret = append(ret, t.CustomTitle.Value(title))
+	}
+	if r.Note != nil {
+		if utf8.RuneCountInString(*r.Note) > maxNoteLength {
+			return nil, fmt.Errorf("the note is longer than %d characters", maxNoteLength)
+		}
+		ret = append(ret, t.Note.Value(*r.Note))
+	}
+	if r.Pinned != nil {
+		ret = append(ret, t.Pinned.Value(int32(lo.Ternary(*r.Pinned, 1, 0))))
+	}
+	if r.ExpiresAt != nil {
+		expiry, err := parseExpiry(*r.ExpiresAt, loc)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, t.ExpiresAt.Value(expiry))
+	}
+	if r.Tags != nil {
+		names, err := parseTagNames(*r.Tags)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if _, err := checkTagName(name); err != nil {
+				return nil, err
+			}
+		}
+		*r.Tags = names
+	}
+	if len(ret) == 0 && r.Tags == nil {
+		return nil, errors.New("nothing to update")
+	}
+	return ret, nil
+}
+
+// patchSharedLink edits the user-owned fields of a shared link of the current user.
+// It is conditional on the version, the current shared link is responded with 409 if it has been edited.
+// The version is increased by each edit, and is not changed by background updaters, see updateSharedLinks.
+func patchSharedLink(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "invalid id")))
+		return
+	}
+	var req patchSharedLinkRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	assignments, err := req.assignments(userLocation(user))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	// the fields and the tags are edited together, so that the version is not increased by a half applied edit.
+	var conflicted bool
+	err = query.Q.Transaction(func(tx *query.Query) error {
+		t := &tx.SharedLink
+		ret, err := t.WithContext(ctx).
+			Where(t.AutoID.Eq(id), t.UserID.Eq(user.ID), t.Version.Eq(*req.Version)).
+			UpdateSimple(append(assignments, t.Version.Add(1), t.UpdatedAt.Value(time.Now()))...)
+		if err != nil {
+			return err
+		}
+		if ret.RowsAffected == 0 {
+			conflicted = true
+			return nil
+		}
+		if req.Tags != nil {
+			return setSharedLinkTags(ctx, tx, user.ID, id, *req.Tags)
+		}
+		return nil
+	})
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	t := query.SharedLink
+	s, err := t.WithContext(ctx).Where(t.AutoID.Eq(id), t.UserID.Eq(user.ID)).Take()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, mdw.ErrResp(c, "shared_link_not_found", i18n.WithDataMap("id", c.Param("id"))))
+		return
+	}
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	if conflicted {
+		tags, _ := sharedLinkTagNames(ctx, []int64{id})
+		resp := mdw.ErrResp(c, "shared_link_conflict")
+		resp["shared_link"] = s
+		resp["tags"] = tags[id]
+		c.JSON(http.StatusConflict, resp)
+		return
+	}
+
+	tags, err := sharedLinkTagNames(ctx, []int64{id})
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	notifySharedLinksUpdated(ctx, s.OriginalLinkHash)
+
+	c.JSON(http.StatusOK, Map{"shared_link": s, "tags": lo.Ternary(tags[id] == nil, []string{}, tags[id])})
+}
+
+// setSharedLinkTags replaces the tags of the shared link with the names by the query q, missing tags are created.
+func setSharedLinkTags(ctx context.Context, q *query.Query, userID string, id int64, names []string) error {
+	var tagIDs []int64
+	if len(names) > 0 {
+		var err error
+		if tagIDs, err = userTagIDs(ctx, q, userID, names, true); err != nil {
+			return err
+		}
+	}
+
+	lt := &q.SharedLinkTag
+	do := lt.WithContext(ctx).Where(lt.SharedLinkID.Eq(id))
+	if len(tagIDs) > 0 {
+		do = do.Where(lt.TagID.NotIn(tagIDs...))
+	}
+	if _, err := do.Delete(); err != nil {
+		return err
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := lo.Map(tagIDs, func(tagID int64, _ int) *model.SharedLinkTag {
+		return &model.SharedLinkTag{TagID: tagID, SharedLinkID: id, CreatedAt: now}
+	})
+	return lt.WithContext(ctx).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(rows...)
+}
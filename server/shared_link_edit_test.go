@@ -0,0 +1,96 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUpdateSharedLinksOmitsUserOwnedFields(t *testing.T) {
+	db := useDryRunSharedLink(t)
+	var sql string
+	err := db.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	})
+	require.NoError(t, err)
+
+	// a background updater which sets user-owned fields by mistake.
+	update := &model.SharedLink{
+		State:       "OK",
+		Title:       "name on host",
+		CustomTitle: "mine",
+		Note:        "note",
+		Pinned:      1,
+		ExpiresAt:   time.Now(),
+		Version:     2,
+	}
+	_, err = updateSharedLinks(context.Background(), update, query.SharedLink.AutoID.Eq(1))
+	require.NoError(t, err)
+	assert.Contains(t, sql, "`state`=?")
+	assert.Contains(t, sql, "`title`=?")
+	for _, col := range []string{"custom_title", "note", "pinned", "expires_at", "version"} {
+		assert.NotContains(t, sql, "`"+col+"`", sql)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	expiry, err := parseExpiry("", loc)
+	require.NoError(t, err)
+	assert.Equal(t, noExpiry, expiry)
+
+	expiry, err = parseExpiry(" 2030-01-02 03:04 ", loc)
+	require.NoError(t, err)
+	assert.True(t, expiry.Equal(time.Date(2030, 1, 2, 3, 4, 0, 0, loc)))
+
+	for _, s := range []string{"tomorrow", "1999-12-31", "+7d"} {
+		_, err := parseExpiry(s, loc)
+		assert.Error(t, err, s)
+	}
+
+	now := time.Date(2030, 1, 2, 3, 4, 0, 0, loc)
+	assert.False(t, sharedLinkExpired(&model.SharedLink{}, now))
+	assert.False(t, sharedLinkExpired(&model.SharedLink{ExpiresAt: noExpiry}, now))
+	assert.False(t, sharedLinkExpired(&model.SharedLink{ExpiresAt: now.Add(time.Second)}, now))
+	assert.True(t, sharedLinkExpired(&model.SharedLink{ExpiresAt: now}, now))
+
+	assert.Equal(t, "host", sharedLinkTitle(&model.SharedLink{Title: "host"}))
+	assert.Equal(t, "mine", sharedLinkTitle(&model.SharedLink{Title: "host", CustomTitle: "mine"}))
+}
+
+func TestPatchSharedLinkRequest(t *testing.T) {
+	useDryRunSharedLink(t)
+
+	ptr := func(s string) *string { return &s }
+	var version int64
+	tags := []string{" a ", "b,a"}
+	pinned := true
+	req := &patchSharedLinkRequest{Version: &version, Title: ptr(" mine "), Pinned: &pinned, Tags: &tags}
+	assignments, err := req.assignments(time.UTC)
+	require.NoError(t, err)
+	assert.Len(t, assignments, 2)
+	assert.Equal(t, []string{"a", "b"}, *req.Tags)
+
+	errs := []*patchSharedLinkRequest{
+		{Title: ptr("mine")},
+		{Version: &version},
+		{Version: &version, Title: ptr(strings.Repeat("x", maxCustomTitleLength+1))},
+		{Version: &version, Note: ptr(strings.Repeat("x", maxNoteLength+1))},
+		{Version: &version, ExpiresAt: ptr("never")},
+	}
+	for _, req := range errs {
+		_, err := req.assignments(time.UTC)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/config"
+	"github.com/KeepShareOrg/keepshare/hosts"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// eventsRedis publishes changes of shared links to the streams of all replicas, nothing is published if it is nil.
+var eventsRedis *redis.Client
+
+const (
+	// sharedLinkEventsPrefix is the prefix of redis channels, followed by the original link hash.
+	sharedLinkEventsPrefix = "shared_link_events:"
+
+	sharedLinkEventState    = "state"
+	sharedLinkEventProgress = "progress"
+
+	sharedLinkEventsHeartbeat = 15 * time.Second
+	// sharedLinkEventsFallback is the interval to reload shared links in case of lost events.
+	sharedLinkEventsFallback = 30 * time.Second
+	// sharedLinkEventsBuffer is the number of events buffered for a stream, more events are dropped.
+	sharedLinkEventsBuffer = 8
+)
+
+// sharedLinkEvent is published to the channel of an original link.
+// Subscribers reload the shared link on state events, progress events are forwarded to clients directly.
+type sharedLinkEvent struct {
+	Type     string `json:"type"`
+	Progress int    `json:"progress,omitempty"`
+}
+
+func publishSharedLinkEvent(ctx context.Context, originalLinkHash string, e *sharedLinkEvent) {
+	if eventsRedis == nil || originalLinkHash == "" {
+		return
+	}
+	b, _ := json.Marshal(e)
+	if err := eventsRedis.Publish(ctx, sharedLinkEventsPrefix+originalLinkHash, b).Err(); err != nil {
+		log.WithContext(ctx).WithField("original_link_hash", originalLinkHash).Errorf("publish shared link event err: %v", err)
+	}
+}
+
+// notifySharedLinksUpdated notifies streams that shared links of the original links are updated.
+func notifySharedLinksUpdated(ctx context.Context, originalLinkHashes ...string) {
+	for _, h := range originalLinkHashes {
+		publishSharedLinkEvent(ctx, h, &sharedLinkEvent{Type: sharedLinkEventState})
+	}
+}
+
+// sharedLinkHub fans out the events of shared links to the local streams.
+// A replica receives the events of all shared links by one pattern subscription, see run.
+type sharedLinkHub struct {
+	mu sync.Mutex
+	// streams are keyed by original link hashes.
+	streams map[string]map[chan *sharedLinkEvent]struct{}
+}
+
+var sharedLinkStreams = &sharedLinkHub{streams: make(map[string]map[chan *sharedLinkEvent]struct{})}
+
+// subscribe returns the events of the original link, cancel must be called when the stream ends.
+func (h *sharedLinkHub) subscribe(originalLinkHash string) (events <-chan *sharedLinkEvent, cancel func()) {
+	ch := make(chan *sharedLinkEvent, sharedLinkEventsBuffer)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.streams[originalLinkHash] == nil {
+		h.streams[originalLinkHash] = make(map[chan *sharedLinkEvent]struct{})
+	}
+	h.streams[originalLinkHash][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.streams[originalLinkHash], ch)
+		if len(h.streams[originalLinkHash]) == 0 {
+			delete(h.streams, originalLinkHash)
+		}
+	}
+}
+
+// dispatch sends the event to the streams of the original link without blocking,
+// the events are dropped for slow streams, which reload shared links periodically.
+func (h *sharedLinkHub) dispatch(originalLinkHash string, e *sharedLinkEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.streams[originalLinkHash] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// run receives the events published by all replicas, the subscription is reconnected by the client automatically.
+func (h *sharedLinkHub) run(ctx context.Context, rdb *redis.Client) {
+	sub := rdb.PSubscribe(ctx, sharedLinkEventsPrefix+"*")
+	defer sub.Close()
+	for msg := range sub.Channel() {
+		e := new(sharedLinkEvent)
+		if err := json.Unmarshal([]byte(msg.Payload), e); err != nil {
+			log.WithContext(ctx).Errorf("unmarshal shared link event err: %v", err)
+			continue
+		}
+		h.dispatch(strings.TrimPrefix(msg.Channel, sharedLinkEventsPrefix), e)
+	}
+}
+
+// listenFileEvents forwards download progresses of hosts to streams,
+// shared links are updated by the subscribers of FileComplete events which notify streams later.
+func listenFileEvents(b *hosts.EventBus) {
+	hosts.Subscribe(b, func(ctx context.Context, e hosts.FileProgressEvent) error {
+		publishSharedLinkEvent(ctx, e.OriginalLinkHash, &sharedLinkEvent{Type: sharedLinkEventProgress, Progress: e.Progress})
+		return nil
+	})
+	hosts.Subscribe(b, func(ctx context.Context, e hosts.FileCompleteEvent) error {
+		publishSharedLinkEvent(ctx, e.OriginalLinkHash, &sharedLinkEvent{Type: sharedLinkEventProgress, Progress: 100})
+		return nil
+	})
+	hosts.Subscribe(b, func(ctx context.Context, e hosts.FileErrorEvent) error {
+		notifySharedLinksUpdated(ctx, e.OriginalLinkHash)
+		return nil
+	})
+}
+
+// isFinalState reports whether the state of shared links will not change without actions of users.
+func isFinalState(s share.State) bool {
+	switch s {
+	case share.StatusPending, share.StatusCreated, share.StatusUnknown:
+		return false
+	}
+	return true
+}
+
+// sharedLinkState is the data of state events.
+type sharedLinkState struct {
+	ID           int64  `json:"id"`
+	State        string `json:"state"`
+	OriginalLink string `json:"original_link,omitempty"`
+	HostLink     string `json:"host_link,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func newSharedLinkState(s *model.SharedLink, setting *model.RedirectSetting) *sharedLinkState {
+	st := &sharedLinkState{ID: s.AutoID, State: s.State, OriginalLink: s.OriginalLink, Title: sharedLinkTitle(s), Size: s.Size, Error: s.Error}
+	if s.State == share.StatusOK.String() {
+		st.HostLink = hostLinkWithParams(setting, s.Host, s.HostSharedLink)
+	}
+	return st
+}
+
+// sharedLinkEvents streams changes of the shared link by Server-Sent Events, it replaces polling querySharedLinkInfo.
+// Events: `state` with sharedLinkState, `progress` with the download percentage.
+// The stream ends after a final state or a timeout, clients reconnect automatically if the state is not final.
+func sharedLinkEvents(c *gin.Context) {
+	autoID, err := strconv.ParseInt(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "invalid id")))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.SharedLink
+	load := func() (*model.SharedLink, error) {
+		return t.WithContext(ctx).Where(t.AutoID.Eq(autoID)).Take()
+	}
+	rec, err := load()
+	if gormutil.IsNotFoundError(err) {
+		c.JSON(http.StatusNotFound, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "shared link not found")))
+		return
+	}
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	report := log.NewReport("shared_link_events")
+	reqID, start := log.RequestIDFromContext(ctx)
+	report.Sets(Map{
+		constant.IP:        c.ClientIP(),
+		constant.DeviceID:  c.GetHeader(constant.HeaderDeviceID),
+		constant.RequestID: reqID,
+		constant.UserID:    rec.UserID,
+		constant.Link:      rec.OriginalLink,
+		constant.Host:      rec.Host,
+	})
+	events := 0
+	defer func() {
+		report.Sets(Map{keyState: rec.State, "events": events, keyTotalMS: time.Since(start).Milliseconds()}).Done()
+	}()
+
+	// reload after subscribed, so that no changes are missed.
+	messages, cancel := sharedLinkStreams.subscribe(rec.OriginalLinkHash)
+	defer cancel()
+	if rec, err = load(); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	send := func(name string, data any) {
+		events++
+		c.SSEvent(name, data)
+		c.Writer.Flush()
+	}
+	setting := userRedirectSetting(ctx, rec.UserID)
+	send(sharedLinkEventState, newSharedLinkState(rec, setting))
+	if isFinalState(share.State(rec.State)) {
+		return
+	}
+
+	// reload sends the state if it is changed, and returns false if the stream should end.
+	reload := func() bool {
+		s, err := load()
+		if err != nil {
+			log.WithContext(ctx).Errorf("reload shared link err: %v", err)
+			return !gormutil.IsNotFoundError(err)
+		}
+		if s.State != rec.State || s.HostSharedLink != rec.HostSharedLink {
+			send(sharedLinkEventState, newSharedLinkState(s, setting))
+		}
+		rec = s
+		return !isFinalState(share.State(s.State))
+	}
+
+	timeout := time.NewTimer(config.SharedLinkEventsTimeout())
+	defer timeout.Stop()
+	heartbeat := time.NewTicker(sharedLinkEventsHeartbeat)
+	defer heartbeat.Stop()
+	fallback := time.NewTicker(sharedLinkEventsFallback)
+	defer fallback.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout.C:
+			return
+		case <-heartbeat.C:
+			_, _ = io.WriteString(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case <-fallback.C:
+			if !reload() {
+				return
+			}
+		case e := <-messages:
+			if e.Type == sharedLinkEventProgress {
+				send(sharedLinkEventProgress, Map{"id": rec.AutoID, "progress": e.Progress})
+				continue
+			}
+			if !reload() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/KeepShareOrg/keepshare/hosts/hoststest"
+	"github.com/KeepShareOrg/keepshare/pkg/share"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedLinkState(t *testing.T) {
+	for _, s := range []share.State{share.StatusPending, share.StatusCreated, share.StatusUnknown} {
+		assert.False(t, isFinalState(s), s)
+	}
+	for _, s := range []share.State{share.StatusOK, share.StatusError, share.StatusSensitive, share.StatusBlocked, share.StatusDeleted} {
+		assert.True(t, isFinalState(s), s)
+	}
+
+	fake := hoststest.NewFake()
+	fake.Caps.RedirectParams = []string{"act"}
+	hoststest.Register(t, "eventsfake", fake)
+	setting := defaultRedirectSetting("user")
+
+	created := newSharedLinkState(&model.SharedLink{AutoID: 1, State: share.StatusCreated.String(), Host: "eventsfake", HostSharedLink: "https://host/s/1"}, setting)
+	assert.Equal(t, &sharedLinkState{ID: 1, State: "CREATED"}, created, "host links are sent when OK only")
+
+	ok := newSharedLinkState(&model.SharedLink{AutoID: 1, State: share.StatusOK.String(), Host: "eventsfake", HostSharedLink: "https://host/s/1", Title: "a", Size: 10}, setting)
+	assert.Equal(t, &sharedLinkState{ID: 1, State: "OK", HostLink: "https://host/s/1?act=play", Title: "a", Size: 10}, ok)
+
+	renamed := newSharedLinkState(&model.SharedLink{AutoID: 1, State: share.StatusCreated.String(), Title: "a", CustomTitle: "mine"}, setting)
+	assert.Equal(t, "mine", renamed.Title, "the title set by the user is sent")
+}
+
+func TestSharedLinkHub(t *testing.T) {
+	h := &sharedLinkHub{streams: make(map[string]map[chan *sharedLinkEvent]struct{})}
+	a, cancelA := h.subscribe("hash")
+	b, cancelB := h.subscribe("hash")
+	other, cancelOther := h.subscribe("other")
+	defer cancelOther()
+
+	e := &sharedLinkEvent{Type: sharedLinkEventProgress, Progress: 10}
+	h.dispatch("hash", e)
+	assert.Equal(t, e, <-a)
+	assert.Equal(t, e, <-b)
+	assert.Empty(t, other)
+
+	// events of slow streams are dropped without blocking.
+	for i := 0; i < sharedLinkEventsBuffer+1; i++ {
+		h.dispatch("hash", e)
+	}
+	assert.Len(t, a, sharedLinkEventsBuffer)
+
+	cancelA()
+	cancelB()
+	assert.NotContains(t, h.streams, "hash")
+	assert.Contains(t, h.streams, "other")
+}
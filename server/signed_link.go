@@ -0,0 +1,220 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+)
+
+// query parameters of signed auto sharing links, they are reserved names to not collide with parameters of original links.
+const (
+	signatureParam = "ks_sig"
+	expiresParam   = "ks_exp"
+)
+
+// Errors of verifying signed auto sharing links.
+var (
+	errSignatureMissing = errors.New("signature is missing")
+	errSignatureInvalid = errors.New("signature is invalid")
+	errSignatureExpired = errors.New("signature is expired")
+)
+
+// linkSignature signs the channel, the hash of the link and the expiration in unix seconds, 0 means never expire.
+// The hash of the link is signed, so that the signature is valid for all equivalent forms of the link.
+func linkSignature(secret, channel, linkHash string, expires int64) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(m, "%s\n%s\n%d", channel, linkHash, expires)
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil)[:16])
+}
+
+// makeSignedKeepSharingLink returns the auto sharing link with the signature, and the expiration if it is not zero.
+func makeSignedKeepSharingLink(channel, secret, originalLink string, expires time.Time) string {
+	var exp int64
+	q := url.Values{}
+	if !expires.IsZero() {
+		exp = expires.Unix()
+		q.Set(expiresParam, strconv.FormatInt(exp, 10))
+	}
+	q.Set(signatureParam, linkSignature(secret, channel, lk.Hash(originalLink), exp))
+	return makeKeepSharingLink(channel, originalLink) + "?" + q.Encode()
+}
+
+// verifyLinkSignature verifies the signature and the expiration in the query of auto sharing links.
+func verifyLinkSignature(user *model.User, linkHash string, q url.Values, now time.Time) error {
+	sig := q.Get(signatureParam)
+	if sig == "" {
+		return errSignatureMissing
+	}
+	if user.LinkSecret == "" {
+		return errSignatureInvalid
+	}
+
+	var exp int64
+	if v := q.Get(expiresParam); v != "" {
+		var err error
+		if exp, err = strconv.ParseInt(v, 10, 64); err != nil || exp <= 0 {
+			return errSignatureInvalid
+		}
+	}
+	if !hmac.Equal([]byte(sig), []byte(linkSignature(user.LinkSecret, user.Channel, linkHash, exp))) {
+		return errSignatureInvalid
+	}
+	if exp > 0 && now.Unix() > exp {
+		return errSignatureExpired
+	}
+	return nil
+}
+
+func newLinkSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func getSignedMode(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"enabled": user.SignedMode == 1})
+}
+
+// setSignedMode switches on or off the signed mode of the channel,
+// links signed before are invalid after rotating the secret.
+func setSignedMode(c *gin.Context) {
+	var req struct {
+		Enabled      bool `json:"enabled"`
+		RotateSecret bool `json:"rotate_secret"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.User
+	user, err := t.WithContext(ctx).Where(t.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	user.SignedMode = 0
+	if req.Enabled {
+		user.SignedMode = 1
+	}
+	if user.LinkSecret == "" || req.RotateSecret {
+		if user.LinkSecret, err = newLinkSecret(); err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+	}
+	_, err = t.WithContext(ctx).Where(t.ID.Eq(user.ID)).UpdateSimple(t.SignedMode.Value(user.SignedMode), t.LinkSecret.Value(user.LinkSecret))
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"enabled": req.Enabled})
+}
+
+const maxSignLinks = 1000
+
+// signLinks returns signed auto sharing links of the original links, the secret is created if it does not exist,
+// so that links can be signed before switching on the signed mode.
+func signLinks(c *gin.Context) {
+	var req struct {
+		Links []string `json:"links"`
+		// ExpiresIn is the duration in seconds before the links expire, 0 means never expire.
+		ExpiresIn int64 `json:"expires_in"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	if len(req.Links) == 0 {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "links_is_empty"))
+		return
+	}
+	if len(req.Links) > maxSignLinks {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "submit_too_many_links", i18n.WithDataMap("count", strconv.Itoa(maxSignLinks))))
+		return
+	}
+	if req.ExpiresIn < 0 {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "expires_in must not be negative")))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.User
+	user, err := t.WithContext(ctx).Where(t.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	if user.LinkSecret == "" {
+		if user.LinkSecret, err = newLinkSecret(); err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+		// do not overwrite the secret created by concurrent requests.
+		info, err := t.WithContext(ctx).Where(t.ID.Eq(user.ID), t.LinkSecret.Eq("")).UpdateSimple(t.LinkSecret.Value(user.LinkSecret))
+		if err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+		if info.RowsAffected == 0 {
+			if user, err = t.WithContext(ctx).Where(t.ID.Eq(user.ID)).Take(); err != nil {
+				mdw.RespInternal(c, err.Error())
+				return
+			}
+		}
+	}
+
+	var expires time.Time
+	if req.ExpiresIn > 0 {
+		expires = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+	}
+
+	links := make(map[string]string, len(req.Links))
+	var invalid []string
+	for _, link := range req.Links {
+		simple, _, ok := validateLink(link)
+		if !ok {
+			invalid = append(invalid, link)
+			continue
+		}
+		links[link] = makeSignedKeepSharingLink(user.Channel, user.LinkSecret, simple, expires)
+	}
+
+	resp := Map{"links": links, "invalid": invalid}
+	if !expires.IsZero() {
+		resp["expires_at"] = expires.Unix()
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,65 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedKeepSharingLink(t *testing.T) {
+	user := &model.User{Channel: "abcd1234", LinkSecret: "secret"}
+	link := "magnet:?xt=urn:btih:3e5f0ef1240ff8906281955666e73d7f8a14e42b"
+	hash := "3e5f0ef1240ff8906281955666e73d7f8a14e42b"
+	now := time.Now()
+
+	query := func(signed string) url.Values {
+		assert.True(t, strings.HasPrefix(signed, makeKeepSharingLink(user.Channel, link)+"?"), "compatible with unsigned links")
+		u, err := url.Parse(signed)
+		require.NoError(t, err)
+		return u.Query()
+	}
+
+	never := query(makeSignedKeepSharingLink(user.Channel, user.LinkSecret, link, time.Time{}))
+	assert.Empty(t, never.Get(expiresParam))
+	assert.NoError(t, verifyLinkSignature(user, hash, never, now))
+	assert.NoError(t, verifyLinkSignature(user, hash, never, now.AddDate(10, 0, 0)))
+
+	expiring := query(makeSignedKeepSharingLink(user.Channel, user.LinkSecret, link, now.Add(time.Hour)))
+	assert.NoError(t, verifyLinkSignature(user, hash, expiring, now))
+	assert.ErrorIs(t, verifyLinkSignature(user, hash, expiring, now.Add(2*time.Hour)), errSignatureExpired)
+
+	// the expiration can not be changed or removed.
+	extended := url.Values{signatureParam: expiring[signatureParam], expiresParam: {"9999999999"}}
+	assert.ErrorIs(t, verifyLinkSignature(user, hash, extended, now), errSignatureInvalid)
+	removed := url.Values{signatureParam: expiring[signatureParam]}
+	assert.ErrorIs(t, verifyLinkSignature(user, hash, removed, now), errSignatureInvalid)
+
+	assert.ErrorIs(t, verifyLinkSignature(user, hash, url.Values{}, now), errSignatureMissing)
+	assert.ErrorIs(t, verifyLinkSignature(user, "other", never, now), errSignatureInvalid)
+	assert.ErrorIs(t, verifyLinkSignature(&model.User{Channel: "other123", LinkSecret: "secret"}, hash, never, now), errSignatureInvalid)
+	assert.ErrorIs(t, verifyLinkSignature(&model.User{Channel: user.Channel, LinkSecret: "rotated"}, hash, never, now), errSignatureInvalid)
+	assert.ErrorIs(t, verifyLinkSignature(&model.User{Channel: user.Channel}, hash, never, now), errSignatureInvalid)
+}
+
+func TestSignedKeepSharingLinkWithParams(t *testing.T) {
+	user := &model.User{Channel: "abcd1234", LinkSecret: "secret"}
+	link := "https://example.com/file.zip?sig=abc&exp=1"
+
+	u, err := url.Parse(makeSignedKeepSharingLink(user.Channel, user.LinkSecret, link, time.Time{}))
+	require.NoError(t, err)
+	channel, got, ok := getChannelAndLinkFromURL(u)
+	require.True(t, ok)
+	assert.Equal(t, user.Channel, channel)
+	assert.Equal(t, link, got, "parameters of the original link are kept")
+	assert.NoError(t, verifyLinkSignature(user, lk.Hash(got), u.Query(), time.Now()))
+}
@@ -0,0 +1,425 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxTagNameLength = 64
+	// maxTagsPerRequest is the maximum number of tags to filter, tag or untag shared links at a time.
+	maxTagsPerRequest = 20
+	// tagBatchSize is the number of shared links tagged or untagged at a time.
+	tagBatchSize = 1000
+)
+
+// parseTagNames trims and dedupes the tag names, names separated by comma are split.
+func parseTagNames(names []string) ([]string, error) {
+	var ret []string
+	for _, s := range names {
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name == "" || lo.Contains(ret, name) {
+				continue
+			}
+			if utf8.RuneCountInString(name) > maxTagNameLength {
+				return nil, fmt.Errorf("the tag %q is longer than %d characters", name, maxTagNameLength)
+			}
+			ret = append(ret, name)
+		}
+	}
+	if len(ret) > maxTagsPerRequest {
+		return nil, fmt.Errorf("up to %d tags are allowed", maxTagsPerRequest)
+	}
+	return ret, nil
+}
+
+// checkTagName checks the name of a tag to create or rename.
+func checkTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", errors.New("the tag name is empty")
+	case strings.Contains(name, ","):
+		return "", errors.New("the tag name can not contain commas")
+	case utf8.RuneCountInString(name) > maxTagNameLength:
+		return "", fmt.Errorf("the tag name is longer than %d characters", maxTagNameLength)
+	}
+	return name, nil
+}
+
+// taggedSharedLinkIDs returns the sub query of ids of shared links with any of the tags of the user.
+func taggedSharedLinkIDs(userID string, names ...string) *gorm.DB {
+	tg, lt := query.Tag, query.SharedLinkTag
+	tags := tg.WithContext(context.Background()).Select(tg.ID).Where(tg.UserID.Eq(userID), tg.Name.In(names...))
+	return lt.WithContext(context.Background()).
+		Select(lt.SharedLinkID).
+		Where(field.ContainsSubQuery([]field.Expr{lt.TagID}, tags.UnderlyingDB())).
+		UnderlyingDB()
+}
+
+// taggedWith returns the condition of shared links with any of the tags of the user.
+func taggedWith(userID string, names ...string) field.Expr {
+	return field.ContainsSubQuery([]field.Expr{query.SharedLink.AutoID}, taggedSharedLinkIDs(userID, names...))
+}
+
+// sharedLinkTagNames returns the tag names of the shared links by their ids.
+func sharedLinkTagNames(ctx context.Context, ids []int64) (map[int64][]string, error) {
+	ret := make(map[int64][]string)
+	if len(ids) == 0 {
+		return ret, nil
+	}
+	tg, lt := query.Tag, query.SharedLinkTag
+	var rows []struct {
+		SharedLinkID int64
+		Name         string
+	}
+	err := lt.WithContext(ctx).
+		Select(lt.SharedLinkID, tg.Name).
+		Join(tg, tg.ID.EqCol(lt.TagID)).
+		Where(lt.SharedLinkID.In(ids...)).
+		Order(tg.Name).
+		Scan(&rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		ret[r.SharedLinkID] = append(ret[r.SharedLinkID], r.Name)
+	}
+	return ret, nil
+}
+
+// untagSharedLinks removes all tags of the shared links, it is called after shared links are deleted.
+func untagSharedLinks(ctx context.Context, ids []int64) error {
+	lt := query.SharedLinkTag
+	for _, batch := range lo.Chunk(ids, tagBatchSize) {
+		if _, err := lt.WithContext(ctx).Where(lt.SharedLinkID.In(batch...)).Delete(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type tagWithCount struct {
+	*model.Tag
+	// Count is the number of shared links with the tag.
+	Count int64 `json:"count"`
+}
+
+// listTags returns all tags of the current user ordered by name, with the numbers of shared links.
+func listTags(c *gin.Context) {
+	ctx := c.Request.Context()
+	tg, lt := query.Tag, query.SharedLinkTag
+	tags, err := tg.WithContext(ctx).Where(tg.UserID.Eq(c.GetString(constant.UserID))).Order(tg.Name).Find()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	list := make([]*tagWithCount, 0, len(tags))
+	if len(tags) > 0 {
+		var counts []struct {
+			TagID int64
+			Count int64
+		}
+		err := lt.WithContext(ctx).
+			Select(lt.TagID, lt.SharedLinkID.Count().As("count")).
+			Where(lt.TagID.In(lo.Map(tags, func(t *model.Tag, _ int) int64 { return t.ID })...)).
+			Group(lt.TagID).
+			Scan(&counts)
+		if err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+		m := lo.SliceToMap(counts, func(v struct {
+			TagID int64
+			Count int64
+		}) (int64, int64) {
+			return v.TagID, v.Count
+		})
+		for _, t := range tags {
+			list = append(list, &tagWithCount{Tag: t, Count: m[t.ID]})
+		}
+	}
+
+	c.JSON(http.StatusOK, Map{"list": list})
+}
+
+type tagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func (r *tagRequest) check() error {
+	var err error
+	if r.Name, err = checkTagName(r.Name); err != nil {
+		return err
+	}
+	if r.Color = strings.TrimSpace(r.Color); len(r.Color) > 16 {
+		return errors.New("the color is longer than 16 characters")
+	}
+	return nil
+}
+
+func createTag(c *gin.Context) {
+	var req tagRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetString(constant.UserID)
+	tg := query.Tag
+	if n, err := tg.WithContext(ctx).Where(tg.UserID.Eq(userID), tg.Name.Eq(req.Name)).Count(); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	} else if n > 0 {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "tag_exists", i18n.WithDataMap("name", req.Name)))
+		return
+	}
+
+	tag := &model.Tag{UserID: userID, Name: req.Name, Color: req.Color, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := tg.WithContext(ctx).Create(tag); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// userTag returns the tag of the id parameter of the current user, it responds the error and returns nil if not found.
+func userTag(c *gin.Context) *model.Tag {
+	id, _ := strconv.ParseInt(c.Param("id"), 10, 64)
+	tg := query.Tag
+	tag, err := tg.WithContext(c.Request.Context()).Where(tg.ID.Eq(id), tg.UserID.Eq(c.GetString(constant.UserID))).Take()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, mdw.ErrResp(c, "tag_not_found", i18n.WithDataMap("id", c.Param("id"))))
+		return nil
+	}
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return nil
+	}
+	return tag
+}
+
+// updateTag renames the tag or changes its color, shared links keep the tag.
+func updateTag(c *gin.Context) {
+	var req tagRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	if err := req.check(); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	tag := userTag(c)
+	if tag == nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tg := query.Tag
+	if req.Name != tag.Name {
+		n, err := tg.WithContext(ctx).Where(tg.UserID.Eq(tag.UserID), tg.Name.Eq(req.Name)).Count()
+		if err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+		if n > 0 {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "tag_exists", i18n.WithDataMap("name", req.Name)))
+			return
+		}
+	}
+
+	tag.Name, tag.Color, tag.UpdatedAt = req.Name, req.Color, time.Now()
+	_, err := tg.WithContext(ctx).Where(tg.ID.Eq(tag.ID)).UpdateSimple(tg.Name.Value(tag.Name), tg.Color.Value(tag.Color), tg.UpdatedAt.Value(tag.UpdatedAt))
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// deleteTag deletes the tag and removes it from shared links, the shared links are not deleted.
+func deleteTag(c *gin.Context) {
+	tag := userTag(c)
+	if tag == nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tg, lt := query.Tag, query.SharedLinkTag
+	if _, err := lt.WithContext(ctx).Where(lt.TagID.Eq(tag.ID)).Delete(); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+	if _, err := tg.WithContext(ctx).Where(tg.ID.Eq(tag.ID)).Delete(); err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"message": "ok"})
+}
+
+// userTagIDs returns the ids of the tags of the user by names, missing tags are created if create is true.
+func userTagIDs(ctx context.Context, q *query.Query, userID string, names []string, create bool) ([]int64, error) {
+	tg := &q.Tag
+	if create {
+		now := time.Now()
+		tags := lo.Map(names, func(name string, _ int) *model.Tag {
+			return &model.Tag{UserID: userID, Name: name, CreatedAt: now, UpdatedAt: now}
+		})
+		if err := tg.WithContext(ctx).Clauses(clause.Insert{Modifier: "IGNORE"}).Create(tags...); err != nil {
+			return nil, err
+		}
+	}
+	var ids []int64
+	err := tg.WithContext(ctx).Where(tg.UserID.Eq(userID), tg.Name.In(names...)).Pluck(tg.ID, &ids)
+	return ids, err
+}
+
+// bulkTagSharedLinks returns the handler to tag or untag the shared links matching the search DSL or ids.
+// Missing tags are created when tagging. Shared links are handled in batches by auto_id,
+// so any number of them can be tagged at a time.
+func bulkTagSharedLinks(untag bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Tags   []string `json:"tags"`
+			Search string   `json:"search"`
+			IDs    []int64  `json:"ids"`
+		}
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+			return
+		}
+		names, err := parseTagNames(req.Tags)
+		if err == nil && len(names) == 0 {
+			err = errors.New("tags are required")
+		}
+		if err == nil && !untag {
+			for _, name := range names {
+				if _, err = checkTagName(name); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+			return
+		}
+		req.Search = strings.TrimSpace(req.Search)
+		if req.Search == "" && len(req.IDs) == 0 {
+			c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", "one of search or ids is required")))
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+		if err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+		t := query.SharedLink
+		conditions := []gen.Condition{t.UserID.Eq(user.ID)}
+		if len(req.IDs) > 0 {
+			conditions = append(conditions, t.AutoID.In(req.IDs...))
+		}
+		if req.Search != "" {
+			cond, err := compileQueryDSL(req.Search, user.ID, userLocation(user))
+			if err != nil {
+				respDSLError(c, err)
+				return
+			}
+			conditions = append(conditions, cond)
+		}
+
+		tagIDs, err := userTagIDs(ctx, query.Q, user.ID, names, !untag)
+		if err != nil {
+			mdw.RespInternal(c, err.Error())
+			return
+		}
+
+		var resp struct {
+			Matched      int64 `json:"matched"`
+			RowsAffected int64 `json:"rows_affected"`
+		}
+		if len(tagIDs) == 0 {
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+
+		lt := query.SharedLinkTag
+		var last int64
+		for {
+			var ids []int64
+			err := t.WithContext(ctx).Where(conditions...).Where(t.AutoID.Gt(last)).Order(t.AutoID).Limit(tagBatchSize).Pluck(t.AutoID, &ids)
+			if err != nil {
+				mdw.RespInternal(c, err.Error())
+				return
+			}
+			if len(ids) == 0 {
+				break
+			}
+			resp.Matched += int64(len(ids))
+			last = ids[len(ids)-1]
+
+			if untag {
+				ret, err := lt.WithContext(ctx).Where(lt.TagID.In(tagIDs...), lt.SharedLinkID.In(ids...)).Delete()
+				if err != nil {
+					mdw.RespInternal(c, err.Error())
+					return
+				}
+				resp.RowsAffected += ret.RowsAffected
+			} else {
+				now := time.Now()
+				rows := make([]*model.SharedLinkTag, 0, len(ids)*len(tagIDs))
+				for _, tagID := range tagIDs {
+					for _, id := range ids {
+						rows = append(rows, &model.SharedLinkTag{TagID: tagID, SharedLinkID: id, CreatedAt: now})
+					}
+				}
+				db := lt.WithContext(ctx).Clauses(clause.Insert{Modifier: "IGNORE"}).UnderlyingDB().CreateInBatches(rows, tagBatchSize)
+				if db.Error != nil {
+					mdw.RespInternal(c, db.Error.Error())
+					return
+				}
+				resp.RowsAffected += db.RowsAffected
+			}
+
+			if len(ids) < tagBatchSize {
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -26,7 +26,10 @@ const (
 	statisticTaskDelay = 2 * time.Minute
 )
 
-var queue *q.Client
+var (
+	queue  *q.Client
+	events *hosts.EventBus
+)
 
 type getStatisticsMessage struct {
 	RecordID int64 `json:"record"`
@@ -87,7 +90,7 @@ func handleGetStatistics(ctx context.Context, task *asynq.Task) error {
 	}
 
 	now := time.Now()
-	update := &model.SharedLink{AutoID: rec.AutoID}
+	update := &model.SharedLink{}
 	if rec.Stored < stat.Stored {
 		update.LastStoredAt = now
 		update.LastVisitedAt = now
@@ -97,7 +100,7 @@ func handleGetStatistics(ctx context.Context, task *asynq.Task) error {
 	update.Revenue = stat.Revenue
 	update.UpdatedAt = now
 
-	_, err = t.WithContext(ctx).Updates(update)
+	_, err = updateSharedLinks(ctx, update, t.AutoID.Eq(rec.AutoID))
 	if err != nil {
 		log.WithContext(ctx).WithField(constant.Error, err).Error("update statistics error")
 		return err
@@ -6,8 +6,11 @@ import (
 	"github.com/KeepShareOrg/keepshare/config"
 	"github.com/KeepShareOrg/keepshare/hosts"
 	"github.com/KeepShareOrg/keepshare/hosts/pikpak/api"
+	"github.com/KeepShareOrg/keepshare/hosts/pikpak/comm"
 	pm "github.com/KeepShareOrg/keepshare/hosts/pikpak/model"
 	pq "github.com/KeepShareOrg/keepshare/hosts/pikpak/query"
+	"github.com/KeepShareOrg/keepshare/pkg/gormutil"
+	lk "github.com/KeepShareOrg/keepshare/pkg/link"
 	"github.com/KeepShareOrg/keepshare/pkg/log"
 	"github.com/KeepShareOrg/keepshare/pkg/share"
 	"github.com/KeepShareOrg/keepshare/server/constant"
@@ -28,24 +31,75 @@ func NewAsyncTaskRunner() *AsyncTaskRunner {
 
 var createNotExistsHostTasksBuffer = make(chan *model.SharedLink, 100000)
 
-func (r *AsyncTaskRunner) ListenCompleteFiles() {
+// ListenCompleteFiles subscribes the FileComplete events to update shared links,
+// the events are delivered by the queue so that each of them is handled once across replicas.
+func (r *AsyncTaskRunner) ListenCompleteFiles() error {
 	pp := pq.Use(config.MySQL())
-	host := hosts.Get(config.DefaultHost())
-	ctx := context.Background()
-	host.AddEventListener(hosts.FileComplete, func(userID, originalLinkHash string) {
-		log.Debugf("file complete event: %s %s", userID, originalLinkHash)
+	_, err := hosts.SubscribeQueue(events, "update_shared_links", func(ctx context.Context, e hosts.FileCompleteEvent) error {
+		log.Debugf("file complete event: %#v", e)
+		if e.Host != comm.HostName {
+			return r.handleCompleteFile(ctx, e)
+		}
+
+		// the user id of PikPak events is the worker user id.
 		files, err := pp.File.WithContext(ctx).Where(
-			pp.File.WorkerUserID.Eq(userID),
-			pp.File.OriginalLinkHash.Eq(originalLinkHash),
+			pp.File.WorkerUserID.Eq(e.UserID),
+			pp.File.OriginalLinkHash.Eq(e.OriginalLinkHash),
 		).Find()
 		if err != nil {
-			log.Errorf("query files error: %v", err)
-			return
+			return fmt.Errorf("query files error: %w", err)
 		}
 		if err := r.handleCompleteUniqueTasks(ctx, files); err != nil {
-			log.Errorf("handle complete unique tasks error: %v", err)
+			return fmt.Errorf("handle complete unique tasks error: %w", err)
 		}
+		return nil
 	})
+	return err
+}
+
+// handleCompleteFile updates the shared link of the file completed by hosts
+// which publish events with keepshare user id.
+func (r *AsyncTaskRunner) handleCompleteFile(ctx context.Context, e hosts.FileCompleteEvent) error {
+	host := hosts.Get(e.Host)
+	if host == nil {
+		return nil
+	}
+
+	t := query.SharedLink
+	rec, err := t.WithContext(ctx).Where(
+		t.UserID.Eq(e.UserID),
+		t.OriginalLinkHash.Eq(e.OriginalLinkHash),
+		t.Host.Eq(host.Name()),
+	).Take()
+	if gormutil.IsNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("query shared link error: %w", err)
+	}
+
+	sharedLinks, err := host.CreateFromLinks(ctx, rec.UserID, []string{rec.OriginalLink}, rec.CreatedBy, "")
+	if err != nil {
+		return fmt.Errorf("create share from links error: %w", err)
+	}
+	sh := sharedLinks[rec.OriginalLink]
+	if sh == nil {
+		return nil
+	}
+
+	_, err = updateSharedLinks(ctx, &model.SharedLink{
+		State:              sh.State.String(),
+		Size:               sh.Size,
+		Title:              sh.Title,
+		HostSharedLinkHash: lk.Hash(sh.HostSharedLink),
+		HostSharedLink:     sh.HostSharedLink,
+		Error:              sh.Error,
+		UpdatedAt:          time.Now(),
+	}, t.AutoID.Eq(rec.AutoID))
+	if err == nil {
+		notifySharedLinksUpdated(ctx, rec.OriginalLinkHash)
+	}
+	return err
 }
 
 func (r *AsyncTaskRunner) Run() {
@@ -170,19 +224,16 @@ func (r *AsyncTaskRunner) handleCompleteUniqueTasks(ctx context.Context, files [
 		}
 
 		uid, ohs := strings.Split(v.UniqueHash, ":")[0], v.OriginalLinkHash
-		if _, err := query.SharedLink.WithContext(ctx).
-			Where(
-				query.SharedLink.UserID.Eq(uid),
-				query.SharedLink.OriginalLinkHash.Eq(ohs),
-			).
-			Updates(&model.SharedLink{
-				State:          share.StatusOK.String(),
-				HostSharedLink: sharedLink,
-				UpdatedAt:      time.Now(),
-			}); err != nil {
+		update := &model.SharedLink{
+			State:          share.StatusOK.String(),
+			HostSharedLink: sharedLink,
+			UpdatedAt:      time.Now(),
+		}
+		if _, err := updateSharedLinks(ctx, update, query.SharedLink.UserID.Eq(uid), query.SharedLink.OriginalLinkHash.Eq(ohs)); err != nil {
 			log.Errorf("update keepshare_shared_link state error: %v", err)
 			continue
 		}
+		notifySharedLinksUpdated(ctx, ohs)
 	}
 
 	return nil
@@ -199,18 +250,20 @@ func (r *AsyncTaskRunner) handleErrorUniqueHashes(ctx context.Context, hashes []
 		return []string{uid, ohs}
 	})
 
-	_, err := query.SharedLink.WithContext(ctx).Where(
-		query.SharedLink.WithContext(ctx).
-			Columns(query.SharedLink.UserID, query.SharedLink.OriginalLinkHash).
-			In(field.Values(tupleConditions)),
-	).Updates(&model.SharedLink{
+	_, err := updateSharedLinks(ctx, &model.SharedLink{
 		State:     share.StatusError.String(),
 		UpdatedAt: time.Now(),
-	})
+	}, query.SharedLink.WithContext(ctx).
+		Columns(query.SharedLink.UserID, query.SharedLink.OriginalLinkHash).
+		In(field.Values(tupleConditions)),
+	)
 	if err != nil {
 		log.Errorf("update keepshare_shared_link state error: %v", err)
 		return err
 	}
+	for _, v := range tupleConditions {
+		notifySharedLinksUpdated(ctx, v[1])
+	}
 
 	return nil
 }
@@ -248,15 +301,14 @@ func (r *AsyncTaskRunner) createNotExistsHostTasks(ctx context.Context, tasks ch
 				}).Debugf("create share from links err: %v", err)
 
 				if IsForbiddenShareResourceError(err) || api.IsShouldNotRetryError(err) {
-					_, _ = query.SharedLink.WithContext(ctx).
-						Where(query.SharedLink.AutoID.Eq(ksl.AutoID)).
-						Updates(&model.SharedLink{
-							State:     constant.StatusError,
-							Error:     err.Error(),
-							UpdatedAt: time.Now(),
-						})
+					_, _ = updateSharedLinks(ctx, &model.SharedLink{
+						State:     constant.StatusError,
+						Error:     err.Error(),
+						UpdatedAt: time.Now(),
+					}, query.SharedLink.AutoID.Eq(ksl.AutoID))
+					notifySharedLinksUpdated(ctx, ksl.OriginalLinkHash)
 				}
-				log.Errorf("create share from links err: %w", err)
+				log.Errorf("create share from links err: %v", err)
 			} else {
 				log.Debugf("create share from links ok: %s", ksl.OriginalLink)
 			}
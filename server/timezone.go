@@ -0,0 +1,86 @@
+// Copyright 2023 The KeepShare Authors. All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	_ "time/tzdata" // the image may have no zoneinfo.
+
+	"github.com/KeepShareOrg/keepshare/pkg/i18n"
+	"github.com/KeepShareOrg/keepshare/pkg/log"
+	"github.com/KeepShareOrg/keepshare/server/constant"
+	mdw "github.com/KeepShareOrg/keepshare/server/middleware"
+	"github.com/KeepShareOrg/keepshare/server/model"
+	"github.com/KeepShareOrg/keepshare/server/query"
+	"github.com/gin-gonic/gin"
+)
+
+// userLocation returns the time zone of the user, it is the time zone of the server if the user has not set one.
+func userLocation(user *model.User) *time.Location {
+	if user.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		log.WithField(constant.UserID, user.ID).Errorf("load location %s err: %v", user.Timezone, err)
+		return time.Local
+	}
+	return loc
+}
+
+// parseTimezone checks the IANA time zone name, the empty name resets to the time zone of the server.
+func parseTimezone(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil
+	}
+	if name == "Local" || len(name) > 64 {
+		return "", fmt.Errorf("invalid timezone %q", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", fmt.Errorf("invalid timezone %q", name)
+	}
+	return name, nil
+}
+
+func getTimezone(c *gin.Context) {
+	ctx := c.Request.Context()
+	user, err := query.User.WithContext(ctx).Where(query.User.ID.Eq(c.GetString(constant.UserID))).Take()
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"timezone": user.Timezone, "location": userLocation(user).String()})
+}
+
+// setTimezone sets the time zone of absolute times in the search DSL of shared links.
+func setTimezone(c *gin.Context) {
+	var req struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+	tz, err := parseTimezone(req.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, mdw.ErrResp(c, "invalid_params", i18n.WithDataMap("error", err.Error())))
+		return
+	}
+
+	ctx := c.Request.Context()
+	t := query.User
+	_, err = t.WithContext(ctx).Where(t.ID.Eq(c.GetString(constant.UserID))).UpdateSimple(t.Timezone.Value(tz))
+	if err != nil {
+		mdw.RespInternal(c, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, Map{"timezone": tz})
+}
@@ -26,6 +26,12 @@ export interface SharedLinkInfo {
   stored: number;
   revenue: number;
   title: string;
+  // user-owned fields, see patchSharedLink
+  custom_title: string;
+  note: string;
+  pinned: number;
+  expires_at: string;
+  version: number;
   days_not_visit: number;
   resource_link_hash: string;
   shared_link_hash: string;
@@ -45,10 +51,19 @@ export interface QuerySharedLinksParams {
   filter?: string;
   pageToken?: string;
   pageIndex?: number;
+  // eg. size, -visitor, last_visited_at:desc
+  sort?: string;
+  // next_cursor of the previous page
+  cursor?: string;
+  // names of tags, shared links with any of them are returned
+  tags?: string[];
 }
 export interface QuerySharedLinksResponse {
   list: SharedLinkInfo[];
   next_page_token: string;
+  next_cursor: string;
+  // tag names of shared links by their auto ids
+  tags: Record<number, string[]>;
   page_size: number;
   total: number;
 }
@@ -59,6 +74,9 @@ export const querySharedLinks = ({
   filter,
   pageIndex,
   pageToken,
+  sort,
+  cursor,
+  tags,
 }: QuerySharedLinksParams = {}) => {
   const ps = new URLSearchParams();
   search && ps.set("search", search);
@@ -66,6 +84,9 @@ export const querySharedLinks = ({
   limit && ps.set("limit", String(limit));
   pageIndex && ps.set("page_index", String(pageIndex));
   pageToken && ps.set("page_token", pageToken);
+  sort && ps.set("sort", sort);
+  cursor && ps.set("cursor", cursor);
+  tags?.forEach((tag) => ps.append("tag", tag));
 
   // eslint-disable-next-line
   return useSWR<AxiosResponse<QuerySharedLinksResponse, any>>(
@@ -91,6 +112,29 @@ export const createShareLink = (links: string[]) => {
   });
 };
 
+// edit user-owned fields of a shared link, version is the one read last,
+// the request fails with 409 if the shared link has been edited since then.
+export interface PatchSharedLinkParams {
+  version: number;
+  title?: string;
+  note?: string;
+  tags?: string[];
+  pinned?: boolean;
+  // "" removes the expiry
+  expires_at?: string;
+}
+interface PatchSharedLinkResponse {
+  shared_link: SharedLinkInfo;
+  tags: string[];
+}
+export const patchSharedLink = (id: number, params: PatchSharedLinkParams) => {
+  return axiosWrapper<PatchSharedLinkResponse>({
+    method: "PATCH",
+    url: `/api/shared_links/${id}`,
+    data: params,
+  });
+};
+
 // get shared link info by auto id
 export const getSharedLinkInfo = (id: string, requestId: string, isEnd: boolean) => {
   return axiosWrapper<SharedLinkInfo>({
@@ -99,6 +143,26 @@ export const getSharedLinkInfo = (id: string, requestId: string, isEnd: boolean)
   });
 };
 
+// the data of `state` events of shared link streams, host_link is sent if the state is OK.
+export interface SharedLinkStateEvent {
+  id: number;
+  state: SharedLinkStatus;
+  original_link: string;
+  host_link?: string;
+  title?: string;
+  size?: number;
+  error?: string;
+}
+// the data of `progress` events of shared link streams, the percentage of downloading.
+export interface SharedLinkProgressEvent {
+  id: number;
+  progress: number;
+}
+// stream changes of a shared link by Server-Sent Events, it ends after a final state.
+export const openSharedLinkEvents = (id: string) => {
+  return new EventSource(`/api/shared_link/events?id=${id}`);
+};
+
 // link to share submission result
 interface SubmissionResultResponse {
   list: SharedLinkInfo[];
@@ -1,11 +1,12 @@
-import { Space, Typography, message, theme } from "antd";
+import { Space, Typography, theme } from "antd";
 import { Background, ContentWrapper, LogoPng } from "./style";
 import LogoIcon from "@/assets/images/logo-with-text.png";
 import { useEffect, useState } from "react";
 import {
   SharedLinkStatus,
+  type SharedLinkStateEvent,
   getLinkInfoFromWhatsLink,
-  getSharedLinkInfo,
+  openSharedLinkEvents,
 } from "@/api/link";
 import { useSearchParams } from "react-router-dom";
 import { getSupportLanguage } from "@/util";
@@ -52,12 +53,10 @@ const SharedStatus = () => {
 
   const [params] = useSearchParams();
 
-  const [requestId, setRequestId] = useState("");
   const setThemeMode = useStore((state) => state.setThemeMode);
   // status page keep light mode
   useEffect(() => {
     setThemeMode("light");
-    setRequestId(params.get("request_id") || "");
   }, []);
 
   const { original_link: link } = fileInfo;
@@ -77,75 +76,71 @@ const SharedStatus = () => {
   const [status, setStatus] = useState<SharedLinkStatus>("PENDING");
   const { title, subtitle } = useStatusDescribeText(status, remoteDownload);
 
-  const MAX_LOOP_TIMES = 20;
-  const [loopTimes, setLoopTimes] = useState(0);
+  // the loading is shown for a while, unless the task has been created for a while.
+  const LOADING_MS = 10000;
+  const [loading, setLoading] = useState(params.get("st") !== "1");
+  useEffect(() => {
+    const timer = setTimeout(() => setLoading(false), LOADING_MS);
+    return () => clearTimeout(timer);
+  }, []);
+
+  // Get the changes of the shared link from keepshare server, the stream ends after a final state.
+  const [originalLink, setOriginalLink] = useState("");
   useEffect(() => {
     const autoId = params.get("id") || "";
     if (!/^\d+$/i.test(autoId)) {
       return;
     }
 
-    const isLoopEnd = loopTimes > MAX_LOOP_TIMES;
-    let timer: ReturnType<typeof setTimeout> | null = null;
-    // Get data from keepshare server, but the data may be incomplete (returned from PikPak)
-    getSharedLinkInfo(autoId, requestId, isLoopEnd).then(
-      ({ data: newFileInfo, error }) => {
-        if (newFileInfo) {
-          if (loopTimes <= MAX_LOOP_TIMES) {
-            timer = setTimeout(() => {
-              setLoopTimes(loopTimes + 1);
-              timer && clearTimeout(timer);
-            }, 2000);
-          }
-
-          const newStatus = newFileInfo.state;
-          if (status === newStatus) {
-            return;
-          }
-
-          const hostSharedLink = newFileInfo.host_shared_link;
-          if (newStatus === "OK" && hostSharedLink) {
-            location.href = hostSharedLink;
-            timer && clearTimeout(timer);
-          } else {
-            setStatus(newStatus);
-            setFileInfo(Object.assign({}, fileInfo, newFileInfo));
-          }
-
-          if (loopTimes === MAX_LOOP_TIMES) {
-            setStatus("CREATED");
-          }
-        }
+    const source = openSharedLinkEvents(autoId);
+    source.addEventListener("state", (e) => {
+      const data: SharedLinkStateEvent = JSON.parse((e as MessageEvent).data);
+      if (data.state === "OK" && data.host_link) {
+        source.close();
+        location.href = data.host_link;
+        return;
+      }
+
+      setStatus(data.state);
+      setOriginalLink(data.original_link);
+      setFileInfo((info) => ({
+        ...info,
+        state: data.state,
+        original_link: data.original_link,
+        title: info.title || data.title,
+        size: info.size || data.size,
+      }));
+      // the server closes the stream after a final state, do not reconnect.
+      if (!["PENDING", "UNKNOWN", "CREATED"].includes(data.state)) {
+        source.close();
+        setLoading(false);
+      }
+    });
+    return () => source.close();
+  }, []);
 
-        // Get data from whatsLink website
-        if (!error) {
-          getLinkInfoFromWhatsLink(newFileInfo?.original_link || "")
-            .then(({ data, error }) => {
-              try {
-                if (error) {
-                  return;
-                }
-                setFileInfo(
-                  Object.assign({}, newFileInfo, {
-                    title: fileInfo?.title || data?.name,
-                    size: fileInfo?.size || data?.size,
-                    screenshot: data?.screenshots?.[0]?.screenshot,
-                    fileType: data?.file_type || "unknown",
-                  }),
-                );
-              } catch (e) {
-                console.error("get link info error: ", e);
-              }
-            })
-            .catch((err) => {
-              console.warn("get link info from whatslink error: ", err);
-            });
+  // Get data from whatsLink website
+  useEffect(() => {
+    if (!originalLink) {
+      return;
+    }
+    getLinkInfoFromWhatsLink(originalLink)
+      .then(({ data, error }) => {
+        if (error) {
+          return;
         }
-
-        error && message.error(error.message);
-      },
-    );
-  }, [loopTimes]);
+        setFileInfo((info) => ({
+          ...info,
+          title: info.title || data?.name,
+          size: info.size || data?.size,
+          screenshot: data?.screenshots?.[0]?.screenshot,
+          fileType: data?.file_type || "unknown",
+        }));
+      })
+      .catch((err) => {
+        console.warn("get link info from whatslink error: ", err);
+      });
+  }, [originalLink]);
 
   useEffect(() => {
     i18n.changeLanguage(getSupportLanguage());
@@ -156,7 +151,7 @@ const SharedStatus = () => {
       <Link href="/">
         <LogoPng src={LogoIcon} />
       </Link>
-      {loopTimes < 5 && params.get("st") !== "1" ? (
+      {loading ? (
         <Loading>
           <ContentWrapper style={{ minHeight: "auto" }}>
             <LinkInfo